@@ -2,8 +2,8 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 
+	"github.com/maintc/rustmaps-cli/pkg/report"
 	"github.com/spf13/cobra"
 )
 
@@ -14,15 +14,21 @@ var authCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		apiKey := args[0]
 		generator.SetApiKey(apiKey)
-		tier, ok := generator.DetermineTier(logger)
+		tier, ok := generator.DetermineTier(cmd.Context(), logger)
 		if !ok {
-			fmt.Println("Provided API key is invalid, check logs for more info")
-			os.Exit(1)
+			exitWithError(1, "Provided API key is invalid, check logs for more info")
 		}
 		generator.SetTier(tier)
 		if err := generator.SaveConfig(); err != nil {
-			fmt.Println("Error saving config, check logs for more info")
-			os.Exit(1)
+			exitWithError(1, "Error saving config, check logs for more info")
+		}
+		if structuredOutput() {
+			printDocument(report.AuthDocument{
+				Header: report.NewHeader(report.DocumentAuth),
+				Tier:   tier,
+				Limits: currentLimits(cmd.Context()),
+			})
+			return
 		}
 		fmt.Printf("API key verified: 🗺️ %s Subscriber\n", tier)
 	},
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/report"
+)
+
+// Exit codes returned by rustmaps commands, 1 is any other error
+const (
+	exitCodeFailed         = 2   // some maps failed to generate
+	exitCodeAuth           = 3   // RustMaps rejected the API key or refused the account
+	exitCodeQuotaExhausted = 4   // the monthly quota or the run's budget ran out with maps left to submit
+	exitCodeTimeout        = 5   // --timeout or --max-wait-for-quota ran out
+	exitCodeInterrupted    = 130 // interrupted by SIGINT or SIGTERM
+)
+
+// summaryExitCode is the code generate exits with once no map is left to
+// wait for: an auth error when RustMaps turned the account away, failed
+// when any map did not complete, 0 otherwise
+func summaryExitCode(s report.Summary) int {
+	switch {
+	case s.Statuses[common.StatusUnauthorized] > 0 || s.Statuses[common.StatusForbidden] > 0:
+		return exitCodeAuth
+	case len(s.Failed) > 0:
+		return exitCodeFailed
+	}
+	return 0
+}
@@ -1,12 +1,21 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 	"time"
 
+	"github.com/maintc/rustmaps-cli/pkg/api"
+	"github.com/maintc/rustmaps-cli/pkg/report"
+	"github.com/maintc/rustmaps-cli/pkg/rustmaps"
+	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
 )
 
 var generateCmd = &cobra.Command{
@@ -25,28 +34,115 @@ var generateCmd = &cobra.Command{
 		random, _ := cmd.Flags().GetBool("random")
 		download, _ := cmd.Flags().GetBool("download")
 		outputDir, _ := cmd.Flags().GetString("output-dir")
+		parallel, _ := cmd.Flags().GetInt("parallel")
+		generationTimeout, _ := cmd.Flags().GetDuration("generation-timeout")
+		stallTimeout, _ := cmd.Flags().GetDuration("stall-timeout")
+		maxResubmits, _ := cmd.Flags().GetInt("max-resubmits")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		maxWaitForQuota, _ := cmd.Flags().GetDuration("max-wait-for-quota")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		maxMaps, _ := cmd.Flags().GetInt("max-maps")
+		reserve, _ := cmd.Flags().GetInt("reserve")
+		yes, _ := cmd.Flags().GetBool("yes")
+
+		generator.SetParallel(parallel)
+		generator.SetStallPolicy(generationTimeout, stallTimeout, maxResubmits)
+		generator.SetMaxWaitForQuota(maxWaitForQuota)
+		generator.SetMaxMaps(maxMaps)
+		if cmd.Flags().Changed("reserve") {
+			generator.OverrideQuotaReserve(reserve)
+		}
 
 		if outputDir != "" {
 			generator.OverrideDownloadsDir(logger, outputDir)
 		}
 
-		loadFromParams(csv, seed, size, savedConfig, staging, force, random)
+		if dryRun {
+			// Planning must not save, overwrite or lock any map
+			inspectFromParams(csv, seed, size, savedConfig, staging, random)
+		} else {
+			loadFromParams(csv, seed, size, savedConfig, staging, force, random)
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		if dryRun {
+			planRun(ctx, force)
+			return
+		}
+		confirmBudget(ctx, yes)
+
+		if table, ok := reporter.(*report.Table); ok {
+			// Keep elapsed times counting between polls
+			stopTable := table.Start(time.Second)
+			defer stopTable()
+		}
+
+		err := generator.Run(ctx, logger)
+		if ctx.Err() != nil {
+			stop()
+			exitStopped(ctx, timeout)
+		}
 
-		for {
-			if !generator.Generate(logger) {
-				break
+		if err != nil {
+			generator.SaveState(logger)
+			switch {
+			case errors.Is(err, api.ErrUnauthorized):
+				exitRun(ctx, exitCodeAuth, "RustMaps rejected the API key, run `rustmaps auth <api-key>` again")
+			case errors.Is(err, api.ErrForbidden):
+				exitRun(ctx, exitCodeAuth, "RustMaps refused the account, check its subscription on rustmaps.com")
+			case errors.Is(err, rustmaps.ErrQuotaExhausted):
+				exitRun(ctx, exitCodeQuotaExhausted, fmt.Sprintf("Monthly quota exhausted, %d map(s) were not submitted, run the same command again once it resets", len(generator.Unfinished())))
+			case errors.Is(err, rustmaps.ErrBudgetSpent):
+				exitRun(ctx, exitCodeQuotaExhausted, fmt.Sprintf("Generated the maximum of %d map(s) for this run, %d map(s) were not submitted, run the same command again to continue", maxMaps, len(generator.Unfinished())))
+			case errors.Is(err, rustmaps.ErrQuotaWait):
+				exitRun(ctx, exitCodeTimeout, fmt.Sprintf("No generation slot freed up within %s, run the same command again to resume", maxWaitForQuota))
 			}
+			exitRun(ctx, 1, fmt.Sprintf("Error generating maps: %v", err))
 		}
 
+		downloadDir := ""
 		if download {
 			now := time.Now()
 			version := now.Format("2006-01-02_15-04-05")
-			if err := generator.Download(logger, version); err != nil {
-				fmt.Printf("Error downloading maps: %v\n", err)
-				os.Exit(1)
+			if err := generator.Download(ctx, logger, version); err != nil {
+				if ctx.Err() != nil {
+					stop()
+					exitStopped(ctx, timeout)
+				}
+				exitRun(ctx, 1, fmt.Sprintf("Error downloading maps: %v", err))
+			}
+
+			downloadDir = filepath.Join(generator.GetDownloadsDir(), version)
+			if !structuredOutput() {
+				fmt.Printf("Maps downloaded to %s\n", downloadDir)
 			}
+		}
 
-			fmt.Printf("Maps downloaded to %s\n", filepath.Join(generator.GetDownloadsDir(), version))
+		limits := currentLimits(ctx)
+		summary := runSummary(limits)
+		summary.ExitCode = summaryExitCode(summary)
+
+		if structuredOutput() {
+			printDocument(report.GenerateDocument{
+				Header:      report.NewHeader(report.DocumentGenerate),
+				Tier:        generator.GetTier(),
+				Limits:      limits,
+				Maps:        mapRecords(ctx),
+				DownloadDir: downloadDir,
+				Summary:     &summary,
+			})
+		} else {
+			report.WriteSummary(os.Stdout, summary)
+		}
+		if summary.ExitCode != 0 {
+			exit(summary.ExitCode)
 		}
 	},
 }
@@ -61,6 +157,156 @@ func init() {
 	generateCmd.Flags().BoolP("random", "r", false, "Randomly select the seed (size must be set)")
 	generateCmd.Flags().BoolP("download", "d", false, "Download the generated custom maps (you can't download procedural maps)")
 	generateCmd.Flags().StringP("output-dir", "o", "", "Output directory for downloaded maps")
+	generateCmd.Flags().IntP("parallel", "p", 0, "Maximum number of maps to submit at once (0 uses the account's concurrent limit)")
+	generateCmd.Flags().Duration("generation-timeout", rustmaps.DefaultGenerationTimeout, "Give up on a map still generating after this long (0 waits forever)")
+	generateCmd.Flags().Duration("stall-timeout", rustmaps.DefaultStallTimeout, "Give up on a map when RustMaps' generator has not reported on it for this long (0 waits forever)")
+	generateCmd.Flags().Int("max-resubmits", rustmaps.DefaultMaxResubmits, "Times to resubmit a map that was given up on before marking it failed")
+	generateCmd.Flags().Duration("timeout", 0, "Stop the whole run after this long, saving state to resume later (0 runs until done)")
+	generateCmd.Flags().Duration("max-wait-for-quota", 0, "Stop when no generation slot frees up for this long (0 waits forever)")
+	generateCmd.Flags().Bool("dry-run", false, "Print what would be submitted and how much quota it would use, without submitting anything")
+	generateCmd.Flags().Int("max-maps", 0, "Maximum number of maps to generate in this run (0 for no limit)")
+	generateCmd.Flags().Int("reserve", 0, "Maps of the monthly quota to always leave unused (default from config, or 0)")
+	generateCmd.Flags().BoolP("yes", "y", false, "Submit as many maps as the quota allows without asking when not all of them fit")
+}
+
+// confirmBudget checks that the maps waiting to be submitted fit in what
+// is left of the monthly quota after the reserve, and in --max-maps. When
+// they do not it asks whether to submit as many as fit, and exits with
+// exitCodeQuotaExhausted when the answer is no or there is no one to ask.
+// With yes the run goes ahead and stops once the budget is spent.
+func confirmBudget(ctx context.Context, yes bool) {
+	budget, err := generator.Budget(ctx, logger)
+	if err != nil {
+		// The run checks the limits again before every submission
+		logger.Warn("Error checking the quota budget", zap.Error(err))
+		return
+	}
+
+	short := budget.Shortfall()
+	if short == 0 || yes {
+		return
+	}
+	msg := fmt.Sprintf("%s, %d would not be submitted", budget, short)
+	if budget.Available() == 0 {
+		exitWithError(exitCodeQuotaExhausted, msg)
+	}
+	refused := msg + ", use --yes to submit the ones that fit"
+	if structuredOutput() || !isTerminal(os.Stdin) {
+		exitWithError(exitCodeQuotaExhausted, refused)
+	}
+
+	fmt.Println(msg)
+	prompt := promptui.Prompt{
+		Label:     fmt.Sprintf("Submit the %d that fit", budget.Available()),
+		IsConfirm: true,
+	}
+	if _, err := prompt.Run(); err != nil {
+		if errors.Is(err, promptui.ErrAbort) {
+			exitWithError(exitCodeQuotaExhausted, "Nothing was submitted")
+		}
+		// Stdin closed without an answer, e.g. from /dev/null
+		exitWithError(exitCodeQuotaExhausted, refused)
+	}
+}
+
+// planRun prints what generating the loaded maps would do. It exits with
+// exitCodeQuotaExhausted when the maps to submit do not fit in the monthly
+// quota.
+func planRun(ctx context.Context, force bool) {
+	plan, err := generator.Plan(ctx, logger, force)
+	if err != nil {
+		if errors.Is(err, api.ErrUnauthorized) {
+			exitWithError(exitCodeAuth, "RustMaps rejected the API key, run `rustmaps auth <api-key>` again")
+		}
+		exitWithError(1, fmt.Sprintf("Error planning maps: %v", err))
+	}
+
+	if structuredOutput() {
+		printDocument(report.PlanDocument{
+			Header: report.NewHeader(report.DocumentPlan),
+			Tier:   generator.GetTier(),
+			Plan:   plan,
+		})
+	} else {
+		report.WritePlan(os.Stdout, plan)
+	}
+	if plan.Shortfall() > 0 {
+		exit(exitCodeQuotaExhausted)
+	}
+}
+
+// runSummary summarises the run so far, with the monthly usage from limits
+// when they could be fetched
+func runSummary(limits *report.Limits) report.Summary {
+	summary := generator.Summary()
+	if limits != nil {
+		summary.Monthly = &limits.Monthly
+	}
+	return summary
+}
+
+// exitRun prints msg after the run summary, as an error document carrying
+// the summary in the JSON modes, and exits with code. It is exitWithError
+// for runs that stop before every map is finished.
+func exitRun(ctx context.Context, code int, msg string) {
+	summary := runSummary(currentLimits(ctx))
+	summary.ExitCode = code
+	if structuredOutput() {
+		printDocument(report.ErrorDocument{
+			Header:  report.NewHeader(report.DocumentError),
+			Error:   msg,
+			Summary: &summary,
+		})
+	} else {
+		report.WriteSummary(os.Stdout, summary)
+		fmt.Println(msg)
+	}
+	exit(code)
+}
+
+// exitStopped exits after ctx was cancelled, with exitCodeTimeout when the
+// run deadline passed and exitInterrupted otherwise
+func exitStopped(ctx context.Context, timeout time.Duration) {
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		exitInterrupted(ctx)
+	}
+	msg := fmt.Sprintf("Timed out after %s, run the same command again to resume", timeout)
+	if err := generator.SaveState(logger); err != nil {
+		msg = fmt.Sprintf("Timed out after %s, error saving map state, check logs for more info", timeout)
+	}
+	exitRun(ctx, exitCodeTimeout, msg)
+}
+
+// exitInterrupted persists the state of every loaded map, reports what is
+// still outstanding and exits with exitCodeInterrupted
+func exitInterrupted(ctx context.Context) {
+	if structuredOutput() {
+		msg := "Interrupted, run the same command again to resume"
+		if err := generator.SaveState(logger); err != nil {
+			msg = "Interrupted, error saving map state, check logs for more info"
+		}
+		exitRun(ctx, exitCodeInterrupted, msg)
+	}
+
+	fmt.Println()
+	fmt.Println("Interrupted, saving map state")
+	if err := generator.SaveState(logger); err != nil {
+		fmt.Println("Error saving map state, check logs for more info")
+	}
+	summary := runSummary(currentLimits(ctx))
+	summary.ExitCode = exitCodeInterrupted
+	report.WriteSummary(os.Stdout, summary)
+
+	unfinished := generator.Unfinished()
+	if len(unfinished) > 0 {
+		fmt.Printf("%d map(s) not finished:\n", len(unfinished))
+		for _, m := range unfinished {
+			fmt.Printf("  %s\n", m.String())
+		}
+		fmt.Println("Run the same command again to resume")
+	}
+
+	exit(exitCodeInterrupted)
 }
 
 // validateGenerateFlags checks mutual exclusivity and other flag rules
@@ -73,6 +319,33 @@ func validateGenerateFlags(cmd *cobra.Command) error {
 	random, _ := cmd.Flags().GetBool("random")
 	download, _ := cmd.Flags().GetBool("download")
 	outputDir, _ := cmd.Flags().GetString("output-dir")
+	parallel, _ := cmd.Flags().GetInt("parallel")
+
+	if parallel < 0 {
+		return fmt.Errorf("--parallel cannot be negative")
+	}
+
+	generationTimeout, _ := cmd.Flags().GetDuration("generation-timeout")
+	stallTimeout, _ := cmd.Flags().GetDuration("stall-timeout")
+	maxResubmits, _ := cmd.Flags().GetInt("max-resubmits")
+	if generationTimeout < 0 || stallTimeout < 0 {
+		return fmt.Errorf("--generation-timeout and --stall-timeout cannot be negative")
+	}
+	if maxResubmits < 0 {
+		return fmt.Errorf("--max-resubmits cannot be negative")
+	}
+
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	maxWaitForQuota, _ := cmd.Flags().GetDuration("max-wait-for-quota")
+	if timeout < 0 || maxWaitForQuota < 0 {
+		return fmt.Errorf("--timeout and --max-wait-for-quota cannot be negative")
+	}
+
+	maxMaps, _ := cmd.Flags().GetInt("max-maps")
+	reserve, _ := cmd.Flags().GetInt("reserve")
+	if maxMaps < 0 || reserve < 0 {
+		return fmt.Errorf("--max-maps and --reserve cannot be negative")
+	}
 
 	// random can only be used with size
 	if random && seed != "" {
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/api"
+	"github.com/maintc/rustmaps-cli/pkg/report"
+	"github.com/spf13/cobra"
+)
+
+var limitsCmd = &cobra.Command{
+	Use:   "limits",
+	Short: "Show the account's quota usage and when it runs out",
+	Long: `Show how many maps are generating against the concurrent limit, how much
+of the monthly quota is used, the tier it belongs to and how many maps the
+local history says were generated this month. The rate the quota was used
+at so far this month forecasts whether it lasts until the reset, which is
+taken to be the start of the next month in UTC.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := generator.ValidateAuthentication(logger); err != nil {
+			exitWithError(exitCodeAuth, fmt.Sprintf("%v, run `rustmaps auth <api-key>` first", err))
+		}
+
+		now := time.Now()
+		quota, err := generator.Quota(cmd.Context(), logger, now)
+		if err != nil {
+			if errors.Is(err, api.ErrUnauthorized) {
+				exitWithError(exitCodeAuth, "RustMaps rejected the API key, run `rustmaps auth <api-key>` again")
+			}
+			exitWithError(1, fmt.Sprintf("Error getting limits: %v", err))
+		}
+
+		if structuredOutput() {
+			printDocument(report.LimitsDocument{
+				Header: report.NewHeader(report.DocumentLimits),
+				Quota:  quota,
+			})
+			return
+		}
+		report.WriteLimits(os.Stdout, quota, now)
+	},
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/report"
+	"github.com/maintc/rustmaps-cli/pkg/rustmaps"
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List every map rustmaps knows about",
+	Long: `List every map with saved state, whichever command or CSV file it came
+from, and whether its assets were downloaded locally. Filters combine, so
+--saved-config default --since 2024-01-01 lists the maps generated with the
+default config this year.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		seed, _ := cmd.Flags().GetString("seed")
+		size, _ := cmd.Flags().GetInt("size")
+		savedConfig, _ := cmd.Flags().GetString("saved-config")
+		status, _ := cmd.Flags().GetString("status")
+		mapID, _ := cmd.Flags().GetString("map-id")
+		since, _ := cmd.Flags().GetString("since")
+		until, _ := cmd.Flags().GetString("until")
+		order, _ := cmd.Flags().GetString("sort")
+		reverse, _ := cmd.Flags().GetBool("reverse")
+		outputDir, _ := cmd.Flags().GetString("output-dir")
+
+		q := rustmaps.Query{Seed: seed, Size: size, SavedConfig: savedConfig, Status: status, MapID: mapID}
+		if cmd.Flags().Changed("staging") {
+			staging, _ := cmd.Flags().GetBool("staging")
+			q.Staging = &staging
+		}
+		var err error
+		if q.Since, err = parseDate(since, false); err != nil {
+			exitWithError(1, fmt.Sprintf("Invalid --since: %v", err))
+		}
+		if q.Until, err = parseDate(until, true); err != nil {
+			exitWithError(1, fmt.Sprintf("Invalid --until: %v", err))
+		}
+
+		maps, err := generator.QueryMaps(q)
+		if err != nil {
+			exitWithError(1, fmt.Sprintf("Error reading maps: %v", err))
+		}
+		if err := rustmaps.SortMaps(maps, order); err != nil {
+			exitWithError(1, err.Error())
+		}
+		if reverse {
+			for i, j := 0, len(maps)-1; i < j; i, j = i+1, j-1 {
+				maps[i], maps[j] = maps[j], maps[i]
+			}
+		}
+
+		if outputDir != "" {
+			generator.SetDownloadsDir(outputDir)
+		}
+		downloads, err := generator.LocalDownloads(maps)
+		if err != nil {
+			exitWithError(1, fmt.Sprintf("Error reading downloads: %v", err))
+		}
+
+		records := []report.MapRecord{}
+		for _, m := range maps {
+			r := report.NewMapRecord(m)
+			if d, ok := downloads[m]; ok {
+				r.Files = &report.Assets{Map: d.Map, Image: d.Image, ImageIcons: d.ImageIcons, Thumbnail: d.Thumbnail}
+			}
+			records = append(records, r)
+		}
+
+		if structuredOutput() {
+			printDocument(report.ListDocument{
+				Header: report.NewHeader(report.DocumentList),
+				Maps:   records,
+			})
+			return
+		}
+		report.WriteList(os.Stdout, records)
+	},
+}
+
+func init() {
+	listCmd.Flags().StringP("seed", "s", "", "Only maps with this seed")
+	listCmd.Flags().IntP("size", "z", 0, "Only maps of this size")
+	listCmd.Flags().StringP("saved-config", "S", "", "Only maps generated with this saved config, procedural for maps without one")
+	listCmd.Flags().BoolP("staging", "b", false, "Only maps for the staging branch, --staging=false for maps that are not")
+	listCmd.Flags().String("status", "", "Only maps with this status, e.g. Complete or Failed")
+	listCmd.Flags().String("map-id", "", "Only the map with this RustMaps ID")
+	listCmd.Flags().String("since", "", "Only maps submitted (or else completed) on or after this date (YYYY-MM-DD or RFC3339)")
+	listCmd.Flags().String("until", "", "Only maps submitted (or else completed) on or before this date (YYYY-MM-DD or RFC3339)")
+	listCmd.Flags().String("sort", rustmaps.SortSeed, fmt.Sprintf("Order of the maps (%s)", strings.Join(rustmaps.SortOrders, ", ")))
+	listCmd.Flags().Bool("reverse", false, "Reverse the order")
+	listCmd.Flags().StringP("output-dir", "o", "", "Directory maps were downloaded to with generate -o")
+}
+
+// parseDate parses a YYYY-MM-DD date in the local zone or an RFC3339 time.
+// A date ends a range at the end of that day when endOfDay is set. An
+// empty string is the zero time.
+func parseDate(s string, endOfDay bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a YYYY-MM-DD date or an RFC3339 time", s)
+	}
+	if endOfDay {
+		// Until is exclusive, include the second given
+		t = t.Add(time.Second)
+	}
+	return t, nil
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/maintc/rustmaps-cli/pkg/report"
+	"github.com/spf13/cobra"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Move map state from the imports directory into a SQLite database",
+	Long: `Copy the state of every map in the imports directory into a SQLite
+database and keep map state there from now on. The JSON files are left in
+place. Running it again copies them again, replacing what is in the database.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		n, err := generator.MigrateToSQLite()
+		if err != nil {
+			exitWithError(1, fmt.Sprintf("Error migrating maps, %d copied: %v", n, err))
+		}
+		if structuredOutput() {
+			printDocument(report.MigrateDocument{
+				Header:   report.NewHeader(report.DocumentMigrate),
+				Migrated: n,
+				Database: generator.GetDatabasePath(),
+			})
+			return
+		}
+		fmt.Printf("Copied %d map(s) to %s, map state is kept there from now on\n", n, generator.GetDatabasePath())
+	},
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/api/mockserver"
+	"github.com/spf13/cobra"
+)
+
+var mockServerCmd = &cobra.Command{
+	Use:   "mock-server",
+	Short: "Run a local mock of the RustMaps API for offline testing",
+	Long: `Run a local mock of the RustMaps API for offline testing.
+
+Maps are queued, generate and complete on a timer, resubmitting a map that is
+still generating answers 409 Conflict and the concurrent and monthly limits
+are enforced. Nothing is persisted, restarting the server starts over.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		addr, _ := cmd.Flags().GetString("addr")
+		opts := mockserver.DefaultOptions()
+		opts.APIKey, _ = cmd.Flags().GetString("api-key")
+		opts.ConcurrentLimit, _ = cmd.Flags().GetInt("concurrent")
+		opts.MonthlyLimit, _ = cmd.Flags().GetInt("monthly")
+		opts.QueueTime, _ = cmd.Flags().GetDuration("queue-time")
+		opts.GenerationTime, _ = cmd.Flags().GetDuration("generation-time")
+		opts.SavedConfigs, _ = cmd.Flags().GetStringSlice("saved-configs")
+		noStaging, _ := cmd.Flags().GetBool("no-staging")
+		opts.StagingEnabled = !noStaging
+
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			fmt.Printf("Error starting mock server: %v\n", err)
+			exit(1)
+		}
+		server := &http.Server{
+			Handler:           mockserver.New(opts),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(shutdownCtx)
+		}()
+
+		apiUrl := fmt.Sprintf("http://%s%s", listener.Addr(), mockserver.APIPrefix)
+		fmt.Printf("Mock RustMaps API listening on %s\n", apiUrl)
+		fmt.Printf("Use it with: rustmaps --api-url %s generate ...\n", apiUrl)
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("Error running mock server: %v\n", err)
+			exit(1)
+		}
+	},
+}
+
+func init() {
+	defaults := mockserver.DefaultOptions()
+	mockServerCmd.Flags().String("addr", "127.0.0.1:8080", "Address to listen on")
+	mockServerCmd.Flags().String("api-key", "", "Only accept this API key (default accepts any)")
+	mockServerCmd.Flags().Int("concurrent", defaults.ConcurrentLimit, "Maps allowed to generate at once")
+	mockServerCmd.Flags().Int("monthly", defaults.MonthlyLimit, "Maps allowed per month, also decides the reported tier")
+	mockServerCmd.Flags().Duration("queue-time", defaults.QueueTime, "How long a map waits in the queue")
+	mockServerCmd.Flags().Duration("generation-time", defaults.GenerationTime, "How long a map takes to generate")
+	mockServerCmd.Flags().StringSlice("saved-configs", nil, "Saved configs that exist besides default")
+	mockServerCmd.Flags().Bool("no-staging", false, "Reject maps on the staging branch")
+}
@@ -1,37 +1,60 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"os"
 
-	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/report"
 	"github.com/maintc/rustmaps-cli/pkg/types"
 	"github.com/manifoldco/promptui"
 	"github.com/pkg/browser"
 	"github.com/spf13/cobra"
 )
 
-func openInBrowser(m *types.Map) {
-	// Add your callback logic here
-	status, err := generator.GetStatus(logger, m)
+// mapURL returns the rustmaps.com page of m, exiting when there is none
+func mapURL(ctx context.Context, m *types.Map) string {
+	status, err := generator.GetStatus(ctx, logger, m)
 	if err != nil {
-		fmt.Printf("Error getting status: %v\n", err)
-		os.Exit(1)
+		exitWithError(1, fmt.Sprintf("Error getting status: %v", err))
 	}
 	if status.Data.URL == "" {
-		fmt.Println("No URL found")
-		os.Exit(1)
+		exitWithError(1, "No URL found")
 	}
-	if m.SavedConfig == "" && m.Staging {
-		// add query param staging for procedural maps
-		status.Data.URL += "?staging=true"
-	}
-	if err = browser.OpenURL(status.Data.URL); err != nil {
+	return pageURL(m, status.Data.URL)
+}
+
+func openInBrowser(ctx context.Context, m *types.Map) {
+	if err := browser.OpenURL(mapURL(ctx, m)); err != nil {
 		log.Fatalf("Failed to open browser: %v", err)
 	}
 }
 
+// printURLs prints the page of every map instead of opening them, only
+// complete maps have one when more than one map is loaded
+func printURLs(ctx context.Context, maps []*types.Map) {
+	records := []report.MapRecord{}
+	for _, m := range maps {
+		if len(maps) > 1 && !m.IsComplete() {
+			continue
+		}
+		r := report.NewMapRecord(m)
+		r.URL = mapURL(ctx, m)
+		records = append(records, r)
+	}
+
+	if structuredOutput() {
+		printDocument(report.OpenDocument{
+			Header: report.NewHeader(report.DocumentOpen),
+			Maps:   records,
+		})
+		return
+	}
+	for _, r := range records {
+		fmt.Println(r.URL)
+	}
+}
+
 var openCmd = &cobra.Command{
 	Use:   "open",
 	Short: "Open generated maps in the browser",
@@ -46,30 +69,35 @@ var openCmd = &cobra.Command{
 		staging, _ := cmd.Flags().GetBool("staging")
 		force, _ := cmd.Flags().GetBool("force")
 		random, _ := cmd.Flags().GetBool("random")
+		print, _ := cmd.Flags().GetBool("print")
 
 		loadFromParams(csv, seed, size, savedConfig, staging, force, random)
 
 		maps := generator.GetMaps()
 		if len(maps) == 0 {
-			fmt.Println("No maps were loaded")
-			os.Exit(1)
+			exitWithError(1, "No maps were loaded")
+		}
+
+		if print {
+			printURLs(cmd.Context(), maps)
+			return
 		}
 
 		if len(maps) == 1 {
-			openInBrowser(maps[0])
-			os.Exit(0)
+			openInBrowser(cmd.Context(), maps[0])
+			exit(0)
 		}
 
 		var items []string
 		for _, m := range maps {
-			if m.Status == common.StatusComplete {
+			if m.IsComplete() {
 				items = append(items, m.String())
 			}
 		}
 
 		if len(items) == 0 {
 			fmt.Printf("Loaded %d maps, but none are complete", len(maps))
-			os.Exit(1)
+			exit(1)
 		}
 
 		// Create the prompt
@@ -90,13 +118,13 @@ var openCmd = &cobra.Command{
 			_, result, err := prompt.Run()
 			if err != nil {
 				fmt.Printf("Prompt failed: %v\n", err)
-				os.Exit(1)
+				exit(1)
 			}
 
 			// Find the selected map
 			for _, m := range maps {
 				if result == m.String() {
-					openInBrowser(m)
+					openInBrowser(cmd.Context(), m)
 					break
 				}
 			}
@@ -110,6 +138,7 @@ func init() {
 	openCmd.Flags().StringP("seed", "s", "", "Seed to open")
 	openCmd.Flags().IntP("size", "z", 0, "Size of the map to open")
 	openCmd.Flags().BoolP("staging", "b", false, "Open maps against staging branch")
+	openCmd.Flags().Bool("print", false, "Print the map URLs instead of opening them")
 }
 
 // validateOpenFlags checks mutual exclusivity and other flag rules
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/maintc/rustmaps-cli/pkg/api"
+	"github.com/maintc/rustmaps-cli/pkg/report"
+	"github.com/maintc/rustmaps-cli/pkg/rustmaps"
+	"github.com/maintc/rustmaps-cli/pkg/types"
+	"go.uber.org/zap"
+)
+
+// Output modes accepted by --output
+const (
+	outputText   = "text"
+	outputTable  = "table"
+	outputJSON   = "json"
+	outputNDJSON = "ndjson"
+	outputSilent = "silent"
+)
+
+// newReporter returns the reporter for an --output mode. The table needs a
+// terminal to redraw in, so it falls back to text when stdout is piped. JSON
+// mode prints a single document at the end, so progress is not reported.
+func newReporter(mode string) (report.Reporter, error) {
+	switch mode {
+	case outputText:
+		return report.NewText(os.Stdout), nil
+	case outputTable:
+		if !isTerminal(os.Stdout) {
+			return report.NewText(os.Stdout), nil
+		}
+		return report.NewTable(os.Stdout), nil
+	case outputNDJSON:
+		return report.NewNDJSON(os.Stdout), nil
+	case outputJSON, outputSilent:
+		return report.Discard, nil
+	}
+	return nil, fmt.Errorf("invalid output %q, must be one of %s, %s, %s, %s or %s",
+		mode, outputText, outputTable, outputJSON, outputNDJSON, outputSilent)
+}
+
+// isTerminal reports whether f is a character device such as a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// structuredOutput reports whether commands print JSON documents instead of
+// text
+func structuredOutput() bool {
+	return output == outputJSON || output == outputNDJSON
+}
+
+// printDocument writes doc to stdout, on a single line in ndjson mode
+func printDocument(doc any) {
+	if err := report.WriteDocument(os.Stdout, doc, output == outputNDJSON); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+	}
+}
+
+// exitWithError prints msg, as an error document in the JSON modes, and
+// exits with code
+func exitWithError(code int, msg string) {
+	if structuredOutput() {
+		printDocument(report.ErrorDocument{
+			Header: report.NewHeader(report.DocumentError),
+			Error:  msg,
+		})
+	} else {
+		fmt.Println(msg)
+	}
+	exit(code)
+}
+
+// exit closes the generator, releasing the maps it locked and the store,
+// and exits with code. os.Exit skips deferred calls and PersistentPostRun,
+// so commands exit through here.
+func exit(code int) {
+	if generator != nil {
+		if err := generator.Close(); err != nil && logger != nil {
+			logger.Warn("Error closing generator", zap.Error(err))
+		}
+	}
+	os.Exit(code)
+}
+
+// mapRecords describes every loaded map. Maps handled by the last download
+// carry its URLs and files, other complete maps are looked up for their URL.
+func mapRecords(ctx context.Context) []report.MapRecord {
+	downloads := make(map[*types.Map]rustmaps.DownloadResult)
+	for _, d := range generator.Downloads() {
+		downloads[d.Map] = d
+	}
+
+	records := []report.MapRecord{}
+	for _, m := range generator.GetMaps() {
+		r := report.NewMapRecord(m)
+		if d, ok := downloads[m]; ok {
+			r.URL = pageURL(m, d.URL)
+			if !d.Skipped {
+				r.URLs = linkAssets(d.Links)
+				r.Files = &report.Assets{
+					Map:        d.Files.Map,
+					Image:      d.Files.Image,
+					ImageIcons: d.Files.ImageIcons,
+					Thumbnail:  d.Files.Thumbnail,
+				}
+			}
+		} else if m.IsComplete() {
+			if status, err := generator.GetStatus(ctx, logger, m); err == nil {
+				r.URL = pageURL(m, status.Data.URL)
+				if status.Data.CanDownload {
+					r.URLs = linkAssets(rustmaps.DownloadLinks{
+						MapURL:       status.Data.DownloadURL,
+						ImageURL:     status.Data.ImageURL,
+						ImageIconURL: status.Data.ImageIconURL,
+						ThumbnailURL: status.Data.ThumbnailURL,
+					})
+				}
+			}
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func linkAssets(links rustmaps.DownloadLinks) *report.Assets {
+	return &report.Assets{
+		Map:        links.MapURL,
+		Image:      links.ImageURL,
+		ImageIcons: links.ImageIconURL,
+		Thumbnail:  links.ThumbnailURL,
+	}
+}
+
+// pageURL returns the rustmaps.com page for m given the URL the API reported
+func pageURL(m *types.Map, url string) string {
+	if url != "" && m.SavedConfig == "" && m.Staging {
+		// add query param staging for procedural maps
+		url += "?staging=true"
+	}
+	return url
+}
+
+// currentLimits returns the account's limits, nil when they cannot be fetched
+func currentLimits(ctx context.Context) *report.Limits {
+	limits, err := generator.GetLimits(ctx, logger)
+	if err != nil {
+		return nil
+	}
+	return limitsRecord(limits)
+}
+
+func limitsRecord(limits *api.RustMapsLimitsResponse) *report.Limits {
+	return &report.Limits{
+		Concurrent: report.Usage{
+			Current: limits.Data.Concurrent.Current,
+			Allowed: limits.Data.Concurrent.Allowed,
+		},
+		Monthly: report.Usage{
+			Current: limits.Data.Monthly.Current,
+			Allowed: limits.Data.Monthly.Allowed,
+		},
+	}
+}
@@ -1,10 +1,14 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"text/tabwriter"
+	"time"
 
+	"github.com/maintc/rustmaps-cli/pkg/report"
 	"github.com/maintc/rustmaps-cli/pkg/rustmaps"
 	"github.com/maintc/rustmaps-cli/pkg/types"
 
@@ -17,6 +21,16 @@ var (
 	generator *rustmaps.Generator
 	logger    *zap.Logger
 	logLevel  string
+	apiUrl    string
+	rateLimit int
+	rateBurst int
+	// HTTP settings, these override the config file for a single run
+	httpTimeout time.Duration
+	userAgent   string
+	proxy       string
+	caBundle    string
+	output      string
+	reporter    report.Reporter
 )
 
 func GetGenerator() *rustmaps.Generator {
@@ -40,8 +54,11 @@ func initLogger() error {
 	}
 	fileWriteSyncer := zapcore.AddSync(logFile)
 
-	// Create the stdout write syncer
+	// Create the stdout write syncer, stderr when stdout carries JSON documents
 	stdoutWriteSyncer := zapcore.Lock(os.Stdout)
+	if structuredOutput() {
+		stdoutWriteSyncer = zapcore.Lock(os.Stderr)
+	}
 
 	// Parse the log level for stdout
 	var level zapcore.Level
@@ -69,24 +86,37 @@ func initLogger() error {
 }
 
 func loadFromParams(csv, seed string, size int, savedConfig string, staging, force, random bool) {
+	addFromParams(csv, seed, size, savedConfig, staging, random)
+	if err := generator.Import(logger, force); err != nil {
+		if errors.Is(err, rustmaps.ErrMapLocked) {
+			exitWithError(1, fmt.Sprintf("%v, wait for it to finish", err))
+		}
+		exitWithError(1, "Failed to import file, check logs for more info")
+	}
+}
+
+// inspectFromParams loads the maps like loadFromParams without locking or
+// saving any of them
+func inspectFromParams(csv, seed string, size int, savedConfig string, staging, random bool) {
+	addFromParams(csv, seed, size, savedConfig, staging, random)
+	if err := generator.Inspect(logger); err != nil {
+		exitWithError(1, "Failed to import file, check logs for more info")
+	}
+}
+
+// addFromParams adds the maps in csv, or the single map the other flags
+// describe
+func addFromParams(csv, seed string, size int, savedConfig string, staging, random bool) {
 	if csv != "" {
 		if err := generator.LoadCSV(logger, csv); err != nil {
-			fmt.Println("Error validating map file, check logs for more info")
-			fmt.Printf("Error: %v\n", err)
-			os.Exit(1)
-		}
-	} else {
-		if random {
-			seed = generator.GetRandomSeed()
+			exitWithError(1, fmt.Sprintf("Error validating map file: %v", err))
 		}
-		m := types.NewMap(seed, size, savedConfig, staging)
-		generator.AddMap(m)
+		return
 	}
-
-	if err := generator.Import(logger, force); err != nil {
-		fmt.Println("Failed to import file, check logs for more info")
-		os.Exit(1)
+	if random {
+		seed = generator.GetRandomSeed()
 	}
+	generator.AddMap(types.NewMap(seed, size, savedConfig, staging))
 }
 
 var rootCmd = &cobra.Command{
@@ -97,25 +127,73 @@ var rootCmd = &cobra.Command{
 		generator, err = rustmaps.NewGenerator(nil)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error initializing generator: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 
 		if err := generator.InitDirs(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error initializing directories: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 
 		if err := generator.LoadConfig(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
-			os.Exit(1)
+			exit(1)
+		}
+
+		if apiUrl != "" {
+			if err := generator.OverrideAPIUrl(apiUrl); err != nil {
+				fmt.Fprintf(os.Stderr, "Error configuring API URL: %v\n", err)
+				exit(1)
+			}
+		}
+
+		if rateLimit > 0 || rateBurst > 0 {
+			if err := generator.OverrideRateLimit(rateLimit, rateBurst); err != nil {
+				fmt.Fprintf(os.Stderr, "Error configuring rate limit: %v\n", err)
+				exit(1)
+			}
 		}
 
+		if httpTimeout > 0 || userAgent != "" || proxy != "" || caBundle != "" {
+			if err := generator.OverrideHTTP(httpTimeout, userAgent, proxy, caBundle); err != nil {
+				fmt.Fprintf(os.Stderr, "Error configuring HTTP client: %v\n", err)
+				exit(1)
+			}
+		}
+
+		reporter, err = newReporter(output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error configuring output: %v\n", err)
+			exit(1)
+		}
+		generator.SetReporter(reporter)
+
 		if err := initLogger(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error initializing logger: %v\n", err)
-			os.Exit(1)
+			exit(1)
+		}
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		if err := generator.Close(); err != nil {
+			logger.Warn("Error closing generator", zap.Error(err))
 		}
 	},
 	Run: func(cmd *cobra.Command, args []string) {
+		database := ""
+		if generator.GetStore() == rustmaps.StoreSQLite {
+			database = generator.GetDatabasePath()
+		}
+		if structuredOutput() {
+			printDocument(report.InfoDocument{
+				Header:       report.NewHeader(report.DocumentInfo),
+				DownloadsDir: generator.GetDownloadsDir(),
+				ImportsDir:   generator.GetImportDir(),
+				ConfigFile:   generator.GetConfigPath(),
+				LogFile:      generator.GetLogPath(),
+				Database:     database,
+			})
+			return
+		}
 		cmd.Help()
 		fmt.Println()
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
@@ -125,6 +203,9 @@ var rootCmd = &cobra.Command{
 		fmt.Fprintf(w, "  Imports directory\t%s\n", generator.GetImportDir())
 		fmt.Fprintf(w, "  Config file\t%s\n", generator.GetConfigPath())
 		fmt.Fprintf(w, "  Log file\t%s\n", generator.GetLogPath())
+		if database != "" {
+			fmt.Fprintf(w, "  Database\t%s\n", database)
+		}
 		w.Flush()
 	},
 }
@@ -133,6 +214,22 @@ func init() {
 	// Global flags
 	rootCmd.PersistentFlags().StringVarP(&logLevel, "log-level", "l", "fatal",
 		"Log level (debug, info, warn, error, dpanic, panic, fatal)")
+	rootCmd.PersistentFlags().StringVar(&apiUrl, "api-url", "",
+		"RustMaps API URL, e.g. a local mock-server (default from config, or https://api.rustmaps.com/v4)")
+	rootCmd.PersistentFlags().IntVar(&rateLimit, "rate-limit", 0,
+		"Average API calls per minute (default from config, or 60)")
+	rootCmd.PersistentFlags().IntVar(&rateBurst, "rate-burst", 0,
+		"API calls allowed back to back before the rate limit applies (default from config, or 1)")
+	rootCmd.PersistentFlags().DurationVar(&httpTimeout, "http-timeout", 0,
+		"Timeout for each API request attempt, e.g. 30s (default from config, or 10s)")
+	rootCmd.PersistentFlags().StringVar(&userAgent, "user-agent", "",
+		"User-Agent sent to the API (default from config, or rustmaps-cli)")
+	rootCmd.PersistentFlags().StringVar(&proxy, "proxy", "",
+		"HTTP(S) proxy URL for API calls and downloads (default from config, or the environment)")
+	rootCmd.PersistentFlags().StringVar(&caBundle, "ca-bundle", "",
+		"PEM file of extra CA certificates to trust (default from config)")
+	rootCmd.PersistentFlags().StringVar(&output, "output", outputText,
+		"How progress and results are shown (text, table, json, ndjson, silent)")
 	rootCmd.SetHelpCommand(&cobra.Command{
 		Use:    "no-help",
 		Hidden: true,
@@ -141,11 +238,16 @@ func init() {
 	rootCmd.AddCommand(authCmd)
 	rootCmd.AddCommand(openCmd)
 	rootCmd.AddCommand(generateCmd)
+	rootCmd.AddCommand(mockServerCmd)
+	rootCmd.AddCommand(migrateCmd)
+	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(limitsCmd)
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	if err := rootCmd.ExecuteContext(context.Background()); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		exit(1)
 	}
 }
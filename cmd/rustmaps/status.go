@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/api"
+	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/report"
+	"github.com/maintc/rustmaps-cli/pkg/rustmaps"
+	"github.com/maintc/rustmaps-cli/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Refresh and show what RustMaps knows about maps",
+	Long: `Ask RustMaps about each map, record the answer in its saved state and
+print everything RustMaps reports: the URLs, whether the map can be
+downloaded, its monuments, biomes, land, islands, rivers and so on. With
+--watch maps that are still generating are polled until they complete.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return validateStatusFlags(cmd)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		csv, _ := cmd.Flags().GetString("csv")
+		savedConfig, _ := cmd.Flags().GetString("saved-config")
+		seed, _ := cmd.Flags().GetString("seed")
+		size, _ := cmd.Flags().GetInt("size")
+		staging, _ := cmd.Flags().GetBool("staging")
+		mapID, _ := cmd.Flags().GetString("map-id")
+		watch, _ := cmd.Flags().GetBool("watch")
+		interval, _ := cmd.Flags().GetDuration("interval")
+
+		if err := generator.ValidateAuthentication(logger); err != nil {
+			exitWithError(exitCodeAuth, fmt.Sprintf("%v, run `rustmaps auth <api-key>` first", err))
+		}
+
+		maps := statusMaps(csv, seed, size, savedConfig, staging, mapID)
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		records, generating := refreshStatus(ctx, maps)
+		for watch && generating {
+			select {
+			case <-ctx.Done():
+			case <-time.After(interval):
+			}
+			if ctx.Err() != nil {
+				exitWithError(exitCodeInterrupted, "Interrupted, stopped watching")
+			}
+			records, generating = refreshStatus(ctx, maps)
+		}
+
+		if structuredOutput() {
+			printDocument(report.StatusDocument{
+				Header: report.NewHeader(report.DocumentStatus),
+				Maps:   records,
+			})
+		} else {
+			report.WriteStatus(os.Stdout, records)
+		}
+		for _, r := range records {
+			if r.Error != "" {
+				exit(1)
+			}
+		}
+	},
+}
+
+func init() {
+	statusCmd.Flags().StringP("csv", "c", "", "Path to the CSV file")
+	statusCmd.Flags().StringP("saved-config", "S", "", "Saved config the map was generated with")
+	statusCmd.Flags().StringP("seed", "s", "", "Seed of the map")
+	statusCmd.Flags().IntP("size", "z", 0, "Size of the map")
+	statusCmd.Flags().BoolP("staging", "b", false, "Map is for the staging branch")
+	statusCmd.Flags().String("map-id", "", "RustMaps ID of the map")
+	statusCmd.Flags().BoolP("watch", "w", false, "Poll maps that are still generating until they complete")
+	statusCmd.Flags().Duration("interval", 10*time.Second, "Time between polls with --watch")
+}
+
+// statusMaps loads the maps to refresh with their saved state, including
+// maps another rustmaps process is generating. Maps that were never saved
+// locally, and map IDs nothing was saved for, are looked up on RustMaps
+// alone and have no local status.
+func statusMaps(csv, seed string, size int, savedConfig string, staging bool, mapID string) []*types.Map {
+	switch {
+	case mapID != "":
+		known, err := generator.QueryMaps(rustmaps.Query{MapID: mapID})
+		if err != nil {
+			exitWithError(1, fmt.Sprintf("Error reading maps: %v", err))
+		}
+		if len(known) == 0 {
+			return []*types.Map{{MapID: mapID}}
+		}
+		m := known[0]
+		generator.AddMap(types.NewMap(m.Seed, m.Size, m.SavedConfig, m.Staging))
+	case csv != "":
+		if err := generator.LoadCSV(logger, csv); err != nil {
+			exitWithError(1, fmt.Sprintf("Error validating map file: %v", err))
+		}
+	default:
+		generator.AddMap(types.NewMap(seed, size, savedConfig, staging))
+	}
+
+	if err := generator.ImportShared(logger); err != nil {
+		exitWithError(1, "Failed to import file, check logs for more info")
+	}
+	return generator.GetMaps()
+}
+
+// refreshStatus refreshes every map and describes it, reporting whether
+// RustMaps is still generating any of them. It exits when RustMaps rejects
+// the API key.
+func refreshStatus(ctx context.Context, maps []*types.Map) ([]report.StatusRecord, bool) {
+	records := []report.StatusRecord{}
+	generating := false
+	for _, m := range maps {
+		status, err := generator.Refresh(ctx, logger, m)
+		if errors.Is(err, api.ErrUnauthorized) {
+			exitWithError(exitCodeAuth, "RustMaps rejected the API key, run `rustmaps auth <api-key>` again")
+		}
+
+		r := report.StatusRecord{MapRecord: report.NewMapRecord(m)}
+		if err != nil {
+			r.Error = err.Error()
+		}
+		if status != nil {
+			r.RemoteStatus = status.Meta.Status
+			if status.Meta.Status == common.StatusComplete {
+				r.Details = mapDetails(m, status.Data)
+			}
+			if status.Meta.Status == common.StatusGenerating {
+				generating = true
+				if g := status.Generation; g != nil {
+					// Pending maps do not record their progress
+					r.QueuePosition = g.QueuePosition
+					r.CurrentStep = g.CurrentStep
+				}
+			}
+		}
+		records = append(records, r)
+	}
+	return records, generating
+}
+
+func mapDetails(m *types.Map, d api.RustMapsStatusResponseData) *report.MapDetails {
+	details := &report.MapDetails{
+		Type:           d.Type,
+		SaveVersion:    d.SaveVersion,
+		URL:            pageURL(m, d.URL),
+		DownloadURL:    d.DownloadURL,
+		ImageURL:       d.ImageURL,
+		ImageIconURL:   d.ImageIconURL,
+		RawImageURL:    d.RawImageURL,
+		ThumbnailURL:   d.ThumbnailURL,
+		IsCustomMap:    d.IsCustomMap,
+		CanDownload:    d.CanDownload,
+		TotalMonuments: d.TotalMonuments,
+		Monuments:      []report.Monument{},
+		LandPercentage: d.LandPercentageOfMap,
+		Biomes: report.Biomes{
+			Snow:   d.BiomePercentages.S,
+			Desert: d.BiomePercentages.D,
+			Forest: d.BiomePercentages.F,
+			Tundra: d.BiomePercentages.T,
+			Jungle: d.BiomePercentages.J,
+		},
+		Islands:        d.Islands,
+		Mountains:      d.Mountains,
+		IceLakes:       d.IceLakes,
+		Rivers:         d.Rivers,
+		Lakes:          d.Lakes,
+		Canyons:        d.Canyons,
+		Oases:          d.Oases,
+		BuildableRocks: d.BuildableRocks,
+	}
+	for _, monument := range d.Monuments {
+		details.Monuments = append(details.Monuments, report.Monument{
+			Type:         monument.Type,
+			X:            monument.Coordinates.X,
+			Y:            monument.Coordinates.Y,
+			NameOverride: monument.NameOverride,
+		})
+	}
+	return details
+}
+
+// validateStatusFlags checks mutual exclusivity and other flag rules
+func validateStatusFlags(cmd *cobra.Command) error {
+	csv, _ := cmd.Flags().GetString("csv")
+	savedConfig, _ := cmd.Flags().GetString("saved-config")
+	seed, _ := cmd.Flags().GetString("seed")
+	size, _ := cmd.Flags().GetInt("size")
+	staging, _ := cmd.Flags().GetBool("staging")
+	mapID, _ := cmd.Flags().GetString("map-id")
+	interval, _ := cmd.Flags().GetDuration("interval")
+
+	if interval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+
+	if mapID != "" && (csv != "" || seed != "" || size != 0 || savedConfig != "" || staging) {
+		return fmt.Errorf("cannot use --map-id with --csv, --seed, --size, --saved-config or --staging")
+	}
+	if mapID != "" {
+		return nil
+	}
+
+	// csv cannot be used with anything else
+	if csv != "" && (seed != "" || size != 0 || savedConfig != "" || staging) {
+		return fmt.Errorf("cannot use --csv with --seed, --size, --saved-config or --staging")
+	}
+
+	if !(csv != "" || (size != 0 && seed != "")) {
+		return fmt.Errorf("must provide either --map-id, --csv, or --size and --seed with or without --staging")
+	}
+
+	return nil
+}
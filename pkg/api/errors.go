@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors matched by *Error, check them with errors.Is
+var (
+	ErrBadRequest        = errors.New("bad request")
+	ErrUnauthorized      = errors.New("unauthorized")
+	ErrForbidden         = errors.New("forbidden")
+	ErrNotFound          = errors.New("not found")
+	ErrConflict          = errors.New("conflict")
+	ErrStagingNotEnabled = errors.New("staging is not enabled")
+	ErrRateLimited       = errors.New("rate limited")
+	ErrServer            = errors.New("server error")
+)
+
+// stagingNotEnabled is the meta error RustMaps returns with 400 when the
+// account cannot generate maps on the staging branch
+const stagingNotEnabled = "Staging is not enabled"
+
+// Error is returned when the RustMaps API answers with a status other than
+// the ones an endpoint expects
+type Error struct {
+	// StatusCode is the HTTP status of the response
+	StatusCode int
+	// Errors are the messages from the response's meta section
+	Errors []string
+	// RequestID identifies the request in RustMaps support tickets, when the
+	// API sent one
+	RequestID string
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("rustmaps api: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if len(e.Errors) > 0 {
+		msg = fmt.Sprintf("%s: %s", msg, strings.Join(e.Errors, "; "))
+	}
+	if e.RequestID != "" {
+		msg = fmt.Sprintf("%s (request %s)", msg, e.RequestID)
+	}
+	return msg
+}
+
+// Is reports whether target is the sentinel for this error. Staging not being
+// enabled is also a bad request.
+func (e *Error) Is(target error) bool {
+	kind := e.Unwrap()
+	if target == kind {
+		return true
+	}
+	return kind == ErrStagingNotEnabled && target == ErrBadRequest
+}
+
+// Unwrap returns the sentinel error matching the status code, or nil for
+// statuses without one
+func (e *Error) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusBadRequest:
+		if len(e.Errors) > 0 && e.Errors[0] == stagingNotEnabled {
+			return ErrStagingNotEnabled
+		}
+		return ErrBadRequest
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusForbidden:
+		return ErrForbidden
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusConflict:
+		return ErrConflict
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode >= http.StatusInternalServerError:
+		return ErrServer
+	}
+	return nil
+}
+
+// newError builds an *Error from a response and its body
+func newError(resp *http.Response, body []byte) *Error {
+	// Every RustMaps response has the same meta section
+	var envelope struct {
+		Meta struct {
+			Errors []string `json:"errors"`
+		} `json:"meta"`
+	}
+	json.Unmarshal(body, &envelope)
+
+	return &Error{
+		StatusCode: resp.StatusCode,
+		Errors:     envelope.Meta.Errors,
+		RequestID:  resp.Header.Get("X-Request-Id"),
+	}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func Test_newError(t *testing.T) {
+	type args struct {
+		statusCode int
+		requestID  string
+		body       string
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantIs     []error
+		wantNotIs  []error
+		wantString string
+	}{
+		{
+			name: "Bad request",
+			args: args{
+				statusCode: http.StatusBadRequest,
+				body:       `{"meta":{"status":"error","statusCode":400,"errors":["Invalid seed"]}}`,
+			},
+			wantIs:     []error{ErrBadRequest},
+			wantNotIs:  []error{ErrStagingNotEnabled},
+			wantString: "rustmaps api: 400 Bad Request: Invalid seed",
+		},
+		{
+			name: "Staging not enabled",
+			args: args{
+				statusCode: http.StatusBadRequest,
+				body:       `{"meta":{"errors":["Staging is not enabled"]}}`,
+			},
+			wantIs:     []error{ErrStagingNotEnabled, ErrBadRequest},
+			wantString: "rustmaps api: 400 Bad Request: Staging is not enabled",
+		},
+		{
+			name: "Unauthorized with request ID",
+			args: args{
+				statusCode: http.StatusUnauthorized,
+				requestID:  "abc123",
+			},
+			wantIs:     []error{ErrUnauthorized},
+			wantNotIs:  []error{ErrForbidden},
+			wantString: "rustmaps api: 401 Unauthorized (request abc123)",
+		},
+		{
+			name: "Conflict",
+			args: args{
+				statusCode: http.StatusConflict,
+				body:       `{"meta":{"errors":["Map is already generating","Try again later"]}}`,
+			},
+			wantIs:     []error{ErrConflict},
+			wantString: "rustmaps api: 409 Conflict: Map is already generating; Try again later",
+		},
+		{
+			name: "Rate limited",
+			args: args{
+				statusCode: http.StatusTooManyRequests,
+				body:       "not json",
+			},
+			wantIs:     []error{ErrRateLimited},
+			wantString: "rustmaps api: 429 Too Many Requests",
+		},
+		{
+			name: "Server error",
+			args: args{
+				statusCode: http.StatusBadGateway,
+			},
+			wantIs:     []error{ErrServer},
+			wantString: "rustmaps api: 502 Bad Gateway",
+		},
+		{
+			name: "Unexpected status",
+			args: args{
+				statusCode: http.StatusTeapot,
+			},
+			wantNotIs:  []error{ErrBadRequest, ErrServer},
+			wantString: "rustmaps api: 418 I'm a teapot",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.args.statusCode, Header: http.Header{}}
+			if tt.args.requestID != "" {
+				resp.Header.Set("X-Request-Id", tt.args.requestID)
+			}
+			var err error = newError(resp, []byte(tt.args.body))
+			for _, target := range tt.wantIs {
+				if !errors.Is(err, target) {
+					t.Errorf("newError() = %v, want errors.Is %v", err, target)
+				}
+			}
+			for _, target := range tt.wantNotIs {
+				if errors.Is(err, target) {
+					t.Errorf("newError() = %v, want not errors.Is %v", err, target)
+				}
+			}
+			if got := err.Error(); got != tt.wantString {
+				t.Errorf("Error.Error() = %v, want %v", got, tt.wantString)
+			}
+			var apiErr *Error
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.args.statusCode {
+				t.Errorf("newError() = %v, want *Error with status %v", err, tt.args.statusCode)
+			}
+		})
+	}
+}
@@ -2,15 +2,13 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"time"
 
-	"github.com/maintc/rustmaps-cli/pkg/common"
-	"github.com/maintc/rustmaps-cli/pkg/types"
-
 	"go.uber.org/zap"
 )
 
@@ -44,184 +42,92 @@ type RustMapsGenerateCustomRequest struct {
 	ConfigName    string                            `json:"configName"`
 }
 
-func (c *RustMapsClient) GenerateCustom(log *zap.Logger, m *types.Map) (*RustMapsGenerateResponse, error) {
-	c.rateLimiter.Wait()
-	// Create a client with custom timeouts
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+// GenerateRequest describes a map to generate. ConfigName is the saved
+// config used by GenerateCustom, GenerateProcedural ignores it.
+type GenerateRequest struct {
+	Seed       string
+	Size       int
+	Staging    bool
+	ConfigName string
+}
 
+func (r GenerateRequest) fields() []zap.Field {
+	return []zap.Field{zap.String("seed", r.Seed), zap.Int("size", r.Size), zap.String("config", r.ConfigName), zap.Bool("staging", r.Staging)}
+}
+
+func (c *RustMapsClient) GenerateCustom(ctx context.Context, log *zap.Logger, r GenerateRequest) (*RustMapsGenerateResponse, error) {
 	data := RustMapsGenerateCustomRequest{
 		MapParameters: RustMapsGenerateProceduralRequest{
-			Size:    m.Size,
-			Seed:    m.Seed,
-			Staging: m.Staging,
+			Size:    r.Size,
+			Seed:    r.Seed,
+			Staging: r.Staging,
 		},
-		ConfigName: m.SavedConfig,
-	}
-
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return nil, err
-	}
-
-	// Create request
-	log.Debug("Generating custom map", zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.String("config", m.SavedConfig), zap.Bool("staging", m.Staging))
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/maps/custom/saved-config", c.ApiUrl), bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, err
-	}
-
-	// Add headers
-	req.Header.Set("X-API-Key", c.apiKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	// Make request
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Error("Error making request", zap.Error(err))
-		return nil, err
+		ConfigName: r.ConfigName,
 	}
-	defer resp.Body.Close()
-
-	log.Debug("Response status", zap.Int("status", resp.StatusCode), zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.String("config", m.SavedConfig), zap.Bool("staging", m.Staging))
-
-	// Read response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Error("Error reading response", zap.Error(err))
-		return nil, err
-	}
-
-	log.Debug("Response body", zap.String("body", string(body)), zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.String("config", m.SavedConfig), zap.Bool("staging", m.Staging))
 
-	switch resp.StatusCode {
-	case http.StatusUnauthorized:
-		log.Error("Unauthorized request", zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.Bool("staging", m.Staging), zap.String("config", m.SavedConfig), zap.Bool("staging", m.Staging))
-		m.ReportStatus(common.StatusUnauthorized)
-		return nil, fmt.Errorf(common.StatusUnauthorized)
-	case http.StatusForbidden:
-		log.Error("Forbidden request", zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.Bool("staging", m.Staging), zap.String("config", m.SavedConfig), zap.Bool("staging", m.Staging))
-		m.ReportStatus(common.StatusForbidden)
-		return nil, fmt.Errorf(common.StatusForbidden)
-	case http.StatusConflict:
-		log.Debug("Map already generating", zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.Bool("staging", m.Staging), zap.String("config", m.SavedConfig), zap.Bool("staging", m.Staging))
-		m.ReportStatus(common.StatusGenerating)
-		return nil, nil
-	}
-
-	var generateResponse RustMapsGenerateResponse
-	if err := json.Unmarshal(body, &generateResponse); err != nil {
-		return nil, err
-	}
-
-	m.MapID = generateResponse.Data.MapID
-	switch resp.StatusCode {
-	case http.StatusOK:
-		log.Debug("Map generated", zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.Bool("staging", m.Staging), zap.String("config", m.SavedConfig), zap.Bool("staging", m.Staging))
-		m.ReportStatus(common.StatusComplete)
-		return &generateResponse, nil
-	case 201:
-		log.Debug("Map generating", zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.Bool("staging", m.Staging), zap.String("config", m.SavedConfig), zap.Bool("staging", m.Staging))
-		m.ReportStatus(common.StatusGenerating)
-		return &generateResponse, nil
-	case http.StatusBadRequest:
-		log.Error("Bad request", zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.Bool("staging", m.Staging), zap.String("config", m.SavedConfig), zap.Bool("staging", m.Staging))
-		if len(generateResponse.Meta.Errors) > 0 && generateResponse.Meta.Errors[0] == "Staging is not enabled" {
-			m.ReportStatus(common.StatusStagingNotEnabled)
-		} else {
-			m.ReportStatus(common.StatusBadRequest)
-		}
-		return nil, fmt.Errorf(common.StatusBadRequest)
-	}
-
-	return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	log.Debug("Generating custom map", r.fields()...)
+	return c.generate(ctx, log, r, fmt.Sprintf("%s/maps/custom/saved-config", c.ApiUrl), data)
 }
 
-func (c *RustMapsClient) GenerateProcedural(log *zap.Logger, m *types.Map) (*RustMapsGenerateResponse, error) {
-	c.rateLimiter.Wait()
-	// Create a client with custom timeouts
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
+func (c *RustMapsClient) GenerateProcedural(ctx context.Context, log *zap.Logger, r GenerateRequest) (*RustMapsGenerateResponse, error) {
 	data := RustMapsGenerateProceduralRequest{
-		Size:    m.Size,
-		Seed:    m.Seed,
-		Staging: m.Staging,
+		Size:    r.Size,
+		Seed:    r.Seed,
+		Staging: r.Staging,
 	}
 
+	log.Debug("Generating procedural map", r.fields()...)
+	return c.generate(ctx, log, r, fmt.Sprintf("%s/maps", c.ApiUrl), data)
+}
+
+// generate posts a generate request to url. A map that is already
+// generating is returned as an *Error matching ErrConflict.
+func (c *RustMapsClient) generate(ctx context.Context, log *zap.Logger, r GenerateRequest, url string, data any) (*RustMapsGenerateResponse, error) {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return nil, err
 	}
 
-	// Create request
-	log.Debug("Generating procedural map", zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.Bool("staging", m.Staging))
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/maps", c.ApiUrl), bytes.NewBuffer(jsonData))
+	// Create request, duplicates are rejected with 409 so retrying is safe
+	req, err := http.NewRequestWithContext(WithConflictSafe(ctx), "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, err
 	}
 
-	// Add headers
-	req.Header.Set("X-API-Key", c.apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	// Make request
-	resp, err := client.Do(req)
+	resp, body, err := c.do(ctx, log, req)
 	if err != nil {
-		log.Error("Error making request", zap.Error(err))
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	log.Debug("Response status", zap.Int("status", resp.StatusCode), zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.String("config", m.SavedConfig), zap.Bool("staging", m.Staging))
 
-	// Read response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Error("Error reading response", zap.Error(err))
-		return nil, err
+	generateResponse, err := decodeGenerateResponse(resp, body)
+	switch {
+	case err == nil:
+		log.Debug("Map submitted", append(r.fields(), zap.Int("status", resp.StatusCode), zap.String("map_id", generateResponse.Data.MapID))...)
+	case errors.Is(err, ErrConflict):
+		log.Debug("Map already generating", r.fields()...)
+	default:
+		log.Error("Error generating map", append(r.fields(), zap.Error(err))...)
 	}
+	return generateResponse, err
+}
 
-	log.Debug("Response body", zap.String("body", string(body)), zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.String("config", m.SavedConfig), zap.Bool("staging", m.Staging))
-
+// decodeGenerateResponse decodes the body of a generate call. 200 means the
+// map already exists and 201 that it was queued, anything else is an *Error.
+func decodeGenerateResponse(resp *http.Response, body []byte) (*RustMapsGenerateResponse, error) {
 	switch resp.StatusCode {
-	case http.StatusBadRequest:
-		log.Error("Bad request", zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.Bool("staging", m.Staging))
-		m.ReportStatus(common.StatusBadRequest)
-		return nil, fmt.Errorf(common.StatusBadRequest)
-	case http.StatusUnauthorized:
-		log.Error("Unauthorized request", zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.Bool("staging", m.Staging))
-		m.ReportStatus(common.StatusUnauthorized)
-		return nil, fmt.Errorf(common.StatusUnauthorized)
-	case http.StatusForbidden:
-		log.Error("Forbidden request", zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.Bool("staging", m.Staging))
-		m.ReportStatus(common.StatusForbidden)
-		return nil, fmt.Errorf(common.StatusForbidden)
-	case http.StatusConflict:
-		log.Debug("Map already generating", zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.Bool("staging", m.Staging))
-		m.ReportStatus(common.StatusGenerating)
-		return nil, nil
+	case http.StatusOK, http.StatusCreated:
+	default:
+		return nil, newError(resp, body)
 	}
 
 	var generateResponse RustMapsGenerateResponse
 	if err := json.Unmarshal(body, &generateResponse); err != nil {
 		return nil, err
 	}
-
-	m.MapID = generateResponse.Data.MapID
-
-	switch resp.StatusCode {
-	case http.StatusOK:
-		log.Debug("Map generated", zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.Bool("staging", m.Staging))
-		m.ReportStatus(common.StatusComplete)
-		return &generateResponse, nil
-	case 201:
-		log.Debug("Map generating", zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.Bool("staging", m.Staging))
-		m.ReportStatus(common.StatusGenerating)
-		return &generateResponse, nil
-	}
-
-	return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	// Callers tell queued from existing maps by the status code, make sure
+	// it matches the response
+	generateResponse.Meta.StatusCode = resp.StatusCode
+	return &generateResponse, nil
 }
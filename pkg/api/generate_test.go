@@ -1,13 +1,14 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
-	"github.com/maintc/rustmaps-cli/pkg/types"
 	"go.uber.org/zap"
 )
 
@@ -73,6 +74,23 @@ func TestRustMapsClient_GenerateCustom(t *testing.T) {
 							Errors:     []string{},
 						},
 					}
+				} else if req.MapParameters.Seed == "6" {
+					response = &RustMapsGenerateResponse{
+						Meta: RustMapsGenerateResponseMeta{
+							Status:     "error",
+							StatusCode: 400,
+							Errors:     []string{"Staging is not enabled"},
+						},
+					}
+				} else if req.MapParameters.Seed == "5" {
+					w.Header().Set("Retry-After", "30")
+					response = &RustMapsGenerateResponse{
+						Meta: RustMapsGenerateResponseMeta{
+							Status:     "error",
+							StatusCode: 429,
+							Errors:     []string{"Too many requests"},
+						},
+					}
 				} else {
 					response = &RustMapsGenerateResponse{
 						Meta: RustMapsGenerateResponseMeta{
@@ -97,7 +115,7 @@ func TestRustMapsClient_GenerateCustom(t *testing.T) {
 	}
 	type args struct {
 		log *zap.Logger
-		m   *types.Map
+		r   GenerateRequest
 	}
 	tests := []struct {
 		name    string
@@ -105,6 +123,8 @@ func TestRustMapsClient_GenerateCustom(t *testing.T) {
 		args    args
 		want    *RustMapsGenerateResponse
 		wantErr bool
+		// wantErrIs is the sentinel the error must match, if checked
+		wantErrIs error
 	}{
 		{
 			name: "GenerateCustom 200",
@@ -115,11 +135,11 @@ func TestRustMapsClient_GenerateCustom(t *testing.T) {
 			},
 			args: args{
 				log: zap.NewNop(),
-				m: &types.Map{
-					Size:        3500,
-					Seed:        "1",
-					Staging:     false,
-					SavedConfig: "default",
+				r: GenerateRequest{
+					Size:       3500,
+					Seed:       "1",
+					Staging:    false,
+					ConfigName: "default",
 				},
 			},
 			want: &RustMapsGenerateResponse{
@@ -139,11 +159,11 @@ func TestRustMapsClient_GenerateCustom(t *testing.T) {
 			},
 			args: args{
 				log: zap.NewNop(),
-				m: &types.Map{
-					Size:        3500,
-					Seed:        "1111",
-					Staging:     false,
-					SavedConfig: "default",
+				r: GenerateRequest{
+					Size:       3500,
+					Seed:       "1111",
+					Staging:    false,
+					ConfigName: "default",
 				},
 			},
 			want: &RustMapsGenerateResponse{
@@ -163,11 +183,11 @@ func TestRustMapsClient_GenerateCustom(t *testing.T) {
 			},
 			args: args{
 				log: zap.NewNop(),
-				m: &types.Map{
-					Size:        3500,
-					Seed:        "11",
-					Staging:     false,
-					SavedConfig: "default",
+				r: GenerateRequest{
+					Size:       3500,
+					Seed:       "11",
+					Staging:    false,
+					ConfigName: "default",
 				},
 			},
 			want: &RustMapsGenerateResponse{
@@ -177,7 +197,8 @@ func TestRustMapsClient_GenerateCustom(t *testing.T) {
 					Errors:     []string{},
 				},
 			},
-			wantErr: true,
+			wantErr:   true,
+			wantErrIs: ErrBadRequest,
 		},
 		{
 			name: "GenerateCustom 401",
@@ -188,11 +209,11 @@ func TestRustMapsClient_GenerateCustom(t *testing.T) {
 			},
 			args: args{
 				log: zap.NewNop(),
-				m: &types.Map{
-					Size:        3500,
-					Seed:        "2",
-					Staging:     false,
-					SavedConfig: "default",
+				r: GenerateRequest{
+					Size:       3500,
+					Seed:       "2",
+					Staging:    false,
+					ConfigName: "default",
 				},
 			},
 			want: &RustMapsGenerateResponse{
@@ -202,7 +223,8 @@ func TestRustMapsClient_GenerateCustom(t *testing.T) {
 					Errors:     []string{},
 				},
 			},
-			wantErr: true,
+			wantErr:   true,
+			wantErrIs: ErrUnauthorized,
 		},
 		{
 			name: "GenerateCustom 403",
@@ -213,11 +235,11 @@ func TestRustMapsClient_GenerateCustom(t *testing.T) {
 			},
 			args: args{
 				log: zap.NewNop(),
-				m: &types.Map{
-					Size:        3500,
-					Seed:        "3",
-					Staging:     false,
-					SavedConfig: "default",
+				r: GenerateRequest{
+					Size:       3500,
+					Seed:       "3",
+					Staging:    false,
+					ConfigName: "default",
 				},
 			},
 			want: &RustMapsGenerateResponse{
@@ -227,7 +249,27 @@ func TestRustMapsClient_GenerateCustom(t *testing.T) {
 					Errors:     []string{},
 				},
 			},
-			wantErr: true,
+			wantErr:   true,
+			wantErrIs: ErrForbidden,
+		},
+		{
+			name: "GenerateCustom 400 staging not enabled",
+			fields: fields{
+				apiURL:      mockServer.URL,
+				apiKey:      "test",
+				rateLimiter: &RateLimiter{},
+			},
+			args: args{
+				log: zap.NewNop(),
+				r: GenerateRequest{
+					Size:       3500,
+					Seed:       "6",
+					Staging:    true,
+					ConfigName: "default",
+				},
+			},
+			wantErr:   true,
+			wantErrIs: ErrStagingNotEnabled,
 		},
 		{
 			name: "GenerateCustom 409",
@@ -238,15 +280,36 @@ func TestRustMapsClient_GenerateCustom(t *testing.T) {
 			},
 			args: args{
 				log: zap.NewNop(),
-				m: &types.Map{
-					Size:        3500,
-					Seed:        "4",
-					Staging:     false,
-					SavedConfig: "default",
+				r: GenerateRequest{
+					Size:       3500,
+					Seed:       "4",
+					Staging:    false,
+					ConfigName: "default",
 				},
 			},
-			want:    nil,
-			wantErr: false,
+			want:      nil,
+			wantErr:   true,
+			wantErrIs: ErrConflict,
+		},
+		{
+			name: "GenerateCustom 429",
+			fields: fields{
+				apiURL:      mockServer.URL,
+				apiKey:      "test",
+				rateLimiter: &RateLimiter{},
+			},
+			args: args{
+				log: zap.NewNop(),
+				r: GenerateRequest{
+					Size:       3500,
+					Seed:       "5",
+					Staging:    false,
+					ConfigName: "default",
+				},
+			},
+			want:      nil,
+			wantErr:   true,
+			wantErrIs: ErrRateLimited,
 		},
 		{
 			name: "GenerateCustom 500",
@@ -257,11 +320,11 @@ func TestRustMapsClient_GenerateCustom(t *testing.T) {
 			},
 			args: args{
 				log: zap.NewNop(),
-				m: &types.Map{
-					Size:        3500,
-					Seed:        "111",
-					Staging:     false,
-					SavedConfig: "default",
+				r: GenerateRequest{
+					Size:       3500,
+					Seed:       "111",
+					Staging:    false,
+					ConfigName: "default",
 				},
 			},
 			want: &RustMapsGenerateResponse{
@@ -271,7 +334,8 @@ func TestRustMapsClient_GenerateCustom(t *testing.T) {
 					Errors:     []string{},
 				},
 			},
-			wantErr: true,
+			wantErr:   true,
+			wantErrIs: ErrServer,
 		},
 		{
 			name: "GenerateCustom 500 part 2",
@@ -282,11 +346,11 @@ func TestRustMapsClient_GenerateCustom(t *testing.T) {
 			},
 			args: args{
 				log: zap.NewNop(),
-				m: &types.Map{
-					Size:        3500,
-					Seed:        "100",
-					Staging:     false,
-					SavedConfig: "default",
+				r: GenerateRequest{
+					Size:       3500,
+					Seed:       "100",
+					Staging:    false,
+					ConfigName: "default",
 				},
 			},
 			wantErr: true,
@@ -299,11 +363,14 @@ func TestRustMapsClient_GenerateCustom(t *testing.T) {
 				apiKey:      tt.fields.apiKey,
 				rateLimiter: tt.fields.rateLimiter,
 			}
-			got, err := c.GenerateCustom(tt.args.log, tt.args.m)
+			got, err := c.GenerateCustom(context.Background(), tt.args.log, tt.args.r)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("RustMapsClient.GenerateCustom() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if tt.wantErrIs != nil && !errors.Is(err, tt.wantErrIs) {
+				t.Errorf("RustMapsClient.GenerateCustom() error = %v, want %v", err, tt.wantErrIs)
+			}
 			if got != nil && got.Meta.StatusCode != tt.want.Meta.StatusCode {
 				t.Errorf("RustMapsClient.GenerateCustom() = %v, want %v", got, tt.want)
 			}
@@ -338,7 +405,7 @@ func TestRustMapsClient_GenerateProcedural(t *testing.T) {
 	}
 	type args struct {
 		log *zap.Logger
-		m   *types.Map
+		r   GenerateRequest
 	}
 	tests := []struct {
 		name    string
@@ -356,7 +423,7 @@ func TestRustMapsClient_GenerateProcedural(t *testing.T) {
 			},
 			args: args{
 				log: zap.NewNop(),
-				m: &types.Map{
+				r: GenerateRequest{
 					Size:    3500,
 					Seed:    "1",
 					Staging: false,
@@ -378,7 +445,7 @@ func TestRustMapsClient_GenerateProcedural(t *testing.T) {
 				apiKey:      tt.fields.apiKey,
 				rateLimiter: tt.fields.rateLimiter,
 			}
-			got, err := c.GenerateProcedural(tt.args.log, tt.args.m)
+			got, err := c.GenerateProcedural(context.Background(), tt.args.log, tt.args.r)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("RustMapsClient.GenerateProcedural() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -1,11 +1,10 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"time"
 
 	"go.uber.org/zap"
 )
@@ -36,49 +35,26 @@ type RustMapsLimitsResponse struct {
 	Data RustMapsLimitsResponseData `json:"data"`
 }
 
-func (c *RustMapsClient) GetLimits(log *zap.Logger) (*RustMapsLimitsResponse, error) {
-	c.rateLimiter.Wait()
-	// Create a client with custom timeouts
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
+func (c *RustMapsClient) GetLimits(ctx context.Context, log *zap.Logger) (*RustMapsLimitsResponse, error) {
 	// Create request
 	log.Debug("GET /maps/limits - Getting API limits")
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/maps/limits", c.ApiUrl), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/maps/limits", c.ApiUrl), nil)
 	if err != nil {
 		log.Error("Error creating request", zap.Error(err))
 		return nil, err
 	}
 
-	// Add headers
-	req.Header.Set("X-API-Key", c.apiKey)
-
-	// Make request
-	resp, err := client.Do(req)
+	resp, body, err := c.do(ctx, log, req)
 	if err != nil {
-		log.Error("Error making request", zap.Error(err))
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	log.Debug("Response status", zap.Int("status", resp.StatusCode))
 
-	// Read response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Error("Error reading response", zap.Error(err))
+	if resp.StatusCode != http.StatusOK {
+		err := newError(resp, body)
+		log.Error("Error getting limits", zap.Error(err))
 		return nil, err
 	}
 
-	log.Debug("Response body", zap.String("body", string(body)))
-
-	switch resp.StatusCode {
-	case http.StatusUnauthorized:
-		log.Error("Unauthorized request")
-		return nil, fmt.Errorf("unauthorized")
-	}
-
 	limits := &RustMapsLimitsResponse{}
 	if err := json.Unmarshal(body, limits); err != nil {
 		log.Error("Error unmarshalling response", zap.Error(err))
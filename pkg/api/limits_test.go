@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -71,7 +72,7 @@ func TestRustMapsClient_GetLimits(t *testing.T) {
 				apiKey:      tt.fields.apiKey,
 				rateLimiter: tt.fields.rateLimiter,
 			}
-			got, err := c.GetLimits(tt.args.log)
+			got, err := c.GetLimits(context.Background(), tt.args.log)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("RustMapsClient.GetLimits() error = %v, wantErr %v", err, tt.wantErr)
 				return
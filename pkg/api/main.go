@@ -1,67 +1,150 @@
 package api
 
 import (
-	"sync"
+	"context"
+	"io"
+	"net/http"
+	"strings"
 	"time"
 
-	"github.com/maintc/rustmaps-cli/pkg/types"
 	"go.uber.org/zap"
 )
 
 type RustMapsClientBase interface {
-	GetStatus(log *zap.Logger, m *types.Map) (*RustMapsStatusResponse, error)
+	GetStatus(ctx context.Context, log *zap.Logger, r StatusRequest) (*RustMapsStatusResponse, error)
 	SetApiKey(apiKey string)
-	GetLimits(log *zap.Logger) (*RustMapsLimitsResponse, error)
-	GenerateCustom(log *zap.Logger, m *types.Map) (*RustMapsGenerateResponse, error)
-	GenerateProcedural(log *zap.Logger, m *types.Map) (*RustMapsGenerateResponse, error)
+	GetLimits(ctx context.Context, log *zap.Logger) (*RustMapsLimitsResponse, error)
+	GenerateCustom(ctx context.Context, log *zap.Logger, r GenerateRequest) (*RustMapsGenerateResponse, error)
+	GenerateProcedural(ctx context.Context, log *zap.Logger, r GenerateRequest) (*RustMapsGenerateResponse, error)
 }
 
+// DefaultApiUrl is the RustMaps v4 API
+const DefaultApiUrl = "https://api.rustmaps.com/v4"
+
+// DefaultUserAgent is sent with every API request unless overridden
+const DefaultUserAgent = "rustmaps-cli"
+
 type RustMapsClient struct {
 	RustMapsClientBase
 	ApiUrl      string
 	apiKey      string
 	rateLimiter *RateLimiter
+	retryPolicy RetryPolicy
+	// httpClient is shared by all calls so connections are reused
+	httpClient *http.Client
+	// timeout overrides the retry policy's per attempt timeout
+	timeout   time.Duration
+	userAgent string
+}
+
+// Option configures a RustMapsClient
+type Option func(*RustMapsClient)
+
+// WithRetryPolicy replaces DefaultRetryPolicy
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *RustMapsClient) {
+		c.retryPolicy = policy
+	}
+}
+
+// WithRateLimiter replaces the default rate limiter
+func WithRateLimiter(rateLimiter *RateLimiter) Option {
+	return func(c *RustMapsClient) {
+		c.rateLimiter = rateLimiter
+	}
+}
+
+// WithHTTPClient replaces the default http.Client, for example to route
+// requests through a proxy or a test transport. Retries are layered on top of
+// its transport.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *RustMapsClient) {
+		c.httpClient = httpClient
+	}
 }
 
-func NewRustMapsClient(apiKey string) RustMapsClientBase {
-	return &RustMapsClient{
-		ApiUrl:      "https://api.rustmaps.com/v4",
+// WithTimeout bounds each request attempt
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *RustMapsClient) {
+		c.timeout = timeout
+	}
+}
+
+// WithUserAgent replaces DefaultUserAgent
+func WithUserAgent(userAgent string) Option {
+	return func(c *RustMapsClient) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithBaseURL replaces DefaultApiUrl
+func WithBaseURL(baseURL string) Option {
+	return func(c *RustMapsClient) {
+		c.ApiUrl = strings.TrimRight(baseURL, "/")
+	}
+}
+
+func NewRustMapsClient(apiKey string, opts ...Option) RustMapsClientBase {
+	c := &RustMapsClient{
+		ApiUrl:      DefaultApiUrl,
 		apiKey:      apiKey,
-		rateLimiter: NewRateLimiter(60),
+		rateLimiter: NewRateLimiter(DefaultCallsPerMinute, DefaultBurst),
+		retryPolicy: DefaultRetryPolicy,
+		httpClient:  &http.Client{},
+		userAgent:   DefaultUserAgent,
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
 }
 
 func (r *RustMapsClient) SetApiKey(apiKey string) {
 	r.apiKey = apiKey
 }
 
-// RateLimiter manages API request timing
-type RateLimiter struct {
-	callsPerMinute int
-	interval       time.Duration
-	lastCall       time.Time
-	mu             sync.Mutex
-}
+// do sends req once the rate limiter allows it and returns the response
+// along with its fully read body. Every attempt waits for the rate limiter
+// and its status is fed back into it so it can slow down or recover.
+func (c *RustMapsClient) do(ctx context.Context, log *zap.Logger, req *http.Request) (*http.Response, []byte, error) {
+	// Transient failures are retried on top of the shared client's transport,
+	// each attempt has its own timeout
+	client := http.Client{}
+	if c.httpClient != nil {
+		client = *c.httpClient
+	}
+	policy := c.retryPolicy
+	if c.timeout > 0 {
+		policy.AttemptTimeout = c.timeout
+	}
+	transport := NewRetryTransport(client.Transport, policy)
+	transport.Limiter = c.rateLimiter
+	client.Transport = transport
 
-// NewRateLimiter creates a new rate limiter
-func NewRateLimiter(callsPerMinute int) *RateLimiter {
-	return &RateLimiter{
-		callsPerMinute: callsPerMinute,
-		interval:       time.Minute / time.Duration(callsPerMinute),
+	// Add headers
+	req.Header.Set("X-API-Key", c.apiKey)
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
 	}
-}
 
-// Wait ensures enough time has passed since the last call
-func (r *RateLimiter) Wait() {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	now := time.Now()
-	if !r.lastCall.IsZero() {
-		timePassed := now.Sub(r.lastCall)
-		if timePassed < r.interval {
-			time.Sleep(r.interval - timePassed)
-		}
+	// Make request
+	resp, err := client.Do(req.WithContext(WithLogger(req.Context(), log)))
+	if err != nil {
+		log.Error("Error making request", zap.Error(err))
+		return nil, nil, err
 	}
-	r.lastCall = time.Now()
+	defer resp.Body.Close()
+
+	log.Debug("Response status", zap.String("method", req.Method), zap.String("url", req.URL.String()), zap.Int("status", resp.StatusCode))
+
+	// Read response
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Error("Error reading response", zap.Error(err))
+		return nil, nil, err
+	}
+
+	log.Debug("Response body", zap.String("body", string(body)))
+
+	return resp, body, nil
 }
@@ -1,15 +1,21 @@
 package api
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
-	"sync"
 	"testing"
 	"time"
+
+	"go.uber.org/zap"
 )
 
 func TestNewRustMapsClient(t *testing.T) {
+	httpClient := &http.Client{}
 	type args struct {
 		apiKey string
+		opts   []Option
 	}
 	tests := []struct {
 		name string
@@ -22,18 +28,39 @@ func TestNewRustMapsClient(t *testing.T) {
 				apiKey: "test",
 			},
 			want: &RustMapsClient{
-				ApiUrl: "https://api.rustmaps.com/v4",
+				ApiUrl:      "https://api.rustmaps.com/v4",
+				apiKey:      "test",
+				rateLimiter: NewRateLimiter(DefaultCallsPerMinute, DefaultBurst),
+				retryPolicy: DefaultRetryPolicy,
+				httpClient:  &http.Client{},
+				userAgent:   DefaultUserAgent,
+			},
+		},
+		{
+			name: "NewRustMapsClient with options",
+			args: args{
 				apiKey: "test",
-				rateLimiter: &RateLimiter{
-					callsPerMinute: 60,
-					interval:       time.Minute / time.Duration(60),
+				opts: []Option{
+					WithHTTPClient(httpClient),
+					WithTimeout(time.Minute),
+					WithUserAgent("mainloot/1.0"),
+					WithBaseURL("http://localhost:8080/v4/"),
 				},
 			},
+			want: &RustMapsClient{
+				ApiUrl:      "http://localhost:8080/v4",
+				apiKey:      "test",
+				rateLimiter: NewRateLimiter(DefaultCallsPerMinute, DefaultBurst),
+				retryPolicy: DefaultRetryPolicy,
+				httpClient:  httpClient,
+				timeout:     time.Minute,
+				userAgent:   "mainloot/1.0",
+			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := NewRustMapsClient(tt.args.apiKey); !reflect.DeepEqual(got, tt.want) {
+			if got := NewRustMapsClient(tt.args.apiKey, tt.args.opts...); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("NewRustMapsClient() = %v, want %v", got, tt.want)
 			}
 		})
@@ -71,54 +98,54 @@ func TestRustMapsClient_SetApiKey(t *testing.T) {
 	}
 }
 
-func TestNewRateLimiter(t *testing.T) {
-	type args struct {
-		callsPerMinute int
-	}
-	tests := []struct {
-		name string
-		args args
-		want *RateLimiter
-	}{
-		// TODO: Add test cases.
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := NewRateLimiter(tt.args.callsPerMinute); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("NewRateLimiter() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
+func TestRustMapsClient_do(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Seen-User-Agent", r.UserAgent())
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mockServer.Close()
 
-func TestRateLimiter_Wait(t *testing.T) {
-	type fields struct {
-		callsPerMinute int
-		interval       time.Duration
-		lastCall       time.Time
-		mu             sync.Mutex
+	// injected counts requests so the test can tell it was used
+	var calls int
+	injected := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			calls++
+			return http.DefaultTransport.RoundTrip(req)
+		}),
 	}
+
 	tests := []struct {
-		name   string
-		fields fields
+		name          string
+		opts          []Option
+		wantUserAgent string
+		wantCalls     int
 	}{
 		{
-			name: "Wait",
-			fields: fields{
-				lastCall: time.Now(),
-				interval: 100000000,
-			},
+			name:          "Default client",
+			wantUserAgent: DefaultUserAgent,
+		},
+		{
+			name:          "Injected client and user agent",
+			opts:          []Option{WithHTTPClient(injected), WithUserAgent("mainloot/1.0")},
+			wantUserAgent: "mainloot/1.0",
+			wantCalls:     1,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			r := &RateLimiter{
-				callsPerMinute: tt.fields.callsPerMinute,
-				interval:       tt.fields.interval,
-				lastCall:       tt.fields.lastCall,
-				mu:             tt.fields.mu,
+			calls = 0
+			c := NewRustMapsClient("test", tt.opts...).(*RustMapsClient)
+			req, _ := http.NewRequestWithContext(context.Background(), "GET", mockServer.URL, nil)
+			resp, _, err := c.do(context.Background(), zap.NewNop(), req)
+			if err != nil {
+				t.Fatalf("RustMapsClient.do() error = %v", err)
+			}
+			if got := resp.Header.Get("X-Seen-User-Agent"); got != tt.wantUserAgent {
+				t.Errorf("RustMapsClient.do() user agent = %v, want %v", got, tt.wantUserAgent)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("RustMapsClient.do() injected client calls = %v, want %v", calls, tt.wantCalls)
 			}
-			r.Wait()
 		})
 	}
 }
@@ -0,0 +1,407 @@
+// Package mockserver emulates the parts of the RustMaps v4 API used by
+// rustmaps-cli, so map generation can be rehearsed offline without spending
+// any of the monthly quota
+package mockserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/api"
+)
+
+const (
+	// APIPrefix is where the API is served, point api_url at host + APIPrefix
+	APIPrefix = "/v4"
+	// filesPrefix serves the generated map assets
+	filesPrefix = "/files"
+
+	minSize = 1000
+	maxSize = 6000
+)
+
+// Options controls the behaviour of a Server
+type Options struct {
+	// APIKey, when set, is the only X-API-Key accepted, others get 401
+	APIKey string
+	// ConcurrentLimit is how many maps may generate at once
+	ConcurrentLimit int
+	// MonthlyLimit is how many maps may be submitted in total. It also
+	// decides the tier the CLI reports, 800 is Premium.
+	MonthlyLimit int
+	// QueueTime is how long a map waits in the queue before generating
+	QueueTime time.Duration
+	// GenerationTime is how long a map takes to generate once it leaves the
+	// queue
+	GenerationTime time.Duration
+	// StagingEnabled allows maps on the staging branch
+	StagingEnabled bool
+	// SavedConfigs lists the custom configs that exist, "default" always does
+	SavedConfigs []string
+}
+
+// DefaultOptions resembles a Premium subscription with quick generation
+func DefaultOptions() Options {
+	return Options{
+		ConcurrentLimit: 2,
+		MonthlyLimit:    800,
+		QueueTime:       5 * time.Second,
+		GenerationTime:  20 * time.Second,
+		StagingEnabled:  true,
+	}
+}
+
+// mockMap is a map submitted to the server
+type mockMap struct {
+	id string
+	// seq orders maps by submission
+	seq         int
+	seed        string
+	size        int
+	staging     bool
+	savedConfig string
+	submittedAt time.Time
+}
+
+// key identifies a map by its parameters, resubmitting the same key is a
+// conflict while it generates
+func (m *mockMap) key() string {
+	return fmt.Sprintf("%d/%s/%t/%s", m.size, m.seed, m.staging, m.savedConfig)
+}
+
+// Server is an http.Handler emulating the RustMaps API
+type Server struct {
+	opts Options
+	mux  *http.ServeMux
+
+	mu     sync.Mutex
+	maps   map[string]*mockMap
+	byKey  map[string]*mockMap
+	nextID int
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// New creates a Server
+func New(opts Options) *Server {
+	s := &Server{
+		opts:  opts,
+		mux:   http.NewServeMux(),
+		maps:  make(map[string]*mockMap),
+		byKey: make(map[string]*mockMap),
+		now:   time.Now,
+	}
+	s.mux.HandleFunc("POST "+APIPrefix+"/maps", s.handleGenerateProcedural)
+	s.mux.HandleFunc("POST "+APIPrefix+"/maps/custom/saved-config", s.handleGenerateCustom)
+	s.mux.HandleFunc("GET "+APIPrefix+"/maps/limits", s.handleLimits)
+	s.mux.HandleFunc("GET "+APIPrefix+"/maps/{id}", s.handleStatusByID)
+	s.mux.HandleFunc("GET "+APIPrefix+"/maps/{size}/{seed}", s.handleStatusBySeed)
+	s.mux.HandleFunc("GET "+filesPrefix+"/{id}/{asset}", s.handleFile)
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Assets are served from a CDN and need no key
+	isFile := strings.HasPrefix(r.URL.Path, filesPrefix+"/")
+	if s.opts.APIKey != "" && r.Header.Get("X-API-Key") != s.opts.APIKey && !isFile {
+		writeMeta(w, http.StatusUnauthorized, "Invalid API key")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// state returns where m is in its lifecycle and, while queued, its position
+// among the other queued maps
+func (s *Server) state(m *mockMap) (state string, queuePosition int) {
+	elapsed := s.now().Sub(m.submittedAt)
+	switch {
+	case elapsed < s.opts.QueueTime:
+		position := 0
+		for _, other := range s.maps {
+			if s.now().Sub(other.submittedAt) < s.opts.QueueTime && other.seq <= m.seq {
+				position++
+			}
+		}
+		return "Queued", max(position, 1)
+	case elapsed < s.opts.QueueTime+s.opts.GenerationTime:
+		return "Generating", 0
+	}
+	return "Complete", 0
+}
+
+// generating counts maps that have not completed yet
+func (s *Server) generating() int {
+	n := 0
+	for _, m := range s.maps {
+		if state, _ := s.state(m); state != "Complete" {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *Server) handleGenerateProcedural(w http.ResponseWriter, r *http.Request) {
+	var req api.RustMapsGenerateProceduralRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeMeta(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	s.generate(w, &mockMap{seed: req.Seed, size: req.Size, staging: req.Staging})
+}
+
+func (s *Server) handleGenerateCustom(w http.ResponseWriter, r *http.Request) {
+	var req api.RustMapsGenerateCustomRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeMeta(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !s.savedConfigExists(req.ConfigName) {
+		writeMeta(w, http.StatusBadRequest, fmt.Sprintf("Saved config %q not found", req.ConfigName))
+		return
+	}
+	s.generate(w, &mockMap{
+		seed:        req.MapParameters.Seed,
+		size:        req.MapParameters.Size,
+		staging:     req.MapParameters.Staging,
+		savedConfig: req.ConfigName,
+	})
+}
+
+func (s *Server) savedConfigExists(name string) bool {
+	if name == "default" {
+		return true
+	}
+	for _, c := range s.opts.SavedConfigs {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// generate validates and queues m, answering like the real API: 201 for a
+// new map, 200 when it already exists and 409 while it is still generating
+func (s *Server) generate(w http.ResponseWriter, m *mockMap) {
+	if _, err := strconv.Atoi(m.seed); err != nil || m.seed == "" {
+		writeMeta(w, http.StatusBadRequest, "Seed must be a number")
+		return
+	}
+	if m.size < minSize || m.size > maxSize {
+		writeMeta(w, http.StatusBadRequest, fmt.Sprintf("Size must be between %d and %d", minSize, maxSize))
+		return
+	}
+	if m.staging && !s.opts.StagingEnabled {
+		writeMeta(w, http.StatusBadRequest, "Staging is not enabled")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.byKey[m.key()]; ok {
+		state, position := s.state(existing)
+		if state != "Complete" {
+			resp := s.generateResponse(existing, http.StatusConflict, state, position)
+			resp.Meta.Errors = []string{"Map is already generating"}
+			writeJSON(w, http.StatusConflict, resp)
+			return
+		}
+		writeJSON(w, http.StatusOK, s.generateResponse(existing, http.StatusOK, state, position))
+		return
+	}
+
+	if len(s.maps) >= s.opts.MonthlyLimit {
+		writeMeta(w, http.StatusForbidden, "Monthly limit reached")
+		return
+	}
+	if s.generating() >= s.opts.ConcurrentLimit {
+		writeMeta(w, http.StatusForbidden, "Concurrent limit reached")
+		return
+	}
+
+	s.nextID++
+	m.seq = s.nextID
+	m.id = fmt.Sprintf("%032x", s.nextID)
+	m.submittedAt = s.now()
+	s.maps[m.id] = m
+	s.byKey[m.key()] = m
+
+	state, position := s.state(m)
+	writeJSON(w, http.StatusCreated, s.generateResponse(m, http.StatusCreated, state, position))
+}
+
+func (s *Server) generateResponse(m *mockMap, code int, state string, position int) api.RustMapsGenerateResponse {
+	return api.RustMapsGenerateResponse{
+		Meta: api.RustMapsGenerateResponseMeta{
+			Status:     http.StatusText(code),
+			StatusCode: code,
+		},
+		Data: api.RustMapsGenerateResponseData{
+			MapID:                m.id,
+			QueuePosition:        position,
+			State:                state,
+			CurrentStep:          state,
+			LastGeneratorPingUtc: s.now().UTC(),
+		},
+	}
+}
+
+func (s *Server) handleLimits(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, api.RustMapsLimitsResponse{
+		Meta: api.RustMapsLimitsResponseMeta{
+			Status:     http.StatusText(http.StatusOK),
+			StatusCode: http.StatusOK,
+		},
+		Data: api.RustMapsLimitsResponseData{
+			Concurrent: api.RustMapsLimitsResponseDataConcurrent{
+				Current: s.generating(),
+				Allowed: s.opts.ConcurrentLimit,
+			},
+			Monthly: api.RustMapsLimitsResponseDataMonthly{
+				Current: len(s.maps),
+				Allowed: s.opts.MonthlyLimit,
+			},
+		},
+	})
+}
+
+func (s *Server) handleStatusByID(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status(w, r, s.maps[r.PathValue("id")])
+}
+
+func (s *Server) handleStatusBySeed(w http.ResponseWriter, r *http.Request) {
+	size, err := strconv.Atoi(r.PathValue("size"))
+	if err != nil {
+		writeMeta(w, http.StatusBadRequest, "Size must be a number")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Lookups by seed only find procedural maps on the release branch
+	m := s.byKey[(&mockMap{seed: r.PathValue("seed"), size: size}).key()]
+	s.status(w, r, m)
+}
+
+// status answers 404 for unknown maps, 409 with the generation progress
+// while generating and the map's details once complete
+func (s *Server) status(w http.ResponseWriter, r *http.Request, m *mockMap) {
+	if m == nil {
+		writeMeta(w, http.StatusNotFound, "Map not found")
+		return
+	}
+	if state, position := s.state(m); state != "Complete" {
+		resp := s.generateResponse(m, http.StatusConflict, state, position)
+		resp.Meta.Errors = []string{"Map is still generating"}
+		writeJSON(w, http.StatusConflict, resp)
+		return
+	}
+
+	seed, _ := strconv.Atoi(m.seed)
+	files := fmt.Sprintf("%s%s/%s", baseURL(r), filesPrefix, m.id)
+	mapType := "Procedural"
+	if m.savedConfig != "" {
+		mapType = "Custom"
+	}
+	writeJSON(w, http.StatusOK, api.RustMapsStatusResponse{
+		Meta: api.RustMapsStatusResponseMeta{
+			Status:     http.StatusText(http.StatusOK),
+			StatusCode: http.StatusOK,
+		},
+		Data: api.RustMapsStatusResponseData{
+			ID:           m.id,
+			Type:         mapType,
+			Seed:         seed,
+			Size:         m.size,
+			URL:          fmt.Sprintf("%s/maps/%s", baseURL(r), m.id),
+			RawImageURL:  files + "/raw.png",
+			ImageURL:     files + "/image.png",
+			ImageIconURL: files + "/icons.png",
+			ThumbnailURL: files + "/thumbnail.png",
+			IsStaging:    m.staging,
+			IsCustomMap:  m.savedConfig != "",
+			CanDownload:  true,
+			DownloadURL:  files + "/map.map",
+			SaveVersion:  1,
+			// Made up from the seed, so every map looks different but
+			// the same map always looks the same
+			TotalMonuments:      2,
+			Monuments:           mockMonuments(seed, m.size),
+			LandPercentageOfMap: 40 + seed%30,
+			BiomePercentages: api.RustMapsStatusResponseDataBiomePercentages{
+				F: 45, D: 25, S: 15, T: 10, J: 5,
+			},
+			Islands:        seed % 5,
+			Mountains:      seed % 7,
+			IceLakes:       seed % 2,
+			Rivers:         1 + seed%4,
+			Lakes:          seed % 3,
+			Canyons:        seed % 4,
+			Oases:          seed % 3,
+			BuildableRocks: seed % 6,
+		},
+	})
+}
+
+// mockMonuments places a couple of monuments on a map of size, spread by
+// seed
+func mockMonuments(seed, size int) []api.RustMapsStatusResponseDataMonuments {
+	half := max(size/2, 1)
+	return []api.RustMapsStatusResponseDataMonuments{
+		{Type: "Airfield_1", Coordinates: api.RustMapsStatusResponseDataCoordinates{X: seed % half, Y: -(seed % half)}},
+		{Type: "Harbor_1", Coordinates: api.RustMapsStatusResponseDataCoordinates{X: -(seed % half), Y: seed % half}},
+	}
+}
+
+func (s *Server) handleFile(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	m, ok := s.maps[r.PathValue("id")]
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	fmt.Fprintf(w, "mock %s for map %s (seed %s, size %d)\n", r.PathValue("asset"), m.id, m.seed, m.size)
+}
+
+// baseURL is the scheme and host the request was made to
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, r.Host)
+}
+
+// writeMeta writes an error response with only the meta section, like the
+// real API does
+func writeMeta(w http.ResponseWriter, code int, errs ...string) {
+	writeJSON(w, code, map[string]any{
+		"meta": api.RustMapsGenerateResponseMeta{
+			Status:     http.StatusText(code),
+			StatusCode: code,
+			Errors:     errs,
+		},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,202 @@
+package mockserver
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/api"
+)
+
+// step is one request in a scenario, after advancing the clock by elapsed
+type step struct {
+	elapsed  time.Duration
+	method   string
+	path     string
+	body     string
+	apiKey   string
+	wantCode int
+	// wantError is expected in meta.errors
+	wantError string
+	// wantState is the generation state expected in data
+	wantState string
+}
+
+func newTestServer(t *testing.T, opts Options) (*httptest.Server, *time.Time) {
+	s := New(opts)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	return server, &now
+}
+
+func TestServer(t *testing.T) {
+	opts := Options{
+		APIKey:          "secret",
+		ConcurrentLimit: 1,
+		MonthlyLimit:    2,
+		QueueTime:       time.Second,
+		GenerationTime:  10 * time.Second,
+		SavedConfigs:    []string{"CombinedOutpost"},
+	}
+	procedural := `{"size":4000,"seed":"1","staging":false}`
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "Procedural map progression",
+			steps: []step{
+				{method: "POST", path: "/v4/maps", body: procedural, wantCode: http.StatusCreated},
+				{method: "POST", path: "/v4/maps", body: procedural, wantCode: http.StatusConflict, wantState: "Queued"},
+				{method: "GET", path: "/v4/maps/4000/1", wantCode: http.StatusConflict, wantState: "Queued"},
+				{elapsed: 2 * time.Second, method: "GET", path: "/v4/maps/4000/1", wantCode: http.StatusConflict, wantState: "Generating"},
+				{elapsed: 11 * time.Second, method: "GET", path: "/v4/maps/4000/1", wantCode: http.StatusOK},
+				{method: "GET", path: "/v4/maps/00000000000000000000000000000001", wantCode: http.StatusOK},
+				{method: "POST", path: "/v4/maps", body: procedural, wantCode: http.StatusOK},
+				{method: "GET", path: "/files/00000000000000000000000000000001/map.map", apiKey: "-", wantCode: http.StatusOK},
+			},
+		},
+		{
+			name: "Limits",
+			steps: []step{
+				{method: "POST", path: "/v4/maps", body: procedural, wantCode: http.StatusCreated},
+				{method: "POST", path: "/v4/maps", body: `{"size":4000,"seed":"2"}`, wantCode: http.StatusForbidden, wantError: "Concurrent limit reached"},
+				{elapsed: 11 * time.Second, method: "POST", path: "/v4/maps", body: `{"size":4000,"seed":"2"}`, wantCode: http.StatusCreated},
+				{elapsed: 11 * time.Second, method: "POST", path: "/v4/maps", body: `{"size":4000,"seed":"3"}`, wantCode: http.StatusForbidden, wantError: "Monthly limit reached"},
+				{method: "GET", path: "/v4/maps/limits", wantCode: http.StatusOK},
+			},
+		},
+		{
+			name: "Custom maps",
+			steps: []step{
+				{method: "POST", path: "/v4/maps/custom/saved-config", body: `{"mapParameters":{"size":4000,"seed":"1"},"configName":"CombinedOutpost"}`, wantCode: http.StatusCreated},
+				{method: "POST", path: "/v4/maps/custom/saved-config", body: `{"mapParameters":{"size":4000,"seed":"1"},"configName":"missing"}`, wantCode: http.StatusBadRequest},
+			},
+		},
+		{
+			name: "Invalid requests",
+			steps: []step{
+				{method: "POST", path: "/v4/maps", body: procedural, apiKey: "wrong", wantCode: http.StatusUnauthorized},
+				{method: "POST", path: "/v4/maps", body: `{"size":4000,"seed":"1","staging":true}`, wantCode: http.StatusBadRequest, wantError: "Staging is not enabled"},
+				{method: "POST", path: "/v4/maps", body: `{"size":100,"seed":"1"}`, wantCode: http.StatusBadRequest},
+				{method: "POST", path: "/v4/maps", body: `{"size":4000,"seed":"abc"}`, wantCode: http.StatusBadRequest},
+				{method: "POST", path: "/v4/maps", body: `not json`, wantCode: http.StatusBadRequest},
+				{method: "GET", path: "/v4/maps/missing", wantCode: http.StatusNotFound},
+				{method: "GET", path: "/files/missing/map.map", wantCode: http.StatusNotFound},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, now := newTestServer(t, opts)
+			for i, st := range tt.steps {
+				*now = now.Add(st.elapsed)
+				req, err := http.NewRequest(st.method, server.URL+st.path, strings.NewReader(st.body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				apiKey := st.apiKey
+				if apiKey == "" {
+					apiKey = opts.APIKey
+				}
+				req.Header.Set("X-API-Key", apiKey)
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if resp.StatusCode != st.wantCode {
+					t.Errorf("step %d %s %s = %v, want %v: %s", i, st.method, st.path, resp.StatusCode, st.wantCode, body)
+				}
+				if st.wantState != "" {
+					var got api.RustMapsGenerateResponse
+					json.Unmarshal(body, &got)
+					if got.Data.State != st.wantState {
+						t.Errorf("step %d state = %v, want %v", i, got.Data.State, st.wantState)
+					}
+				}
+				if st.wantError != "" {
+					var got api.RustMapsGenerateResponse
+					json.Unmarshal(body, &got)
+					if len(got.Meta.Errors) == 0 || got.Meta.Errors[0] != st.wantError {
+						t.Errorf("step %d errors = %v, want %v", i, got.Meta.Errors, st.wantError)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestServer_limits(t *testing.T) {
+	server, now := newTestServer(t, DefaultOptions())
+	http.Post(server.URL+"/v4/maps", "application/json", strings.NewReader(`{"size":4000,"seed":"1"}`))
+	http.Post(server.URL+"/v4/maps", "application/json", strings.NewReader(`{"size":4000,"seed":"2"}`))
+
+	tests := []struct {
+		name           string
+		elapsed        time.Duration
+		wantConcurrent int
+		wantMonthly    int
+	}{
+		{
+			name:           "While generating",
+			wantConcurrent: 2,
+			wantMonthly:    2,
+		},
+		{
+			name:           "After completing",
+			elapsed:        time.Minute,
+			wantConcurrent: 0,
+			wantMonthly:    2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*now = now.Add(tt.elapsed)
+			resp, err := http.Get(server.URL + "/v4/maps/limits")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			var limits api.RustMapsLimitsResponse
+			if err := json.NewDecoder(resp.Body).Decode(&limits); err != nil {
+				t.Fatal(err)
+			}
+			if limits.Data.Concurrent.Current != tt.wantConcurrent {
+				t.Errorf("concurrent = %v, want %v", limits.Data.Concurrent.Current, tt.wantConcurrent)
+			}
+			if limits.Data.Monthly.Current != tt.wantMonthly {
+				t.Errorf("monthly = %v, want %v", limits.Data.Monthly.Current, tt.wantMonthly)
+			}
+			if limits.Data.Monthly.Allowed != 800 {
+				t.Errorf("monthly allowed = %v, want %v", limits.Data.Monthly.Allowed, 800)
+			}
+		})
+	}
+}
+
+func TestServer_queuePosition(t *testing.T) {
+	opts := DefaultOptions()
+	opts.ConcurrentLimit = 3
+	server, _ := newTestServer(t, opts)
+
+	for i, seed := range []string{"1", "2", "3"} {
+		resp, err := http.Post(server.URL+"/v4/maps", "application/json", strings.NewReader(`{"size":4000,"seed":"`+seed+`"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got api.RustMapsGenerateResponse
+		json.NewDecoder(resp.Body).Decode(&got)
+		resp.Body.Close()
+		if got.Data.State != "Queued" || got.Data.QueuePosition != i+1 {
+			t.Errorf("seed %s state = %v position %v, want Queued %v", seed, got.Data.State, got.Data.QueuePosition, i+1)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultCallsPerMinute is the documented RustMaps API rate limit
+	DefaultCallsPerMinute = 60
+	// DefaultBurst is how many calls may be made back to back before the
+	// steady rate applies
+	DefaultBurst = 1
+
+	// defaultRetryAfter is how long to back off after a 429 that carries no
+	// usable Retry-After header
+	defaultRetryAfter = time.Minute
+
+	// slowdownFactor is applied to the current rate after a 429 or 5xx
+	slowdownFactor = 0.5
+	// minRateFraction is the lowest fraction of the configured rate the
+	// limiter will slow down to
+	minRateFraction = 0.1
+	// recoverFraction of the configured rate is added back after every
+	// successful response
+	recoverFraction = 0.05
+)
+
+// RateLimiter is a token bucket that paces API requests. It slows down
+// multiplicatively when the API pushes back and recovers gradually as
+// requests succeed again.
+type RateLimiter struct {
+	baseRate    float64 // configured tokens per second
+	rate        float64 // current tokens per second
+	burst       int
+	tokens      float64
+	lastRefill  time.Time
+	pausedUntil time.Time
+	mu          sync.Mutex
+}
+
+// NewRateLimiter creates a rate limiter allowing callsPerMinute requests on
+// average with bursts of up to burst requests. Non-positive values fall back
+// to DefaultCallsPerMinute and DefaultBurst.
+func NewRateLimiter(callsPerMinute, burst int) *RateLimiter {
+	if callsPerMinute <= 0 {
+		callsPerMinute = DefaultCallsPerMinute
+	}
+	if burst <= 0 {
+		burst = DefaultBurst
+	}
+	rate := float64(callsPerMinute) / 60
+	return &RateLimiter{
+		baseRate: rate,
+		rate:     rate,
+		burst:    burst,
+		tokens:   float64(burst),
+	}
+}
+
+// refill adds the tokens accrued since the last refill, r.mu must be held
+func (r *RateLimiter) refill(now time.Time) {
+	if !r.lastRefill.IsZero() {
+		r.tokens += now.Sub(r.lastRefill).Seconds() * r.rate
+		r.tokens = min(r.tokens, float64(r.burst))
+	}
+	r.lastRefill = now
+}
+
+// Wait blocks until a token is available, returning early with the
+// context's error if it is cancelled while waiting
+func (r *RateLimiter) Wait(ctx context.Context) error {
+	r.mu.Lock()
+	now := time.Now()
+
+	// A zero value limiter only honours pauses
+	reserved := r.rate > 0
+	var delay time.Duration
+	if reserved {
+		r.refill(now)
+		// Reserve a token up front so concurrent callers queue up behind
+		// each other instead of all waking at once
+		r.tokens--
+		if r.tokens < 0 {
+			delay = time.Duration(-r.tokens / r.rate * float64(time.Second))
+		}
+	}
+	if pause := r.pausedUntil.Sub(now); pause > delay {
+		delay = pause
+	}
+	r.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		if reserved {
+			// Hand the reservation back
+			r.mu.Lock()
+			r.tokens++
+			r.mu.Unlock()
+		}
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// PauseUntil holds back every caller of Wait until t has passed
+func (r *RateLimiter) PauseUntil(t time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if t.After(r.pausedUntil) {
+		r.pausedUntil = t
+	}
+}
+
+// Slowdown cuts the current rate after the API answered with 429 or 5xx
+func (r *RateLimiter) Slowdown() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.refill(time.Now())
+	r.rate = max(r.rate*slowdownFactor, r.baseRate*minRateFraction)
+}
+
+// Recover nudges the current rate back towards the configured rate after a
+// successful response
+func (r *RateLimiter) Recover() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.rate >= r.baseRate {
+		return
+	}
+	r.refill(time.Now())
+	r.rate = min(r.rate+r.baseRate*recoverFraction, r.baseRate)
+}
+
+// observe adjusts the limiter to a response: a 429 pauses every caller for
+// as long as the API asked and slows down, so does a 5xx, anything else
+// successful recovers
+func (r *RateLimiter) observe(log *zap.Logger, resp *http.Response) {
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		wait := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		log.Warn("Rate limited by RustMaps API", zap.Duration("retry_after", wait))
+		r.PauseUntil(time.Now().Add(wait))
+		r.Slowdown()
+	case resp.StatusCode >= http.StatusInternalServerError:
+		r.Slowdown()
+	case resp.StatusCode < http.StatusBadRequest:
+		r.Recover()
+	}
+}
+
+// parseRetryAfter interprets a Retry-After header value, which is either a
+// number of seconds or an HTTP-date, returning how long to wait from now
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return defaultRetryAfter
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		return max(t.Sub(now), 0)
+	}
+
+	return defaultRetryAfter
+}
@@ -0,0 +1,252 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewRateLimiter(t *testing.T) {
+	type args struct {
+		callsPerMinute int
+		burst          int
+	}
+	tests := []struct {
+		name string
+		args args
+		want *RateLimiter
+	}{
+		{
+			name: "NewRateLimiter",
+			args: args{
+				callsPerMinute: 120,
+				burst:          5,
+			},
+			want: &RateLimiter{
+				baseRate: 2,
+				rate:     2,
+				burst:    5,
+				tokens:   5,
+			},
+		},
+		{
+			name: "NewRateLimiter defaults",
+			args: args{},
+			want: &RateLimiter{
+				baseRate: 1,
+				rate:     1,
+				burst:    DefaultBurst,
+				tokens:   DefaultBurst,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewRateLimiter(tt.args.callsPerMinute, tt.args.burst); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewRateLimiter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	type fields struct {
+		rate        float64
+		burst       int
+		tokens      float64
+		lastRefill  time.Time
+		pausedUntil time.Time
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name    string
+		fields  fields
+		ctx     context.Context
+		wantErr bool
+	}{
+		{
+			name: "Wait with a token available",
+			fields: fields{
+				rate:   1,
+				burst:  1,
+				tokens: 1,
+			},
+			ctx: context.Background(),
+		},
+		{
+			name: "Wait for a token",
+			fields: fields{
+				rate:       10,
+				burst:      1,
+				tokens:     0,
+				lastRefill: time.Now(),
+			},
+			ctx: context.Background(),
+		},
+		{
+			name:   "Wait unlimited",
+			fields: fields{},
+			ctx:    context.Background(),
+		},
+		{
+			name: "Wait cancelled while paused",
+			fields: fields{
+				pausedUntil: time.Now().Add(time.Hour),
+			},
+			ctx:     cancelled,
+			wantErr: true,
+		},
+		{
+			name: "Wait cancelled",
+			fields: fields{
+				rate:       1.0 / 3600,
+				burst:      1,
+				tokens:     0,
+				lastRefill: time.Now(),
+			},
+			ctx:     cancelled,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &RateLimiter{
+				baseRate:    tt.fields.rate,
+				rate:        tt.fields.rate,
+				burst:       tt.fields.burst,
+				tokens:      tt.fields.tokens,
+				lastRefill:  tt.fields.lastRefill,
+				pausedUntil: tt.fields.pausedUntil,
+			}
+			if err := r.Wait(tt.ctx); (err != nil) != tt.wantErr {
+				t.Errorf("RateLimiter.Wait() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && r.tokens < tt.fields.tokens {
+				t.Errorf("RateLimiter.Wait() tokens = %v, want reservation returned (%v)", r.tokens, tt.fields.tokens)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_Burst(t *testing.T) {
+	r := NewRateLimiter(1, 3)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := r.Wait(context.Background()); err != nil {
+			t.Fatalf("RateLimiter.Wait() error = %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("RateLimiter.Wait() burst took %v, want immediate", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := r.Wait(ctx); err == nil {
+		t.Errorf("RateLimiter.Wait() after burst returned immediately, want to block")
+	}
+}
+
+func TestRateLimiter_PauseUntil(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name        string
+		pausedUntil time.Time
+		until       time.Time
+		want        time.Time
+	}{
+		{
+			name:  "Pause",
+			until: now.Add(time.Minute),
+			want:  now.Add(time.Minute),
+		},
+		{
+			name:        "Pause does not shorten an existing pause",
+			pausedUntil: now.Add(time.Hour),
+			until:       now.Add(time.Minute),
+			want:        now.Add(time.Hour),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &RateLimiter{pausedUntil: tt.pausedUntil}
+			r.PauseUntil(tt.until)
+			if !r.pausedUntil.Equal(tt.want) {
+				t.Errorf("RateLimiter.PauseUntil() = %v, want %v", r.pausedUntil, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_SlowdownRecover(t *testing.T) {
+	r := NewRateLimiter(60, 1)
+
+	r.Slowdown()
+	if r.rate != 0.5 {
+		t.Errorf("RateLimiter.Slowdown() rate = %v, want %v", r.rate, 0.5)
+	}
+
+	for i := 0; i < 10; i++ {
+		r.Slowdown()
+	}
+	if r.rate != 0.1 {
+		t.Errorf("RateLimiter.Slowdown() rate = %v, want floor %v", r.rate, 0.1)
+	}
+
+	r.Recover()
+	if want := 0.15; r.rate < want-1e-9 || r.rate > want+1e-9 {
+		t.Errorf("RateLimiter.Recover() rate = %v, want %v", r.rate, want)
+	}
+
+	for i := 0; i < 100; i++ {
+		r.Recover()
+	}
+	if r.rate != r.baseRate {
+		t.Errorf("RateLimiter.Recover() rate = %v, want ceiling %v", r.rate, r.baseRate)
+	}
+}
+
+func Test_parseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{
+			name:  "Seconds",
+			value: "120",
+			want:  2 * time.Minute,
+		},
+		{
+			name:  "HTTP date",
+			value: now.Add(90 * time.Second).Format(http.TimeFormat),
+			want:  90 * time.Second,
+		},
+		{
+			name:  "HTTP date in the past",
+			value: now.Add(-time.Minute).Format(http.TimeFormat),
+			want:  0,
+		},
+		{
+			name:  "Missing",
+			value: "",
+			want:  defaultRetryAfter,
+		},
+		{
+			name:  "Invalid",
+			value: "soon",
+			want:  defaultRetryAfter,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value, now); got != tt.want {
+				t.Errorf("parseRetryAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RetryPolicy controls how RetryTransport retries failed requests
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first
+	MaxAttempts int
+	// BaseDelay is the backoff before the first retry, doubled on each one
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff between attempts
+	MaxDelay time.Duration
+	// AttemptTimeout bounds each attempt, including reading its body. Zero
+	// means no per attempt timeout.
+	AttemptTimeout time.Duration
+}
+
+// Backoff returns the jittered delay before the given retry, BaseDelay
+// doubled for every retry before it and capped at MaxDelay
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << (attempt - 1)
+	if delay > p.MaxDelay || delay <= 0 {
+		delay = p.MaxDelay
+	}
+	// Equal jitter: keep half the delay and randomise the other half
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + time.Duration(rand.Int63n(int64(half)))
+}
+
+// DefaultRetryPolicy is used for RustMaps API calls
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	BaseDelay:      500 * time.Millisecond,
+	MaxDelay:       8 * time.Second,
+	AttemptTimeout: 10 * time.Second,
+}
+
+// RetryTransport is an http.RoundTripper that retries transient failures
+// with jittered exponential backoff.
+//
+// Idempotent requests (GET, HEAD, OPTIONS, PUT, DELETE) are retried after
+// connection errors and 5xx responses. Other requests are only retried when
+// the connection failed before the request was written, unless they were
+// marked with WithConflictSafe, meaning a duplicate is answered with 409
+// Conflict and retrying is therefore harmless.
+type RetryTransport struct {
+	Base   http.RoundTripper
+	Policy RetryPolicy
+	// Limiter, when set, is waited on before every attempt and told how
+	// each one went, so retries stay within the API rate limit and a run
+	// of 5xx responses slows it down
+	Limiter *RateLimiter
+}
+
+// NewRetryTransport wraps base, or http.DefaultTransport when base is nil
+func NewRetryTransport(base http.RoundTripper, policy RetryPolicy) *RetryTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &RetryTransport{
+		Base:   base,
+		Policy: policy,
+	}
+}
+
+type contextKey int
+
+const (
+	conflictSafeKey contextKey = iota
+	loggerKey
+)
+
+// WithConflictSafe marks requests made with ctx as safe to repeat because
+// the server rejects duplicates with 409 Conflict
+func WithConflictSafe(ctx context.Context) context.Context {
+	return context.WithValue(ctx, conflictSafeKey, true)
+}
+
+// WithLogger attaches a logger that RetryTransport reports retries to
+func WithLogger(ctx context.Context, log *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, log)
+}
+
+func loggerFrom(ctx context.Context) *zap.Logger {
+	if log, ok := ctx.Value(loggerKey).(*zap.Logger); ok && log != nil {
+		return log
+	}
+	return zap.NewNop()
+}
+
+// idempotent reports whether req may be repeated after it reached the server
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	safe, _ := req.Context().Value(conflictSafeKey).(bool)
+	return safe
+}
+
+// retryableStatus reports whether a response status is worth retrying
+func retryableStatus(code int) bool {
+	return code >= http.StatusInternalServerError && code != http.StatusNotImplemented
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	log := loggerFrom(ctx)
+	attempts := max(t.Policy.MaxAttempts, 1)
+
+	for attempt := 1; ; attempt++ {
+		if t.Limiter != nil {
+			if err := t.Limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+		resp, wrote, err := t.attempt(req, attempt)
+		if t.Limiter != nil && resp != nil {
+			t.Limiter.observe(log, resp)
+		}
+
+		retry := false
+		switch {
+		case ctx.Err() != nil:
+		case err != nil:
+			retry = !wrote || idempotent(req)
+		default:
+			retry = retryableStatus(resp.StatusCode) && idempotent(req)
+		}
+		if !retry || attempt >= attempts {
+			return resp, err
+		}
+
+		delay := t.Policy.Backoff(attempt)
+		if resp != nil {
+			if resp.StatusCode == http.StatusServiceUnavailable && resp.Header.Get("Retry-After") != "" {
+				delay = min(parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()), t.Policy.MaxDelay)
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		log.Info("Retrying request",
+			zap.String("method", req.Method),
+			zap.String("url", req.URL.String()),
+			zap.Int("attempt", attempt+1),
+			zap.Duration("backoff", delay),
+			zap.NamedError("last_error", err))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends a single copy of req, reporting whether the request was
+// written to the connection before any error occurred
+func (t *RetryTransport) attempt(req *http.Request, attempt int) (*http.Response, bool, error) {
+	ctx := req.Context()
+	cancel := context.CancelFunc(func() {})
+	if t.Policy.AttemptTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, t.Policy.AttemptTimeout)
+	}
+
+	// The trace hook may fire on the transport's own goroutine
+	var wrote atomic.Bool
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			wrote.Store(true)
+		},
+	})
+
+	r := req.Clone(ctx)
+	if attempt > 1 && req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			cancel()
+			return nil, true, errors.New("request body cannot be replayed")
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, true, err
+		}
+		r.Body = body
+	}
+
+	resp, err := t.Base.RoundTrip(r)
+	if err != nil {
+		cancel()
+		return nil, wrote.Load(), err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, wrote.Load(), nil
+}
+
+// cancelOnClose releases an attempt's timeout once its body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
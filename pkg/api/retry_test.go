@@ -0,0 +1,250 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// roundTripFunc adapts a function to http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRetryTransport_RoundTrip(t *testing.T) {
+	var hits atomic.Int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := hits.Add(1)
+		switch r.URL.Path {
+		case "/flaky":
+			// fail the first attempt only
+			if n == 1 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		case "/down":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/bad":
+			w.WriteHeader(http.StatusBadRequest)
+		case "/hangup":
+			// read the request, then drop the connection without answering
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+		}
+	}))
+	defer mockServer.Close()
+
+	policy := RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    2 * time.Millisecond,
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		conflictSafe bool
+		wantStatus   int
+		wantErr      bool
+		wantAttempts int32
+	}{
+		{
+			name:         "GET retried until it succeeds",
+			method:       http.MethodGet,
+			path:         "/flaky",
+			wantStatus:   http.StatusOK,
+			wantAttempts: 2,
+		},
+		{
+			name:         "GET gives up after max attempts",
+			method:       http.MethodGet,
+			path:         "/down",
+			wantStatus:   http.StatusServiceUnavailable,
+			wantAttempts: 3,
+		},
+		{
+			name:         "GET not retried on 4xx",
+			method:       http.MethodGet,
+			path:         "/bad",
+			wantStatus:   http.StatusBadRequest,
+			wantAttempts: 1,
+		},
+		{
+			name:         "POST not retried on 5xx",
+			method:       http.MethodPost,
+			path:         "/down",
+			wantStatus:   http.StatusServiceUnavailable,
+			wantAttempts: 1,
+		},
+		{
+			name:         "POST not retried after the request was sent",
+			method:       http.MethodPost,
+			path:         "/hangup",
+			wantErr:      true,
+			wantAttempts: 1,
+		},
+		{
+			name:         "Conflict safe POST retried on 5xx",
+			method:       http.MethodPost,
+			path:         "/flaky",
+			conflictSafe: true,
+			wantStatus:   http.StatusOK,
+			wantAttempts: 2,
+		},
+		{
+			name:         "Conflict safe POST retried after the request was sent",
+			method:       http.MethodPost,
+			path:         "/hangup",
+			conflictSafe: true,
+			wantErr:      true,
+			wantAttempts: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits.Store(0)
+			ctx := context.Background()
+			if tt.conflictSafe {
+				ctx = WithConflictSafe(ctx)
+			}
+			req, err := http.NewRequestWithContext(ctx, tt.method, mockServer.URL+tt.path, strings.NewReader(`{"seed":"1"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client := &http.Client{Transport: NewRetryTransport(nil, policy)}
+			resp, err := client.Do(req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RetryTransport.RoundTrip() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if resp != nil {
+				resp.Body.Close()
+				if resp.StatusCode != tt.wantStatus {
+					t.Errorf("RetryTransport.RoundTrip() status = %v, want %v", resp.StatusCode, tt.wantStatus)
+				}
+			}
+			if got := hits.Load(); got != tt.wantAttempts {
+				t.Errorf("RetryTransport.RoundTrip() attempts = %v, want %v", got, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRetryTransport_RoundTripBeforeSend(t *testing.T) {
+	var attempts atomic.Int32
+	transport := NewRetryTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		// fail without ever writing the request, like a refused connection
+		attempts.Add(1)
+		return nil, errors.New("connection refused")
+	}), RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost/maps", strings.NewReader("{}"))
+	if _, err := transport.RoundTrip(req); err == nil {
+		t.Errorf("RetryTransport.RoundTrip() error = nil, want error")
+	}
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("RetryTransport.RoundTrip() attempts = %v, want %v", got, 3)
+	}
+}
+
+func TestRetryTransport_RoundTripLimited(t *testing.T) {
+	var attempts atomic.Int32
+	transport := NewRetryTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		// two 503s, then success
+		code := http.StatusServiceUnavailable
+		if attempts.Add(1) == 3 {
+			code = http.StatusOK
+		}
+		return &http.Response{StatusCode: code, Header: http.Header{}, Body: http.NoBody}, nil
+	}), RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+	transport.Limiter = NewRateLimiter(60, 3)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost/maps/limits", nil)
+	resp, err := transport.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("RetryTransport.RoundTrip() = %v, %v, want 200", resp, err)
+	}
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("RetryTransport.RoundTrip() attempts = %v, want %v", got, 3)
+	}
+
+	l := transport.Limiter
+	// Every attempt took a token, the two 503s halved the rate twice and
+	// the success recovered some of it
+	if l.tokens > 0.5 {
+		t.Errorf("RetryTransport.RoundTrip() left %v tokens, want one taken per attempt", l.tokens)
+	}
+	if want := l.baseRate*0.25 + l.baseRate*recoverFraction; l.rate < want-1e-9 || l.rate > want+1e-9 {
+		t.Errorf("RetryTransport.RoundTrip() rate = %v, want %v", l.rate, want)
+	}
+}
+
+func TestRetryTransport_RoundTripCancelled(t *testing.T) {
+	var attempts atomic.Int32
+	transport := NewRetryTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts.Add(1)
+		return nil, errors.New("connection refused")
+	}), RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost/maps", nil)
+	if _, err := transport.RoundTrip(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("RetryTransport.RoundTrip() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("RetryTransport.RoundTrip() attempts = %v, want %v", got, 1)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{
+		BaseDelay: 100 * time.Millisecond,
+		MaxDelay:  time.Second,
+	}
+	tests := []struct {
+		name    string
+		attempt int
+		min     time.Duration
+		max     time.Duration
+	}{
+		{
+			name:    "First retry",
+			attempt: 1,
+			min:     50 * time.Millisecond,
+			max:     100 * time.Millisecond,
+		},
+		{
+			name:    "Third retry",
+			attempt: 3,
+			min:     200 * time.Millisecond,
+			max:     400 * time.Millisecond,
+		},
+		{
+			name:    "Capped",
+			attempt: 40,
+			min:     500 * time.Millisecond,
+			max:     time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				if got := policy.Backoff(tt.attempt); got < tt.min || got > tt.max {
+					t.Errorf("RetryPolicy.Backoff() = %v, want between %v and %v", got, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
@@ -1,14 +1,12 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"time"
 
 	"github.com/maintc/rustmaps-cli/pkg/common"
-	"github.com/maintc/rustmaps-cli/pkg/types"
 
 	"go.uber.org/zap"
 )
@@ -70,48 +68,37 @@ type RustMapsStatusResponseData struct {
 type RustMapsStatusResponse struct {
 	Meta RustMapsStatusResponseMeta `json:"meta"`
 	Data RustMapsStatusResponseData `json:"data"`
+	// Generation is how far along a map that is still generating is, when
+	// RustMaps says
+	Generation *RustMapsGenerateResponseData `json:"-"`
 }
 
-func (c *RustMapsClient) GetStatus(log *zap.Logger, m *types.Map) (*RustMapsStatusResponse, error) {
-	c.rateLimiter.Wait()
-	// Create a client with custom timeouts
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+// StatusRequest identifies a map by its ID, or by size and seed when the ID
+// is not known yet
+type StatusRequest struct {
+	MapID   string
+	Seed    string
+	Size    int
+	Staging bool
+}
 
-	var endpoint = m.MapID
+func (c *RustMapsClient) GetStatus(ctx context.Context, log *zap.Logger, r StatusRequest) (*RustMapsStatusResponse, error) {
+	var endpoint = r.MapID
 	if endpoint == "" {
-		endpoint = fmt.Sprintf("%d/%s", m.Size, m.Seed)
+		endpoint = fmt.Sprintf("%d/%s", r.Size, r.Seed)
 	}
 
 	// Create request
-	log.Debug("Getting map status", zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.Bool("staging", m.Staging))
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/maps/%s", c.ApiUrl, endpoint), nil)
+	log.Debug("Getting map status", zap.String("seed", r.Seed), zap.Int("size", r.Size), zap.Bool("staging", r.Staging))
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/maps/%s", c.ApiUrl, endpoint), nil)
 	if err != nil {
 		return nil, err
 	}
 
-	// Add headers
-	req.Header.Set("X-API-Key", c.apiKey)
-
-	// Make request
-	resp, err := client.Do(req)
+	resp, body, err := c.do(ctx, log, req)
 	if err != nil {
-		log.Error("Error making request", zap.Error(err))
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	log.Debug("Response status", zap.Int("status", resp.StatusCode), zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.String("config", m.SavedConfig), zap.Bool("staging", m.Staging))
-
-	// Read response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Error("Error reading response", zap.Error(err))
-		return nil, err
-	}
-
-	log.Debug("Response body", zap.String("body", string(body)), zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.String("config", m.SavedConfig), zap.Bool("staging", m.Staging))
 
 	status := &RustMapsStatusResponse{}
 	switch resp.StatusCode {
@@ -121,24 +108,28 @@ func (c *RustMapsClient) GetStatus(log *zap.Logger, m *types.Map) (*RustMapsStat
 		}
 		status.Meta.Status = common.StatusComplete
 		return status, nil
-	case http.StatusUnauthorized:
-		log.Error("Unauthorized request", zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.Bool("staging", m.Staging))
-		status.Meta.Status = common.StatusUnauthorized
-		status.Meta.StatusCode = http.StatusUnauthorized
-	case http.StatusForbidden:
-		log.Error("Forbidden request", zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.Bool("staging", m.Staging))
-		status.Meta.Status = common.StatusForbidden
-		status.Meta.StatusCode = http.StatusForbidden
+	case http.StatusUnauthorized, http.StatusForbidden:
+		// The account was turned away, not the map, so it is an error like
+		// on every other endpoint
+		err := newError(resp, body)
+		log.Error("Request refused", zap.String("seed", r.Seed), zap.Int("size", r.Size), zap.Bool("staging", r.Staging), zap.Error(err))
+		return nil, err
 	case http.StatusNotFound:
-		log.Error("Map not found", zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.Bool("staging", m.Staging))
+		log.Error("Map not found", zap.String("seed", r.Seed), zap.Int("size", r.Size), zap.Bool("staging", r.Staging))
 		status.Meta.Status = common.StatusNotFound
 		status.Meta.StatusCode = http.StatusNotFound
 	case http.StatusConflict:
-		log.Debug("Map generating", zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.Bool("staging", m.Staging))
+		log.Debug("Map generating", zap.String("seed", r.Seed), zap.Int("size", r.Size), zap.Bool("staging", r.Staging))
 		status.Meta.Status = common.StatusGenerating
 		status.Meta.StatusCode = http.StatusConflict
+		var generating RustMapsGenerateResponse
+		if err := json.Unmarshal(body, &generating); err == nil && generating.Data != (RustMapsGenerateResponseData{}) {
+			status.Generation = &generating.Data
+		}
 	default:
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		// The map's state is unknown, including when rate limited, leave it
+		// untouched and let the caller retry
+		return nil, newError(resp, body)
 	}
 
 	return status, nil
@@ -1,14 +1,17 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/maintc/rustmaps-cli/pkg/common"
-	"github.com/maintc/rustmaps-cli/pkg/types"
 	"go.uber.org/zap"
 )
 
@@ -88,6 +91,15 @@ func TestRustMapsClient_GetStatus(t *testing.T) {
 				}
 				json.NewEncoder(w).Encode(response)
 				return
+			} else if strings.HasPrefix(r.URL.Path, "/maps/4000/8") {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusConflict)
+				w.Write([]byte(`{"meta":{"status":"Conflict","statusCode":409,"errors":["Map is still generating"]},"data":{"mapId":"abc","queuePosition":2,"state":"Generating","currentStep":"Placing monuments","lastGeneratorPingUtc":"2024-01-02T03:04:05Z"}}`))
+				return
+			} else if strings.HasPrefix(r.URL.Path, "/maps/4000/7") {
+				w.Header().Set("Retry-After", "30")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
 			}
 		}
 
@@ -102,7 +114,7 @@ func TestRustMapsClient_GetStatus(t *testing.T) {
 	}
 	type args struct {
 		log *zap.Logger
-		m   *types.Map
+		r   StatusRequest
 	}
 	tests := []struct {
 		name    string
@@ -110,9 +122,13 @@ func TestRustMapsClient_GetStatus(t *testing.T) {
 		args    args
 		want    *RustMapsStatusResponse
 		wantErr bool
+		// wantErrIs is the sentinel the error must match, when set
+		wantErrIs error
+		// wantPaused expects the rate limiter to be paused afterwards
+		wantPaused bool
 	}{
 		{
-			name: "Test GetStatus 200",
+			name: "Test GetStatus 409 with progress",
 			fields: fields{
 				apiURL:      mockServer.URL,
 				apiKey:      "test",
@@ -120,22 +136,27 @@ func TestRustMapsClient_GetStatus(t *testing.T) {
 			},
 			args: args{
 				log: zap.NewNop(),
-				m: &types.Map{
-					Seed:        "1",
-					Size:        4000,
-					SavedConfig: "default",
+				r: StatusRequest{
+					Seed: "8",
+					Size: 4000,
 				},
 			},
 			want: &RustMapsStatusResponse{
 				Meta: RustMapsStatusResponseMeta{
-					Status:     common.StatusComplete,
-					StatusCode: 200,
-					Errors:     []string{},
+					Status:     common.StatusGenerating,
+					StatusCode: 409,
+				},
+				Generation: &RustMapsGenerateResponseData{
+					MapID:                "abc",
+					QueuePosition:        2,
+					State:                "Generating",
+					CurrentStep:          "Placing monuments",
+					LastGeneratorPingUtc: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
 				},
 			},
 		},
 		{
-			name: "Test GetStatus 401",
+			name: "Test GetStatus 200",
 			fields: fields{
 				apiURL:      mockServer.URL,
 				apiKey:      "test",
@@ -143,22 +164,21 @@ func TestRustMapsClient_GetStatus(t *testing.T) {
 			},
 			args: args{
 				log: zap.NewNop(),
-				m: &types.Map{
-					Seed:        "2",
-					Size:        4000,
-					SavedConfig: "default",
+				r: StatusRequest{
+					Seed: "1",
+					Size: 4000,
 				},
 			},
 			want: &RustMapsStatusResponse{
 				Meta: RustMapsStatusResponseMeta{
-					Status:     common.StatusUnauthorized,
-					StatusCode: 401,
+					Status:     common.StatusComplete,
+					StatusCode: 200,
 					Errors:     []string{},
 				},
 			},
 		},
 		{
-			name: "Test GetStatus 403",
+			name: "Test GetStatus 401",
 			fields: fields{
 				apiURL:      mockServer.URL,
 				apiKey:      "test",
@@ -166,19 +186,30 @@ func TestRustMapsClient_GetStatus(t *testing.T) {
 			},
 			args: args{
 				log: zap.NewNop(),
-				m: &types.Map{
-					Seed:        "3",
-					Size:        4000,
-					SavedConfig: "default",
+				r: StatusRequest{
+					Seed: "2",
+					Size: 4000,
 				},
 			},
-			want: &RustMapsStatusResponse{
-				Meta: RustMapsStatusResponseMeta{
-					Status:     common.StatusForbidden,
-					StatusCode: 403,
-					Errors:     []string{},
+			wantErr:   true,
+			wantErrIs: ErrUnauthorized,
+		},
+		{
+			name: "Test GetStatus 403",
+			fields: fields{
+				apiURL:      mockServer.URL,
+				apiKey:      "test",
+				rateLimiter: &RateLimiter{},
+			},
+			args: args{
+				log: zap.NewNop(),
+				r: StatusRequest{
+					Seed: "3",
+					Size: 4000,
 				},
 			},
+			wantErr:   true,
+			wantErrIs: ErrForbidden,
 		},
 		{
 			name: "Test GetStatus 404",
@@ -189,10 +220,9 @@ func TestRustMapsClient_GetStatus(t *testing.T) {
 			},
 			args: args{
 				log: zap.NewNop(),
-				m: &types.Map{
-					Seed:        "4",
-					Size:        4000,
-					SavedConfig: "default",
+				r: StatusRequest{
+					Seed: "4",
+					Size: 4000,
 				},
 			},
 			want: &RustMapsStatusResponse{
@@ -212,10 +242,9 @@ func TestRustMapsClient_GetStatus(t *testing.T) {
 			},
 			args: args{
 				log: zap.NewNop(),
-				m: &types.Map{
-					Seed:        "5",
-					Size:        4000,
-					SavedConfig: "default",
+				r: StatusRequest{
+					Seed: "5",
+					Size: 4000,
 				},
 			},
 			want: &RustMapsStatusResponse{
@@ -235,10 +264,9 @@ func TestRustMapsClient_GetStatus(t *testing.T) {
 			},
 			args: args{
 				log: zap.NewNop(),
-				m: &types.Map{
-					Seed:        "6",
-					Size:        4000,
-					SavedConfig: "default",
+				r: StatusRequest{
+					Seed: "6",
+					Size: 4000,
 				},
 			},
 			want: &RustMapsStatusResponse{
@@ -250,6 +278,23 @@ func TestRustMapsClient_GetStatus(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "Test GetStatus 429",
+			fields: fields{
+				apiURL:      mockServer.URL,
+				apiKey:      "test",
+				rateLimiter: &RateLimiter{},
+			},
+			args: args{
+				log: zap.NewNop(),
+				r: StatusRequest{
+					Seed: "7",
+					Size: 4000,
+				},
+			},
+			wantErr:    true,
+			wantPaused: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -258,15 +303,24 @@ func TestRustMapsClient_GetStatus(t *testing.T) {
 				apiKey:      tt.fields.apiKey,
 				rateLimiter: tt.fields.rateLimiter,
 			}
-			got, err := c.GetStatus(tt.args.log, tt.args.m)
+			got, err := c.GetStatus(context.Background(), tt.args.log, tt.args.r)
+			if paused := time.Now().Before(tt.fields.rateLimiter.pausedUntil); paused != tt.wantPaused {
+				t.Errorf("RustMapsClient.GetStatus() paused = %v, wantPaused %v", paused, tt.wantPaused)
+			}
 			if (err != nil) != tt.wantErr {
 				t.Errorf("RustMapsClient.GetStatus() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if tt.wantErrIs != nil && !errors.Is(err, tt.wantErrIs) {
+				t.Errorf("RustMapsClient.GetStatus() error = %v, want %v", err, tt.wantErrIs)
+			}
 
 			if got != nil && got.Meta.StatusCode != tt.want.Meta.StatusCode {
 				t.Errorf("RustMapsClient.GetStatus() = %v, want %v", got, tt.want)
 			}
+			if got != nil && !reflect.DeepEqual(got.Generation, tt.want.Generation) {
+				t.Errorf("RustMapsClient.GetStatus() generation = %+v, want %+v", got.Generation, tt.want.Generation)
+			}
 		})
 	}
 }
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewTransport(t *testing.T) {
+	mockServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mockServer.Close()
+
+	dir := t.TempDir()
+	caBundle := filepath.Join(dir, "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: mockServer.Certificate().Raw})
+	if err := os.WriteFile(caBundle, certPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+	notPEM := filepath.Join(dir, "not.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	type args struct {
+		proxy    string
+		caBundle string
+	}
+	tests := []struct {
+		name        string
+		args        args
+		wantErr     bool
+		wantTrusted bool
+	}{
+		{
+			name: "Defaults",
+		},
+		{
+			name: "Proxy",
+			args: args{
+				proxy: "http://proxy.example.com:3128",
+			},
+		},
+		{
+			name: "Proxy without scheme",
+			args: args{
+				proxy: "proxy.example.com:3128",
+			},
+			wantErr: true,
+		},
+		{
+			name: "CA bundle",
+			args: args{
+				caBundle: caBundle,
+			},
+			wantTrusted: true,
+		},
+		{
+			name: "Missing CA bundle",
+			args: args{
+				caBundle: filepath.Join(dir, "missing.pem"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "CA bundle without certificates",
+			args: args{
+				caBundle: notPEM,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewTransport(tt.args.proxy, tt.args.caBundle)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewTransport() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.args.proxy != "" {
+				req, _ := http.NewRequest(http.MethodGet, "https://api.rustmaps.com/v4/maps", nil)
+				proxyURL, err := got.Proxy(req)
+				if err != nil || proxyURL == nil || proxyURL.String() != tt.args.proxy {
+					t.Errorf("NewTransport() proxy = %v, want %v", proxyURL, tt.args.proxy)
+				}
+				return
+			}
+			resp, err := (&http.Client{Transport: got}).Get(mockServer.URL)
+			if resp != nil {
+				resp.Body.Close()
+			}
+			if (err == nil) != tt.wantTrusted {
+				t.Errorf("NewTransport() request error = %v, wantTrusted %v", err, tt.wantTrusted)
+			}
+		})
+	}
+}
@@ -3,10 +3,14 @@ package common
 const (
 	StatusGenerating        = "Generating"
 	StatusPending           = "Pending"
+	StatusSubmitted         = "Submitted"
 	StatusComplete          = "Complete"
+	StatusDownloaded        = "Downloaded"
 	StatusUnauthorized      = "Unauthorized"
 	StatusForbidden         = "Forbidden"
 	StatusBadRequest        = "Bad Request"
 	StatusStagingNotEnabled = "Staging Not Enabled"
 	StatusNotFound          = "Not Found"
+	StatusRateLimited       = "Rate Limited"
+	StatusFailed            = "Failed"
 )
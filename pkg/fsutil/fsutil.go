@@ -0,0 +1,65 @@
+// Package fsutil writes state files so a crash never leaves them half
+// written, locks them against other processes and moves corrupt ones aside
+package fsutil
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WriteFile writes data to path like os.WriteFile, but atomically: the data
+// goes to a temporary file in the same directory, which is synced to disk
+// and then renamed over path. Readers see the old contents or the new ones,
+// never a truncated file.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	return WriteFrom(path, bytes.NewReader(data), perm)
+}
+
+// WriteFrom is WriteFile for data read from r. When reading r fails, path is
+// left as it was.
+func WriteFrom(path string, r io.Reader, perm os.FileMode) (err error) {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = io.Copy(tmp, r); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	// Make the rename itself survive a crash
+	return syncDir(dir)
+}
+
+// Quarantine moves the corrupt file at path aside, appending ".corrupt-"
+// and the time to its name, so it no longer gets in the way but can still
+// be looked at. It returns the new path.
+func Quarantine(path string) (string, error) {
+	dest := fmt.Sprintf("%s.corrupt-%s", path, time.Now().UTC().Format("20060102T150405Z"))
+	if err := os.Rename(path, dest); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
@@ -0,0 +1,106 @@
+package fsutil
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestWriteFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		data     string
+		wantErr  bool
+	}{
+		{name: "New file", data: `{"seed": "1"}`},
+		{name: "Replaces existing file", existing: `{"seed": "1", "status": "Pending"}`, data: `{"seed": "1"}`},
+		{name: "Missing directory", data: `{}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "1_4000.json")
+			if tt.wantErr {
+				path = filepath.Join(dir, "missing", "1_4000.json")
+			}
+			if tt.existing != "" {
+				if err := os.WriteFile(path, []byte(tt.existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := WriteFile(path, []byte(tt.data), 0644); (err != nil) != tt.wantErr {
+				t.Fatalf("WriteFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			entries, _ := os.ReadDir(dir)
+			for _, e := range entries {
+				if strings.Contains(e.Name(), ".tmp-") {
+					t.Errorf("WriteFile() left temporary file %s", e.Name())
+				}
+			}
+			if tt.wantErr {
+				return
+			}
+			if got, _ := os.ReadFile(path); string(got) != tt.data {
+				t.Errorf("WriteFile() wrote %q, want %q", got, tt.data)
+			}
+		})
+	}
+}
+
+func TestWriteFrom(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "1_4000.map")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Cut off after a few bytes, like a dropped download
+	r := io.MultiReader(strings.NewReader("new"), iotest.ErrReader(io.ErrUnexpectedEOF))
+	if err := WriteFrom(path, r, 0644); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("WriteFrom() error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "old" {
+		t.Errorf("WriteFrom() left %q, want the old contents", got)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("WriteFrom() left %d files, want only %s", len(entries), path)
+	}
+
+	if err := WriteFrom(path, strings.NewReader("new"), 0644); err != nil {
+		t.Fatalf("WriteFrom() error = %v", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "new" {
+		t.Errorf("WriteFrom() wrote %q, want %q", got, "new")
+	}
+}
+
+func TestQuarantine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "1_4000.json")
+	if err := os.WriteFile(path, []byte(`{"seed": "1", "sta`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dest, err := Quarantine(path)
+	if err != nil {
+		t.Fatalf("Quarantine() error = %v", err)
+	}
+	if !strings.HasPrefix(dest, path+".corrupt-") {
+		t.Errorf("Quarantine() = %s, want %s.corrupt-<time>", dest, path)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Quarantine() left %s in place", path)
+	}
+	if got, _ := os.ReadFile(dest); string(got) != `{"seed": "1", "sta` {
+		t.Errorf("Quarantine() moved %q, want the corrupt contents", got)
+	}
+
+	if _, err := Quarantine(path); err == nil {
+		t.Error("Quarantine() of a missing file error = nil, want an error")
+	}
+}
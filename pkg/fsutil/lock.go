@@ -0,0 +1,40 @@
+package fsutil
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrLocked is returned by TryLock when the file is already locked
+var ErrLocked = errors.New("file is locked by another process")
+
+// Lock is an exclusive lock on a file, held until Unlock or until the
+// process exits. It is advisory: only processes that lock the same file
+// are kept out.
+type Lock struct {
+	f *os.File
+}
+
+// TryLock locks the file at path, creating it if needed, without waiting.
+// It returns ErrLocked when another process holds the lock, or another
+// Lock in this one.
+func TryLock(path string) (*Lock, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &Lock{f: f}, nil
+}
+
+// Unlock releases the lock
+func (l *Lock) Unlock() error {
+	if err := unlockFile(l.f); err != nil {
+		l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
@@ -0,0 +1,32 @@
+package fsutil
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestTryLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "1_4000.json.lock")
+
+	lock, err := TryLock(path)
+	if err != nil {
+		t.Fatalf("TryLock() error = %v", err)
+	}
+	if _, err := TryLock(path); !errors.Is(err, ErrLocked) {
+		t.Fatalf("TryLock() of a held lock error = %v, want %v", err, ErrLocked)
+	}
+
+	if err := lock.Unlock(); err != nil {
+		t.Fatalf("Lock.Unlock() error = %v", err)
+	}
+	again, err := TryLock(path)
+	if err != nil {
+		t.Fatalf("TryLock() after Unlock error = %v", err)
+	}
+	again.Unlock()
+
+	if _, err := TryLock(filepath.Join(t.TempDir(), "missing", "x.lock")); err == nil {
+		t.Error("TryLock() in a missing directory error = nil, want an error")
+	}
+}
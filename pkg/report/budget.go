@@ -0,0 +1,53 @@
+package report
+
+import "fmt"
+
+// Budget is how many maps a run needs to submit against how many it may
+type Budget struct {
+	// Needed is how many maps are waiting to be submitted. Some may turn
+	// out to be on RustMaps already and not use any quota.
+	Needed int `json:"needed"`
+	// Monthly is the account's monthly usage
+	Monthly Usage `json:"monthly"`
+	// Reserve is how many maps of the monthly quota are always left unused
+	Reserve int `json:"reserve,omitempty"`
+	// MaxMaps caps how many maps the run may generate, 0 means no cap
+	MaxMaps int `json:"max_maps,omitempty"`
+}
+
+// Available is how many maps the run may submit
+func (b Budget) Available() int {
+	return available(&b.Monthly, b.Reserve, b.MaxMaps)
+}
+
+// Shortfall is how many of the needed maps the run may not submit
+func (b Budget) Shortfall() int {
+	return max(b.Needed-b.Available(), 0)
+}
+
+// String describes what limits the run, e.g. "3 of 5 map(s) fit in the
+// 23 left this month after keeping 20 in reserve"
+func (b Budget) String() string {
+	s := fmt.Sprintf("%d of %d map(s) fit in the %d left this month", min(b.Needed, b.Available()), b.Needed, max(b.Monthly.Allowed-b.Monthly.Current, 0))
+	if b.Reserve > 0 {
+		s = fmt.Sprintf("%s after keeping %d in reserve", s, b.Reserve)
+	}
+	if b.MaxMaps > 0 {
+		s = fmt.Sprintf("%s with at most %d for this run", s, b.MaxMaps)
+	}
+	return s
+}
+
+// available is how many maps may be submitted with the monthly usage,
+// leaving reserve maps unused and submitting at most maxMaps, or -1 when
+// neither the usage nor a maximum is known
+func available(monthly *Usage, reserve, maxMaps int) int {
+	left := -1
+	if monthly != nil {
+		left = max(monthly.Allowed-monthly.Current-reserve, 0)
+	}
+	if maxMaps > 0 && (left < 0 || maxMaps < left) {
+		left = maxMaps
+	}
+	return left
+}
@@ -0,0 +1,53 @@
+package report
+
+import "testing"
+
+func TestBudget(t *testing.T) {
+	tests := []struct {
+		name          string
+		budget        Budget
+		wantAvailable int
+		wantShortfall int
+		wantString    string
+	}{
+		{
+			name:          "Fits",
+			budget:        Budget{Needed: 5, Monthly: Usage{Current: 47, Allowed: 800}},
+			wantAvailable: 753,
+			wantString:    "5 of 5 map(s) fit in the 753 left this month",
+		},
+		{
+			name:          "Short of the reserve",
+			budget:        Budget{Needed: 5, Monthly: Usage{Current: 777, Allowed: 800}, Reserve: 20},
+			wantAvailable: 3,
+			wantShortfall: 2,
+			wantString:    "3 of 5 map(s) fit in the 23 left this month after keeping 20 in reserve",
+		},
+		{
+			name:          "Short of max maps",
+			budget:        Budget{Needed: 5, Monthly: Usage{Allowed: 800}, MaxMaps: 2},
+			wantAvailable: 2,
+			wantShortfall: 3,
+			wantString:    "2 of 5 map(s) fit in the 800 left this month with at most 2 for this run",
+		},
+		{
+			name:          "Over the limit",
+			budget:        Budget{Needed: 1, Monthly: Usage{Current: 801, Allowed: 800}, Reserve: 20},
+			wantShortfall: 1,
+			wantString:    "0 of 1 map(s) fit in the 0 left this month after keeping 20 in reserve",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.budget.Available(); got != tt.wantAvailable {
+				t.Errorf("Budget.Available() = %v, want %v", got, tt.wantAvailable)
+			}
+			if got := tt.budget.Shortfall(); got != tt.wantShortfall {
+				t.Errorf("Budget.Shortfall() = %v, want %v", got, tt.wantShortfall)
+			}
+			if got := tt.budget.String(); got != tt.wantString {
+				t.Errorf("Budget.String() = %q, want %q", got, tt.wantString)
+			}
+		})
+	}
+}
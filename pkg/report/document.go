@@ -0,0 +1,176 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/types"
+)
+
+// SchemaVersion is the version of every JSON document and NDJSON event.
+// Fields may be added without changing it, it changes when a field is
+// renamed, removed or changes meaning.
+const SchemaVersion = 1
+
+// Document types, the "type" field of each document
+const (
+	DocumentGenerate = "generate"
+	DocumentPlan     = "plan"
+	DocumentAuth     = "auth"
+	DocumentOpen     = "open"
+	DocumentInfo     = "info"
+	DocumentMigrate  = "migrate"
+	DocumentList     = "list"
+	DocumentStatus   = "status"
+	DocumentLimits   = "limits"
+	DocumentError    = "error"
+)
+
+// Header starts every document
+type Header struct {
+	Version int    `json:"version"`
+	Type    string `json:"type"`
+}
+
+// NewHeader returns the header for a document of type t
+func NewHeader(t string) Header {
+	return Header{Version: SchemaVersion, Type: t}
+}
+
+// Assets names the files RustMaps generates for a map, either as URLs or as
+// downloaded paths
+type Assets struct {
+	Map        string `json:"map,omitempty"`
+	Image      string `json:"image,omitempty"`
+	ImageIcons string `json:"image_icons,omitempty"`
+	Thumbnail  string `json:"thumbnail,omitempty"`
+}
+
+// MapRecord describes one map
+type MapRecord struct {
+	Seed        string `json:"seed"`
+	Size        int    `json:"size"`
+	SavedConfig string `json:"saved_config"`
+	Staging     bool   `json:"staging"`
+	MapID       string `json:"map_id"`
+	Status      string `json:"status"`
+	// QueuePosition and CurrentStep are set while the map is generating
+	QueuePosition int    `json:"queue_position,omitempty"`
+	CurrentStep   string `json:"current_step,omitempty"`
+	// SubmittedAt and CompletedAt are RFC3339 times, GenerationSeconds is the
+	// time between them
+	SubmittedAt       string `json:"submitted_at,omitempty"`
+	CompletedAt       string `json:"completed_at,omitempty"`
+	GenerationSeconds int    `json:"generation_seconds,omitempty"`
+	// FailureReason says why the map failed or was refused
+	FailureReason string `json:"failure_reason,omitempty"`
+	// URL is the map's page on rustmaps.com
+	URL string `json:"url,omitempty"`
+	// URLs are where the assets can be downloaded from
+	URLs *Assets `json:"urls,omitempty"`
+	// Files are where the assets were downloaded to
+	Files *Assets `json:"files,omitempty"`
+}
+
+// NewMapRecord returns the record for m, without URLs or files
+func NewMapRecord(m *types.Map) MapRecord {
+	r := MapRecord{
+		Seed:              m.Seed,
+		Size:              m.Size,
+		SavedConfig:       m.SavedConfig,
+		Staging:           m.Staging,
+		MapID:             m.MapID,
+		Status:            m.Status,
+		SubmittedAt:       m.SubmittedAt,
+		CompletedAt:       m.CompletedAt,
+		GenerationSeconds: m.GenerationSeconds,
+		FailureReason:     m.FailureReason,
+	}
+	if m.Status == common.StatusGenerating {
+		r.QueuePosition = m.QueuePosition
+		r.CurrentStep = m.CurrentStep
+	}
+	return r
+}
+
+// Usage is how much of a limit is used
+type Usage struct {
+	Current int `json:"current"`
+	Allowed int `json:"allowed"`
+}
+
+// Limits are the account's generation limits
+type Limits struct {
+	Concurrent Usage `json:"concurrent"`
+	Monthly    Usage `json:"monthly"`
+}
+
+// GenerateDocument is the result of rustmaps generate
+type GenerateDocument struct {
+	Header
+	Tier   string      `json:"tier"`
+	Limits *Limits     `json:"limits,omitempty"`
+	Maps   []MapRecord `json:"maps"`
+	// DownloadDir is where this run downloaded maps to, if it did
+	DownloadDir string `json:"download_dir,omitempty"`
+	// Summary is how the run ended
+	Summary *Summary `json:"summary,omitempty"`
+}
+
+// PlanDocument is the result of rustmaps generate --dry-run
+type PlanDocument struct {
+	Header
+	Tier string `json:"tier"`
+	Plan
+}
+
+// AuthDocument is the result of rustmaps auth
+type AuthDocument struct {
+	Header
+	Tier   string  `json:"tier"`
+	Limits *Limits `json:"limits,omitempty"`
+}
+
+// OpenDocument is the result of rustmaps open --print
+type OpenDocument struct {
+	Header
+	Maps []MapRecord `json:"maps"`
+}
+
+// InfoDocument lists where rustmaps keeps its files
+type InfoDocument struct {
+	Header
+	DownloadsDir string `json:"downloads_dir"`
+	ImportsDir   string `json:"imports_dir"`
+	ConfigFile   string `json:"config_file"`
+	LogFile      string `json:"log_file"`
+	// Database is the SQLite store's database, when that store is used
+	Database string `json:"database,omitempty"`
+}
+
+// MigrateDocument is the result of rustmaps migrate
+type MigrateDocument struct {
+	Header
+	// Migrated is how many maps were copied into the database
+	Migrated int    `json:"migrated"`
+	Database string `json:"database"`
+}
+
+// ErrorDocument is written instead of a result when a command fails
+type ErrorDocument struct {
+	Header
+	Error string `json:"error"`
+	// Summary is how far generate got before it stopped
+	Summary *Summary `json:"summary,omitempty"`
+}
+
+// WriteDocument writes doc to w as JSON followed by a newline, indented
+// unless compact is set
+func WriteDocument(w io.Writer, doc any, compact bool) error {
+	enc := json.NewEncoder(w)
+	if !compact {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(doc)
+}
@@ -0,0 +1,69 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/maintc/rustmaps-cli/pkg/types"
+)
+
+func TestWriteDocument(t *testing.T) {
+	m := types.NewMap("123", 4000, "", true)
+	m.MapID = "abc"
+	m.Status = "Complete"
+
+	tests := []struct {
+		name    string
+		doc     any
+		compact bool
+		want    string
+	}{
+		{
+			name: "Compact open",
+			doc: OpenDocument{
+				Header: NewHeader(DocumentOpen),
+				Maps:   []MapRecord{NewMapRecord(m)},
+			},
+			compact: true,
+			want:    `{"version":1,"type":"open","maps":[{"seed":"123","size":4000,"saved_config":"","staging":true,"map_id":"abc","status":"Complete"}]}` + "\n",
+		},
+		{
+			name: "Indented error",
+			doc: ErrorDocument{
+				Header: NewHeader(DocumentError),
+				Error:  "boom",
+			},
+			want: "{\n  \"version\": 1,\n  \"type\": \"error\",\n  \"error\": \"boom\"\n}\n",
+		},
+		{
+			name: "Error with summary",
+			doc: ErrorDocument{
+				Header:  NewHeader(DocumentError),
+				Error:   "boom",
+				Summary: &Summary{Statuses: map[string]int{"Pending": 1}, Generated: 2, ExitCode: 4},
+			},
+			compact: true,
+			want:    `{"version":1,"type":"error","error":"boom","summary":{"statuses":{"Pending":1},"duration_seconds":0,"generated":2,"exit_code":4}}` + "\n",
+		},
+		{
+			name: "Auth without limits",
+			doc: AuthDocument{
+				Header: NewHeader(DocumentAuth),
+				Tier:   "Free",
+			},
+			compact: true,
+			want:    `{"version":1,"type":"auth","tier":"Free"}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteDocument(&buf, tt.doc, tt.compact); err != nil {
+				t.Fatalf("WriteDocument() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("WriteDocument() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
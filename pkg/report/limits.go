@@ -0,0 +1,111 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// LimitsDocument is the result of rustmaps limits
+type LimitsDocument struct {
+	Header
+	Quota
+}
+
+// Quota is the account's limits and how the month is going
+type Quota struct {
+	Tier string `json:"tier"`
+	Limits
+	// Reserve is how many maps of the monthly quota generate always leaves
+	// unused
+	Reserve int `json:"reserve,omitempty"`
+	// Generated is how many maps the local history says were submitted
+	// this month, maps generated with the same key elsewhere are missing
+	Generated int `json:"generated"`
+	// Resets is when the monthly quota is expected to reset
+	Resets time.Time `json:"resets"`
+	// Forecast projects the monthly usage until the reset
+	Forecast Forecast `json:"forecast"`
+}
+
+// Forecast projects the monthly usage at the rate it was used so far this
+// month
+type Forecast struct {
+	// PerDay is how many maps were used a day on average
+	PerDay float64 `json:"per_day"`
+	// Projected is the usage the month would end with at that rate, at
+	// most the allowance
+	Projected int `json:"projected"`
+	// RunsOut is when the allowance would run out at that rate, nil when
+	// it lasts until the reset
+	RunsOut *time.Time `json:"runs_out,omitempty"`
+}
+
+// NewForecast projects monthly, used since start, until the reset at the
+// rate it was used until now. The first day counts as a whole day, so a
+// few maps in the first hours of the month do not project a burn rate of
+// hundreds a day.
+func NewForecast(monthly Usage, start, reset, now time.Time) Forecast {
+	elapsed := max(now.Sub(start), 24*time.Hour)
+	perDay := float64(monthly.Current) / elapsed.Hours() * 24
+	f := Forecast{PerDay: math.Round(perDay*10) / 10, Projected: monthly.Current}
+	if perDay == 0 {
+		return f
+	}
+
+	left := monthly.Allowed - monthly.Current
+	if left <= 0 {
+		f.RunsOut = &now
+		f.Projected = monthly.Allowed
+		return f
+	}
+	if remaining := reset.Sub(now); remaining > 0 {
+		f.Projected = min(monthly.Current+int(math.Round(perDay*remaining.Hours()/24)), monthly.Allowed)
+	}
+	runsOut := now.Add(time.Duration(float64(left) / perDay * float64(24*time.Hour)))
+	if runsOut.Before(reset) {
+		f.RunsOut = &runsOut
+	}
+	return f
+}
+
+// WriteLimits writes q to w as text, with times relative to now
+func WriteLimits(w io.Writer, q Quota, now time.Time) {
+	fmt.Fprintf(w, "Tier: %s\n", q.Tier)
+	fmt.Fprintf(w, "Concurrent: %d/%d generating\n", q.Concurrent.Current, q.Concurrent.Allowed)
+
+	monthly := fmt.Sprintf("Monthly: %d/%d used, %d left", q.Monthly.Current, q.Monthly.Allowed, max(q.Monthly.Allowed-q.Monthly.Current, 0))
+	if q.Reserve > 0 {
+		monthly = fmt.Sprintf("%s, keeping %d in reserve", monthly, q.Reserve)
+	}
+	fmt.Fprintln(w, monthly)
+	fmt.Fprintf(w, "Generated this month: %d map(s) according to the local history\n", q.Generated)
+	fmt.Fprintf(w, "Resets: %s (%s)\n", q.Resets.Format("2006-01-02 15:04 MST"), within(q.Resets.Sub(now)))
+
+	f := q.Forecast
+	switch {
+	case q.Monthly.Current >= q.Monthly.Allowed:
+		fmt.Fprintln(w, "Forecast: the monthly quota is used up")
+	case q.Monthly.Current == 0:
+		fmt.Fprintln(w, "Forecast: no maps used this month")
+	case f.RunsOut != nil:
+		fmt.Fprintf(w, "Forecast: %.1f map(s) a day, runs out %s (%s)\n", f.PerDay, f.RunsOut.Format("2006-01-02"), within(f.RunsOut.Sub(now)))
+	default:
+		fmt.Fprintf(w, "Forecast: %.1f map(s) a day, %d/%d used by the reset\n", f.PerDay, f.Projected, q.Monthly.Allowed)
+	}
+}
+
+// within describes how long until a time in whole days
+func within(d time.Duration) string {
+	days := int(d / (24 * time.Hour))
+	switch {
+	case d <= 0:
+		return "now"
+	case days == 0:
+		return "within a day"
+	case days == 1:
+		return "in 1 day"
+	}
+	return fmt.Sprintf("in %d days", days)
+}
@@ -0,0 +1,136 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestNewForecast(t *testing.T) {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	reset := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		monthly       Usage
+		now           time.Time
+		wantPerDay    float64
+		wantProjected int
+		wantRunsOut   time.Time
+	}{
+		{
+			name:          "Nothing used",
+			monthly:       Usage{Current: 0, Allowed: 800},
+			now:           start.AddDate(0, 0, 10),
+			wantPerDay:    0,
+			wantProjected: 0,
+		},
+		{
+			name:          "Lasts until the reset",
+			monthly:       Usage{Current: 100, Allowed: 800},
+			now:           start.AddDate(0, 0, 10),
+			wantPerDay:    10,
+			wantProjected: 310,
+		},
+		{
+			name:          "Runs out",
+			monthly:       Usage{Current: 500, Allowed: 800},
+			now:           start.AddDate(0, 0, 10),
+			wantPerDay:    50,
+			wantProjected: 800,
+			wantRunsOut:   start.AddDate(0, 0, 16),
+		},
+		{
+			name:          "First hours count as a day",
+			monthly:       Usage{Current: 5, Allowed: 250},
+			now:           start.Add(2 * time.Hour),
+			wantPerDay:    5,
+			wantProjected: 160,
+		},
+		{
+			name:          "Used up",
+			monthly:       Usage{Current: 250, Allowed: 250},
+			now:           start.AddDate(0, 0, 10),
+			wantPerDay:    25,
+			wantProjected: 250,
+			wantRunsOut:   start.AddDate(0, 0, 10),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewForecast(tt.monthly, start, reset, tt.now)
+			if f.PerDay != tt.wantPerDay || f.Projected != tt.wantProjected {
+				t.Errorf("NewForecast() = %v a day, %d projected, want %v, %d", f.PerDay, f.Projected, tt.wantPerDay, tt.wantProjected)
+			}
+			switch {
+			case tt.wantRunsOut.IsZero() && f.RunsOut != nil:
+				t.Errorf("NewForecast() runs out %v, want it to last", *f.RunsOut)
+			case !tt.wantRunsOut.IsZero() && (f.RunsOut == nil || !f.RunsOut.Equal(tt.wantRunsOut)):
+				t.Errorf("NewForecast() runs out %v, want %v", f.RunsOut, tt.wantRunsOut)
+			}
+		})
+	}
+}
+
+func TestWriteLimits(t *testing.T) {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	reset := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	now := start.AddDate(0, 0, 10)
+	tests := []struct {
+		name    string
+		monthly Usage
+		reserve int
+		// wantMonthly and wantForecast are the lines that differ
+		wantMonthly  string
+		wantForecast string
+	}{
+		{
+			name:         "Lasts",
+			monthly:      Usage{Current: 100, Allowed: 800},
+			wantMonthly:  "Monthly: 100/800 used, 700 left\n",
+			wantForecast: "Forecast: 10.0 map(s) a day, 310/800 used by the reset\n",
+		},
+		{
+			name:         "Runs out",
+			monthly:      Usage{Current: 500, Allowed: 800},
+			reserve:      20,
+			wantMonthly:  "Monthly: 500/800 used, 300 left, keeping 20 in reserve\n",
+			wantForecast: "Forecast: 50.0 map(s) a day, runs out 2026-10-17 (in 6 days)\n",
+		},
+		{
+			name:         "Nothing used",
+			monthly:      Usage{Allowed: 800},
+			wantMonthly:  "Monthly: 0/800 used, 800 left\n",
+			wantForecast: "Forecast: no maps used this month\n",
+		},
+		{
+			name:         "Used up",
+			monthly:      Usage{Current: 800, Allowed: 800},
+			wantMonthly:  "Monthly: 800/800 used, 0 left\n",
+			wantForecast: "Forecast: the monthly quota is used up\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := Quota{
+				Tier:      "Premium",
+				Limits:    Limits{Concurrent: Usage{Current: 1, Allowed: 3}, Monthly: tt.monthly},
+				Reserve:   tt.reserve,
+				Generated: 7,
+				Resets:    reset,
+				Forecast:  NewForecast(tt.monthly, start, reset, now),
+			}
+			want := "Tier: Premium\n" +
+				"Concurrent: 1/3 generating\n" +
+				tt.wantMonthly +
+				"Generated this month: 7 map(s) according to the local history\n" +
+				"Resets: 2026-11-01 00:00 UTC (in 21 days)\n" +
+				tt.wantForecast
+
+			var buf bytes.Buffer
+			WriteLimits(&buf, q, now)
+			if got := buf.String(); got != want {
+				t.Errorf("WriteLimits() = %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// ListDocument is the result of rustmaps list. Files are set on the maps
+// downloaded locally.
+type ListDocument struct {
+	Header
+	Maps []MapRecord `json:"maps"`
+}
+
+// WriteList writes maps to w as a table, with whether each one was
+// downloaded locally
+func WriteList(w io.Writer, maps []MapRecord) {
+	if len(maps) == 0 {
+		fmt.Fprintln(w, "No maps found")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SEED\tSIZE\tCONFIG\tSTAGING\tSTATUS\tMAP ID\tSUBMITTED\tCOMPLETED\tDOWNLOADED")
+	for _, m := range maps {
+		config := m.SavedConfig
+		if config == "" {
+			config = "procedural"
+		}
+		downloaded := "no"
+		if m.Files != nil {
+			downloaded = "yes"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%t\t%s\t%s\t%s\t%s\t%s\n",
+			m.Seed, m.Size, config, m.Staging, m.Status, dash(m.MapID),
+			dash(listTime(m.SubmittedAt)), dash(listTime(m.CompletedAt)), downloaded)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "%d map(s)\n", len(maps))
+}
+
+// listTime shortens an RFC3339 time to the minute, in the zone it was
+// recorded in
+func listTime(rfc3339 string) string {
+	t, err := time.Parse(time.RFC3339, rfc3339)
+	if err != nil {
+		return rfc3339
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+// dash stands in for an empty column
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
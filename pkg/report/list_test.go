@@ -0,0 +1,36 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteList(t *testing.T) {
+	tests := []struct {
+		name string
+		maps []MapRecord
+		want string
+	}{
+		{name: "No maps", want: "No maps found\n"},
+		{
+			name: "Maps",
+			maps: []MapRecord{
+				{Seed: "1", Size: 4000, MapID: "abc", Status: "Downloaded", SubmittedAt: "2026-10-02T10:00:00+02:00", CompletedAt: "2026-10-02T10:05:30+02:00", Files: &Assets{Map: "1.map"}},
+				{Seed: "2", Size: 3500, SavedConfig: "mycfg", Staging: true, Status: "Pending"},
+			},
+			want: "SEED  SIZE  CONFIG      STAGING  STATUS      MAP ID  SUBMITTED         COMPLETED         DOWNLOADED\n" +
+				"1     4000  procedural  false    Downloaded  abc     2026-10-02 10:00  2026-10-02 10:05  yes\n" +
+				"2     3500  mycfg       true     Pending     -       -                 -                 no\n" +
+				"2 map(s)\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			WriteList(&buf, tt.maps)
+			if got := buf.String(); got != tt.want {
+				t.Errorf("WriteList() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
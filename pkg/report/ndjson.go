@@ -0,0 +1,61 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/types"
+)
+
+// NDJSON writes every event as a JSON object on its own line
+type NDJSON struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewNDJSON creates an NDJSON reporter writing to w
+func NewNDJSON(w io.Writer) *NDJSON {
+	return &NDJSON{enc: json.NewEncoder(w)}
+}
+
+// record is the JSON form of an Event
+type record struct {
+	Version int        `json:"version"`
+	Type    EventType  `json:"type"`
+	Time    time.Time  `json:"time"`
+	Map     *types.Map `json:"map,omitempty"`
+	Limit   string     `json:"limit,omitempty"`
+	Path    string     `json:"path,omitempty"`
+	Message string     `json:"message,omitempty"`
+	Error   string     `json:"error,omitempty"`
+	// Progress and Limits are pointers so they are left out when unset
+	Progress *Progress `json:"progress,omitempty"`
+	Limits   *Limits   `json:"limits,omitempty"`
+}
+
+// Report implements Reporter
+func (n *NDJSON) Report(e Event) {
+	r := record{
+		Version:  SchemaVersion,
+		Type:     e.Type,
+		Time:     e.Time,
+		Map:      e.Map,
+		Limit:    e.Limit,
+		Path:     e.Path,
+		Message:  e.Message,
+		Progress: e.Progress,
+		Limits:   e.Limits,
+	}
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	if e.Err != nil {
+		r.Error = e.Err.Error()
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.enc.Encode(r)
+}
@@ -0,0 +1,80 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/types"
+)
+
+func TestNDJSON_Report(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	m := types.NewMap("123", 4000, "", false)
+
+	tests := []struct {
+		name  string
+		event Event
+		want  string
+	}{
+		{
+			name:  "Map status",
+			event: Event{Type: MapStatus, Time: at, Map: m},
+			want:  `{"version":1,"type":"map_status","time":"2024-01-02T03:04:05Z","map":` + mustJSON(t, m) + `}`,
+		},
+		{
+			name:  "Limit reached",
+			event: Event{Type: LimitReached, Time: at, Limit: LimitMonthly},
+			want:  `{"version":1,"type":"limit_reached","time":"2024-01-02T03:04:05Z","limit":"monthly"}`,
+		},
+		{
+			name:  "Map status with progress",
+			event: Event{Type: MapStatus, Time: at, Map: m, Progress: &Progress{QueuePosition: 2, State: "Queued", LastPing: at}},
+			want:  `{"version":1,"type":"map_status","time":"2024-01-02T03:04:05Z","map":` + mustJSON(t, m) + `,"progress":{"queue_position":2,"state":"Queued","last_ping":"2024-01-02T03:04:05Z"}}`,
+		},
+		{
+			name:  "Limits checked",
+			event: Event{Type: LimitsChecked, Time: at, Limits: &Limits{Concurrent: Usage{Current: 1, Allowed: 2}, Monthly: Usage{Current: 3, Allowed: 800}}},
+			want:  `{"version":1,"type":"limits_checked","time":"2024-01-02T03:04:05Z","limits":{"concurrent":{"current":1,"allowed":2},"monthly":{"current":3,"allowed":800}}}`,
+		},
+		{
+			name:  "Error",
+			event: Event{Type: Error, Time: at, Message: "getting limits", Err: errors.New("boom")},
+			want:  `{"version":1,"type":"error","time":"2024-01-02T03:04:05Z","message":"getting limits","error":"boom"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			NewNDJSON(&buf).Report(tt.event)
+			if got := strings.TrimSuffix(buf.String(), "\n"); got != tt.want {
+				t.Errorf("NDJSON.Report() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNDJSON_Report_fillsTime(t *testing.T) {
+	var buf bytes.Buffer
+	NewNDJSON(&buf).Report(Event{Type: LimitReached, Limit: LimitConcurrent})
+
+	var got record
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("NDJSON.Report() wrote invalid JSON: %v", err)
+	}
+	if got.Time.IsZero() {
+		t.Errorf("NDJSON.Report() time is zero, want the current time")
+	}
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
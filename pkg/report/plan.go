@@ -0,0 +1,120 @@
+package report
+
+import (
+	"fmt"
+	"io"
+)
+
+// Plan actions, what a run would do with a map
+const (
+	// ActionSubmit maps would be submitted, each using one map of the
+	// monthly quota
+	ActionSubmit = "submit"
+	// ActionComplete maps are already generated
+	ActionComplete = "complete"
+	// ActionWait maps are already generating and would be waited for
+	ActionWait = "wait"
+	// ActionSkip maps failed or were refused before and are left alone
+	// without --force
+	ActionSkip = "skip"
+)
+
+// PlannedMap is a map and what a run would do with it
+type PlannedMap struct {
+	MapRecord
+	Action string `json:"action"`
+	// Reason explains the action when it is not obvious from the status
+	Reason string `json:"reason,omitempty"`
+}
+
+// Plan is what a generate run would do, without doing it
+type Plan struct {
+	Maps []PlannedMap `json:"maps"`
+	// Actions counts the maps per action
+	Actions map[string]int `json:"actions"`
+	// Quota is how many maps of the monthly quota the run would use
+	Quota int `json:"quota"`
+	// Monthly is the account's monthly usage now, when it could be fetched
+	Monthly *Usage `json:"monthly,omitempty"`
+	// Reserve is how many maps of the monthly quota are always left unused
+	Reserve int `json:"reserve,omitempty"`
+	// MaxMaps caps how many maps the run may generate, 0 means no cap
+	MaxMaps int `json:"max_maps,omitempty"`
+}
+
+// Add records that a run would take action on r
+func (p *Plan) Add(r MapRecord, action, reason string) {
+	p.Maps = append(p.Maps, PlannedMap{MapRecord: r, Action: action, Reason: reason})
+	if p.Actions == nil {
+		p.Actions = make(map[string]int)
+	}
+	p.Actions[action]++
+	if action == ActionSubmit {
+		p.Quota++
+	}
+}
+
+// Shortfall is how many of the maps to submit do not fit in what is left
+// of the monthly quota after the reserve, or in the maximum for the run. It
+// is 0 when they fit or neither limit is known.
+func (p Plan) Shortfall() int {
+	left := available(p.Monthly, p.Reserve, p.MaxMaps)
+	if left < 0 {
+		return 0
+	}
+	return max(p.Quota-left, 0)
+}
+
+// planSections are the headings WritePlan groups maps under, in order
+var planSections = []struct {
+	action  string
+	heading string
+}{
+	{ActionSubmit, "Would submit"},
+	{ActionWait, "Already generating"},
+	{ActionComplete, "Already complete"},
+	{ActionSkip, "Would skip, use --force to submit again"},
+}
+
+// WritePlan writes p to w as text
+func WritePlan(w io.Writer, p Plan) {
+	fmt.Fprintf(w, "Plan: %d map(s), nothing was submitted\n", len(p.Maps))
+	for _, section := range planSections {
+		if p.Actions[section.action] == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s (%d):\n", section.heading, p.Actions[section.action])
+		for _, m := range p.Maps {
+			if m.Action != section.action {
+				continue
+			}
+			config := m.SavedConfig
+			if config == "" {
+				config = "procedural"
+			}
+			line := fmt.Sprintf("  Seed: %s | Size: %d | Config: '%s' | Staging: %t", m.Seed, m.Size, config, m.Staging)
+			if m.Reason != "" {
+				line = fmt.Sprintf("%s: %s", line, m.Reason)
+			}
+			fmt.Fprintln(w, line)
+		}
+	}
+
+	quota := fmt.Sprintf("Quota: %d map(s)", p.Quota)
+	if p.Monthly != nil {
+		left := max(p.Monthly.Allowed-p.Monthly.Current, 0)
+		quota = fmt.Sprintf("%s of %d left this month (%d/%d used)", quota, left, p.Monthly.Current, p.Monthly.Allowed)
+	}
+	if p.Reserve > 0 {
+		quota = fmt.Sprintf("%s, keeping %d in reserve", quota, p.Reserve)
+	}
+	if p.MaxMaps > 0 {
+		quota = fmt.Sprintf("%s, at most %d this run", quota, p.MaxMaps)
+	}
+	fmt.Fprintln(w, quota)
+	if short := p.Shortfall(); short > 0 && p.MaxMaps > 0 {
+		fmt.Fprintf(w, "%d map(s) would not be submitted by this run\n", short)
+	} else if short > 0 {
+		fmt.Fprintf(w, "%d map(s) would not be submitted until the monthly quota resets\n", short)
+	}
+}
@@ -0,0 +1,89 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPlan_Add(t *testing.T) {
+	var p Plan
+	p.Add(MapRecord{Seed: "1"}, ActionSubmit, "")
+	p.Add(MapRecord{Seed: "2"}, ActionSubmit, "")
+	p.Add(MapRecord{Seed: "3"}, ActionComplete, "already on RustMaps")
+	if len(p.Maps) != 3 || p.Actions[ActionSubmit] != 2 || p.Actions[ActionComplete] != 1 || p.Quota != 2 {
+		t.Errorf("Plan.Add() = %+v, want 3 maps, 2 to submit using 2 of the quota", p)
+	}
+}
+
+func TestPlan_Shortfall(t *testing.T) {
+	tests := []struct {
+		name    string
+		quota   int
+		monthly *Usage
+		reserve int
+		maxMaps int
+		want    int
+	}{
+		{name: "Unknown usage", quota: 5, want: 0},
+		{name: "Fits", quota: 5, monthly: &Usage{Current: 10, Allowed: 800}, want: 0},
+		{name: "Short", quota: 5, monthly: &Usage{Current: 797, Allowed: 800}, want: 2},
+		{name: "Over the limit", quota: 1, monthly: &Usage{Current: 801, Allowed: 800}, want: 1},
+		{name: "Short of the reserve", quota: 5, monthly: &Usage{Current: 777, Allowed: 800}, reserve: 20, want: 2},
+		{name: "Short of max maps", quota: 5, monthly: &Usage{Current: 10, Allowed: 800}, maxMaps: 3, want: 2},
+		{name: "Max maps with unknown usage", quota: 5, maxMaps: 3, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Plan{Quota: tt.quota, Monthly: tt.monthly, Reserve: tt.reserve, MaxMaps: tt.maxMaps}
+			if got := p.Shortfall(); got != tt.want {
+				t.Errorf("Plan.Shortfall() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWritePlan(t *testing.T) {
+	var p Plan
+	p.Add(MapRecord{Seed: "1", Size: 4000, Status: "Pending"}, ActionSubmit, "")
+	p.Add(MapRecord{Seed: "2", Size: 4000, SavedConfig: "mycfg", Status: "Pending"}, ActionSubmit, "")
+	p.Add(MapRecord{Seed: "3", Size: 3500, Status: "Pending"}, ActionComplete, "already on RustMaps")
+	p.Add(MapRecord{Seed: "4", Size: 3500, Staging: true, Status: "Staging Not Enabled"}, ActionSkip, "Staging Not Enabled")
+	p.Monthly = &Usage{Current: 799, Allowed: 800}
+
+	var buf bytes.Buffer
+	WritePlan(&buf, p)
+	want := "Plan: 4 map(s), nothing was submitted\n" +
+		"Would submit (2):\n" +
+		"  Seed: 1 | Size: 4000 | Config: 'procedural' | Staging: false\n" +
+		"  Seed: 2 | Size: 4000 | Config: 'mycfg' | Staging: false\n" +
+		"Already complete (1):\n" +
+		"  Seed: 3 | Size: 3500 | Config: 'procedural' | Staging: false: already on RustMaps\n" +
+		"Would skip, use --force to submit again (1):\n" +
+		"  Seed: 4 | Size: 3500 | Config: 'procedural' | Staging: true: Staging Not Enabled\n" +
+		"Quota: 2 map(s) of 1 left this month (799/800 used)\n" +
+		"1 map(s) would not be submitted until the monthly quota resets\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WritePlan() = %q, want %q", got, want)
+	}
+}
+
+func TestWritePlan_budget(t *testing.T) {
+	var p Plan
+	p.Add(MapRecord{Seed: "1", Size: 4000, Status: "Pending"}, ActionSubmit, "")
+	p.Add(MapRecord{Seed: "2", Size: 4000, Status: "Pending"}, ActionSubmit, "")
+	p.Monthly = &Usage{Current: 47, Allowed: 800}
+	p.Reserve = 20
+	p.MaxMaps = 1
+
+	var buf bytes.Buffer
+	WritePlan(&buf, p)
+	want := "Plan: 2 map(s), nothing was submitted\n" +
+		"Would submit (2):\n" +
+		"  Seed: 1 | Size: 4000 | Config: 'procedural' | Staging: false\n" +
+		"  Seed: 2 | Size: 4000 | Config: 'procedural' | Staging: false\n" +
+		"Quota: 2 map(s) of 753 left this month (47/800 used), keeping 20 in reserve, at most 1 this run\n" +
+		"1 map(s) would not be submitted by this run\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WritePlan() = %q, want %q", got, want)
+	}
+}
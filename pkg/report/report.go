@@ -0,0 +1,88 @@
+// Package report delivers progress events from the generator to the user,
+// as plain text, a live table, JSON lines or not at all, and defines the
+// versioned JSON documents commands print as their result
+package report
+
+import (
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/types"
+)
+
+// EventType identifies what happened
+type EventType string
+
+const (
+	// MapStatus is sent when a map changes state
+	MapStatus EventType = "map_status"
+	// LimitReached is sent when the account cannot take more maps right now
+	LimitReached EventType = "limit_reached"
+	// DownloadStarted is sent before a map's assets are downloaded
+	DownloadStarted EventType = "download_started"
+	// DownloadFinished is sent once all of a map's assets are downloaded
+	DownloadFinished EventType = "download_finished"
+	// DownloadSkipped is sent for complete maps RustMaps does not allow to
+	// be downloaded
+	DownloadSkipped EventType = "download_skipped"
+	// Error is sent when something failed but the run carries on
+	Error EventType = "error"
+	// LimitsChecked is sent each time the account's limits are fetched
+	LimitsChecked EventType = "limits_checked"
+	// Stalled is sent when a generating map is given up on and submitted
+	// again, Message says why
+	Stalled EventType = "stalled"
+)
+
+// Limits named by LimitReached events
+const (
+	LimitConcurrent = "concurrent"
+	LimitMonthly    = "monthly"
+	// LimitBudget is the maximum number of maps set for the run
+	LimitBudget = "budget"
+)
+
+// Event is a single progress update
+type Event struct {
+	Type EventType
+	Time time.Time
+	// Map the event is about, if any
+	Map *types.Map
+	// Limit is LimitConcurrent, LimitMonthly or LimitBudget for LimitReached
+	Limit string
+	// Path is the download directory for download events
+	Path string
+	// Message is a hint for the user, or what was being done for Error
+	Message string
+	// Err is the failure for Error
+	Err error
+	// Progress is what RustMaps last said about a generating map, sent with
+	// MapStatus when known
+	Progress *Progress
+	// Limits are the account's limits for LimitsChecked
+	Limits *Limits
+}
+
+// Progress is how far along RustMaps is with a map
+type Progress struct {
+	QueuePosition int       `json:"queue_position"`
+	State         string    `json:"state,omitempty"`
+	CurrentStep   string    `json:"current_step,omitempty"`
+	LastPing      time.Time `json:"last_ping,omitempty"`
+}
+
+// Reporter receives progress events. Implementations must be safe for
+// concurrent use, maps are submitted in parallel.
+type Reporter interface {
+	Report(e Event)
+}
+
+// Func adapts a function to Reporter
+type Func func(e Event)
+
+// Report implements Reporter
+func (f Func) Report(e Event) {
+	f(e)
+}
+
+// Discard drops every event
+var Discard Reporter = Func(func(Event) {})
@@ -0,0 +1,20 @@
+package report
+
+import "testing"
+
+func TestFunc_Report(t *testing.T) {
+	var got []EventType
+	r := Func(func(e Event) { got = append(got, e.Type) })
+
+	r.Report(Event{Type: MapStatus})
+	r.Report(Event{Type: Error})
+
+	if len(got) != 2 || got[0] != MapStatus || got[1] != Error {
+		t.Errorf("Func.Report() received %v, want [%s %s]", got, MapStatus, Error)
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	// Discard must accept any event without panicking
+	Discard.Report(Event{Type: MapStatus})
+}
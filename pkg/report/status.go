@@ -0,0 +1,180 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// StatusDocument is the result of rustmaps status
+type StatusDocument struct {
+	Header
+	Maps []StatusRecord `json:"maps"`
+}
+
+// StatusRecord is a map as saved locally and what RustMaps said about it
+type StatusRecord struct {
+	MapRecord
+	// RemoteStatus is the status RustMaps reported, which a Pending map
+	// keeps to itself until generate submits it. Maps not tracked locally
+	// have this status alone.
+	RemoteStatus string `json:"remote_status,omitempty"`
+	// Details are set once RustMaps has generated the map
+	Details *MapDetails `json:"details,omitempty"`
+	// Error is why RustMaps could not be asked about the map
+	Error string `json:"error,omitempty"`
+}
+
+// MapDetails is everything RustMaps knows about a generated map
+type MapDetails struct {
+	Type           string     `json:"type"`
+	SaveVersion    int        `json:"save_version"`
+	URL            string     `json:"url"`
+	DownloadURL    string     `json:"download_url"`
+	ImageURL       string     `json:"image_url"`
+	ImageIconURL   string     `json:"image_icon_url"`
+	RawImageURL    string     `json:"raw_image_url"`
+	ThumbnailURL   string     `json:"thumbnail_url"`
+	IsCustomMap    bool       `json:"is_custom_map"`
+	CanDownload    bool       `json:"can_download"`
+	TotalMonuments int        `json:"total_monuments"`
+	Monuments      []Monument `json:"monuments"`
+	// LandPercentage is how much of the map is land rather than ocean
+	LandPercentage int    `json:"land_percentage"`
+	Biomes         Biomes `json:"biome_percentages"`
+	Islands        int    `json:"islands"`
+	Mountains      int    `json:"mountains"`
+	IceLakes       int    `json:"ice_lakes"`
+	Rivers         int    `json:"rivers"`
+	Lakes          int    `json:"lakes"`
+	Canyons        int    `json:"canyons"`
+	Oases          int    `json:"oases"`
+	BuildableRocks int    `json:"buildable_rocks"`
+}
+
+// Monument is a monument on a map and where it is
+type Monument struct {
+	Type         string `json:"type"`
+	X            int    `json:"x"`
+	Y            int    `json:"y"`
+	NameOverride string `json:"name_override,omitempty"`
+}
+
+// Biomes are the percentages of the land covered by each biome
+type Biomes struct {
+	Snow   float64 `json:"snow"`
+	Desert float64 `json:"desert"`
+	Forest float64 `json:"forest"`
+	Tundra float64 `json:"tundra"`
+	Jungle float64 `json:"jungle"`
+}
+
+// String lists the biomes that cover any of the land
+func (b Biomes) String() string {
+	var parts []string
+	for _, biome := range []struct {
+		name    string
+		percent float64
+	}{
+		{"forest", b.Forest},
+		{"desert", b.Desert},
+		{"snow", b.Snow},
+		{"tundra", b.Tundra},
+		{"jungle", b.Jungle},
+	} {
+		if biome.percent > 0 {
+			parts = append(parts, fmt.Sprintf("%s %.1f%%", biome.name, biome.percent))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// WriteStatus writes each map and what RustMaps said about it to w, one
+// block per map
+func WriteStatus(w io.Writer, maps []StatusRecord) {
+	for i, s := range maps {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		config := s.SavedConfig
+		if config == "" {
+			config = "procedural"
+		}
+		if s.Seed == "" {
+			// Only known by its map ID, which RustMaps did not find
+			fmt.Fprintf(w, "Map ID: %s\n", s.MapID)
+		} else {
+			fmt.Fprintf(w, "Seed: %s | Size: %d | Config: '%s' | Staging: %t\n", s.Seed, s.Size, config, s.Staging)
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		row := func(name string, value any) {
+			fmt.Fprintf(tw, "  %s:\t%v\n", name, value)
+		}
+		status := s.Status
+		if status == "" {
+			status = "not tracked locally"
+		}
+		row("Status", status)
+		if s.RemoteStatus != "" && s.RemoteStatus != s.Status {
+			row("RustMaps", s.RemoteStatus)
+		}
+		if s.Error != "" {
+			row("Error", s.Error)
+		}
+		if s.FailureReason != "" {
+			row("Reason", s.FailureReason)
+		}
+		if s.MapID != "" && s.Seed != "" {
+			row("Map ID", s.MapID)
+		}
+		if s.QueuePosition > 0 {
+			row("Queue position", s.QueuePosition)
+		}
+		if s.CurrentStep != "" {
+			row("Current step", s.CurrentStep)
+		}
+		if s.SubmittedAt != "" {
+			row("Submitted", listTime(s.SubmittedAt))
+		}
+		if s.CompletedAt != "" {
+			row("Completed", listTime(s.CompletedAt))
+		}
+		if d := s.Details; d != nil {
+			row("Type", d.Type)
+			if d.SaveVersion > 0 {
+				row("Save version", d.SaveVersion)
+			}
+			row("Page", dash(d.URL))
+			row("Can download", d.CanDownload)
+			row("Map file", dash(d.DownloadURL))
+			row("Image", dash(d.ImageURL))
+			row("Image with icons", dash(d.ImageIconURL))
+			row("Raw image", dash(d.RawImageURL))
+			row("Thumbnail", dash(d.ThumbnailURL))
+			row("Land", fmt.Sprintf("%d%% of the map", d.LandPercentage))
+			row("Biomes", dash(d.Biomes.String()))
+			row("Monuments", d.TotalMonuments)
+			row("Islands", d.Islands)
+			row("Mountains", d.Mountains)
+			row("Ice lakes", d.IceLakes)
+			row("Rivers", d.Rivers)
+			row("Lakes", d.Lakes)
+			row("Canyons", d.Canyons)
+			row("Oases", d.Oases)
+			row("Buildable rocks", d.BuildableRocks)
+		}
+		tw.Flush()
+
+		if s.Details != nil {
+			for _, m := range s.Details.Monuments {
+				name := m.Type
+				if m.NameOverride != "" {
+					name = fmt.Sprintf("%s (%s)", m.NameOverride, m.Type)
+				}
+				fmt.Fprintf(w, "    %s at %d, %d\n", name, m.X, m.Y)
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBiomes_String(t *testing.T) {
+	tests := []struct {
+		name   string
+		biomes Biomes
+		want   string
+	}{
+		{name: "None", want: ""},
+		{name: "Some", biomes: Biomes{Forest: 41.25, Snow: 12, Jungle: 0}, want: "forest 41.2%, snow 12.0%"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.biomes.String(); got != tt.want {
+				t.Errorf("Biomes.String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		maps []StatusRecord
+		want string
+	}{
+		{
+			name: "Generating",
+			maps: []StatusRecord{{
+				MapRecord:    MapRecord{Seed: "1", Size: 4000, MapID: "abc", Status: "Generating", QueuePosition: 3, SubmittedAt: "2026-10-02T10:00:00+02:00"},
+				RemoteStatus: "Generating",
+			}},
+			want: "Seed: 1 | Size: 4000 | Config: 'procedural' | Staging: false\n" +
+				"  Status:          Generating\n" +
+				"  Map ID:          abc\n" +
+				"  Queue position:  3\n" +
+				"  Submitted:       2026-10-02 10:00\n",
+		},
+		{
+			name: "Complete and not saved",
+			maps: []StatusRecord{
+				{
+					MapRecord:    MapRecord{Seed: "2", Size: 3500, SavedConfig: "mycfg", MapID: "def", Status: "Pending"},
+					RemoteStatus: "Complete",
+					Details: &MapDetails{
+						Type:           "Custom",
+						URL:            "https://rustmaps.com/map/def",
+						DownloadURL:    "https://files/def.map",
+						IsCustomMap:    true,
+						CanDownload:    true,
+						TotalMonuments: 2,
+						Monuments: []Monument{
+							{Type: "Airfield_1", X: 100, Y: -200},
+							{Type: "Harbor_1", X: 5, Y: 6, NameOverride: "Big Harbor"},
+						},
+						LandPercentage: 47,
+						Biomes:         Biomes{Forest: 50, Desert: 25.5},
+						Rivers:         4,
+					},
+				},
+				{
+					MapRecord:    MapRecord{Seed: "3", Size: 4000},
+					RemoteStatus: "Not Found",
+				},
+				{
+					MapRecord:    MapRecord{MapID: "ghi"},
+					RemoteStatus: "Not Found",
+				},
+			},
+			want: "Seed: 2 | Size: 3500 | Config: 'mycfg' | Staging: false\n" +
+				"  Status:            Pending\n" +
+				"  RustMaps:          Complete\n" +
+				"  Map ID:            def\n" +
+				"  Type:              Custom\n" +
+				"  Page:              https://rustmaps.com/map/def\n" +
+				"  Can download:      true\n" +
+				"  Map file:          https://files/def.map\n" +
+				"  Image:             -\n" +
+				"  Image with icons:  -\n" +
+				"  Raw image:         -\n" +
+				"  Thumbnail:         -\n" +
+				"  Land:              47% of the map\n" +
+				"  Biomes:            forest 50.0%, desert 25.5%\n" +
+				"  Monuments:         2\n" +
+				"  Islands:           0\n" +
+				"  Mountains:         0\n" +
+				"  Ice lakes:         0\n" +
+				"  Rivers:            4\n" +
+				"  Lakes:             0\n" +
+				"  Canyons:           0\n" +
+				"  Oases:             0\n" +
+				"  Buildable rocks:   0\n" +
+				"    Airfield_1 at 100, -200\n" +
+				"    Big Harbor (Harbor_1) at 5, 6\n" +
+				"\n" +
+				"Seed: 3 | Size: 4000 | Config: 'procedural' | Staging: false\n" +
+				"  Status:    not tracked locally\n" +
+				"  RustMaps:  Not Found\n" +
+				"\n" +
+				"Map ID: ghi\n" +
+				"  Status:    not tracked locally\n" +
+				"  RustMaps:  Not Found\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			WriteStatus(&buf, tt.maps)
+			if got := buf.String(); got != tt.want {
+				t.Errorf("WriteStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// Summary is how a generate run ended
+type Summary struct {
+	// Statuses counts the maps in each status
+	Statuses map[string]int `json:"statuses"`
+	// Failed are the maps that will not complete without a change, with
+	// the reason
+	Failed          []MapRecord `json:"failed,omitempty"`
+	DurationSeconds int         `json:"duration_seconds"`
+	// Generated is how many maps RustMaps started generating for the run,
+	// each one used up one map of the monthly quota
+	Generated int `json:"generated"`
+	// Monthly is the account's monthly usage at the end of the run, when
+	// it could be fetched
+	Monthly *Usage `json:"monthly,omitempty"`
+	// ExitCode is what the command exits with
+	ExitCode int `json:"exit_code"`
+}
+
+// WriteSummary writes s to w as text
+func WriteSummary(w io.Writer, s Summary) {
+	total := 0
+	statuses := make([]string, 0, len(s.Statuses))
+	for status, n := range s.Statuses {
+		statuses = append(statuses, status)
+		total += n
+	}
+	sort.Strings(statuses)
+
+	duration := time.Duration(s.DurationSeconds) * time.Second
+	fmt.Fprintf(w, "Summary: %d map(s) in %s\n", total, duration)
+	for _, status := range statuses {
+		fmt.Fprintf(w, "  %s: %d\n", status, s.Statuses[status])
+	}
+
+	if len(s.Failed) > 0 {
+		fmt.Fprintf(w, "%d map(s) failed:\n", len(s.Failed))
+		for _, r := range s.Failed {
+			config := r.SavedConfig
+			if config == "" {
+				config = "procedural"
+			}
+			reason := r.FailureReason
+			if reason == "" {
+				reason = r.Status
+			}
+			fmt.Fprintf(w, "  Seed: %s | Size: %d | Config: '%s': %s\n", r.Seed, r.Size, config, reason)
+		}
+	}
+
+	quota := fmt.Sprintf("Quota used: %d map(s)", s.Generated)
+	if s.Monthly != nil {
+		quota = fmt.Sprintf("%s, %d/%d this month", quota, s.Monthly.Current, s.Monthly.Allowed)
+	}
+	fmt.Fprintln(w, quota)
+}
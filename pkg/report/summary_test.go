@@ -0,0 +1,56 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteSummary(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary Summary
+		want    string
+	}{
+		{
+			name: "All complete",
+			summary: Summary{
+				Statuses:        map[string]int{"Complete": 2},
+				DurationSeconds: 312,
+				Generated:       2,
+				Monthly:         &Usage{Current: 47, Allowed: 800},
+			},
+			want: "Summary: 2 map(s) in 5m12s\n" +
+				"  Complete: 2\n" +
+				"Quota used: 2 map(s), 47/800 this month\n",
+		},
+		{
+			name: "Some failed",
+			summary: Summary{
+				Statuses: map[string]int{"Complete": 1, "Failed": 1, "Bad Request": 1},
+				Failed: []MapRecord{
+					{Seed: "1", Size: 4000, Status: "Failed", FailureReason: "still generating after 1h0m0s"},
+					{Seed: "2", Size: 4000, SavedConfig: "default", Status: "Bad Request"},
+				},
+				DurationSeconds: 5,
+				Generated:       2,
+			},
+			want: "Summary: 3 map(s) in 5s\n" +
+				"  Bad Request: 1\n" +
+				"  Complete: 1\n" +
+				"  Failed: 1\n" +
+				"2 map(s) failed:\n" +
+				"  Seed: 1 | Size: 4000 | Config: 'procedural': still generating after 1h0m0s\n" +
+				"  Seed: 2 | Size: 4000 | Config: 'default': Bad Request\n" +
+				"Quota used: 2 map(s)\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			WriteSummary(&buf, tt.summary)
+			if got := buf.String(); got != tt.want {
+				t.Errorf("WriteSummary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
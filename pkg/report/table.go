@@ -0,0 +1,191 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/types"
+)
+
+// maxNotes is how many recent non-map events the table keeps below the maps
+const maxNotes = 5
+
+// gaugeWidth is how many characters a limit gauge fills
+const gaugeWidth = 20
+
+// Table is a live dashboard of every map it has heard about, with the
+// account's limits above and recent events below. It redraws in place on
+// each event and needs a terminal that understands ANSI escapes.
+type Table struct {
+	mu     sync.Mutex
+	w      io.Writer
+	maps   []*types.Map
+	rows   map[*types.Map]*row
+	limits *Limits
+	notes  []string
+	now    func() time.Time
+	// lines is the height of the last drawing, which is erased before the
+	// next one
+	lines int
+}
+
+// row is what the table knows about a map
+type row struct {
+	// m is a copy of the map taken when it was last reported, the map
+	// itself may change while the table draws
+	m        types.Map
+	progress *Progress
+	// started is when the map was first seen generating, finished when it
+	// stopped
+	started  time.Time
+	finished time.Time
+}
+
+// NewTable creates a Table reporter drawing to w
+func NewTable(w io.Writer) *Table {
+	return &Table{
+		w:    w,
+		rows: make(map[*types.Map]*row),
+		now:  time.Now,
+	}
+}
+
+// Report implements Reporter
+func (t *Table) Report(e Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	at := e.Time
+	if at.IsZero() {
+		at = t.now()
+	}
+
+	if e.Map != nil {
+		r, ok := t.rows[e.Map]
+		if !ok {
+			r = &row{}
+			t.rows[e.Map] = r
+			t.maps = append(t.maps, e.Map)
+		}
+		r.m = *e.Map
+		if e.Progress != nil {
+			r.progress = e.Progress
+		}
+		switch {
+		case r.m.Status == common.StatusGenerating && r.started.IsZero():
+			r.started = at
+		case r.m.Status != common.StatusGenerating && !r.started.IsZero() && r.finished.IsZero():
+			r.finished = at
+		}
+	}
+
+	switch e.Type {
+	case MapStatus:
+	case LimitsChecked:
+		t.limits = e.Limits
+	default:
+		if note := line(e); note != "" {
+			t.notes = append(t.notes, note)
+			if len(t.notes) > maxNotes {
+				t.notes = t.notes[len(t.notes)-maxNotes:]
+			}
+		}
+	}
+
+	t.draw()
+}
+
+// Start redraws the table every interval so elapsed times keep counting
+// while nothing happens, until stop is called
+func (t *Table) Start(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	var once sync.Once
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				t.mu.Lock()
+				t.draw()
+				t.mu.Unlock()
+			}
+		}
+	}()
+	return func() { once.Do(func() { close(done) }) }
+}
+
+func (t *Table) draw() {
+	var buf bytes.Buffer
+	if t.lines > 0 {
+		// Move up to the start of the last drawing and clear it
+		fmt.Fprintf(&buf, "\x1b[%dA\x1b[J", t.lines)
+	}
+
+	var table bytes.Buffer
+	if t.limits != nil {
+		fmt.Fprintf(&table, "Concurrent %s   Monthly %s\n\n", gauge(t.limits.Concurrent), gauge(t.limits.Monthly))
+	}
+
+	now := t.now()
+	tw := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SEED\tSIZE\tCONFIG\tSTAGING\tSTATUS\tQUEUE\tSTEP\tELAPSED\tMAP ID")
+	for _, key := range t.maps {
+		r := t.rows[key]
+		m := r.m
+		config := m.SavedConfig
+		if config == "" {
+			config = "procedural"
+		}
+		queue, step := "-", "-"
+		if r.progress != nil && m.Status == common.StatusGenerating {
+			if r.progress.QueuePosition > 0 {
+				queue = fmt.Sprint(r.progress.QueuePosition)
+			}
+			if r.progress.CurrentStep != "" {
+				step = r.progress.CurrentStep
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%t\t%s\t%s\t%s\t%s\t%s\n",
+			m.Seed, m.Size, config, m.Staging, m.Status, queue, step, r.elapsed(now), m.MapID)
+	}
+	tw.Flush()
+	for _, note := range t.notes {
+		table.WriteString(note)
+		table.WriteString("\n")
+	}
+
+	t.lines = strings.Count(table.String(), "\n")
+	buf.Write(table.Bytes())
+	t.w.Write(buf.Bytes())
+}
+
+// elapsed is how long the map has been generating, or took to generate
+func (r *row) elapsed(now time.Time) string {
+	if r.started.IsZero() {
+		return "-"
+	}
+	end := now
+	if !r.finished.IsZero() {
+		end = r.finished
+	}
+	return end.Sub(r.started).Truncate(time.Second).String()
+}
+
+// gauge draws u as a bar, e.g. [#####---------------] 1/4
+func gauge(u Usage) string {
+	filled := 0
+	if u.Allowed > 0 {
+		filled = min(u.Current*gaugeWidth/u.Allowed, gaugeWidth)
+	}
+	filled = max(filled, 0)
+	return fmt.Sprintf("[%s%s] %d/%d", strings.Repeat("#", filled), strings.Repeat("-", gaugeWidth-filled), u.Current, u.Allowed)
+}
@@ -0,0 +1,133 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/types"
+)
+
+// lastDrawing returns what the table drew last, after its final erase
+func lastDrawing(out string) string {
+	if i := strings.LastIndex(out, "\x1b[J"); i >= 0 {
+		return out[i+len("\x1b[J"):]
+	}
+	return out
+}
+
+func TestTable_Report(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	now := start
+
+	a := types.NewMap("1", 4000, "", false)
+	b := types.NewMap("2", 3500, "test", true)
+	c := types.NewMap("3", 3000, "", false)
+
+	var buf bytes.Buffer
+	table := NewTable(&buf)
+	table.now = func() time.Time { return now }
+
+	table.Report(Event{Type: MapStatus, Map: a})
+	first := buf.String()
+	if strings.Contains(first, "\x1b[") {
+		t.Errorf("Table.Report() first drawing = %q, want no cursor movement", first)
+	}
+	if got := strings.Count(first, "\n"); got != 2 {
+		t.Errorf("Table.Report() first drawing has %d lines, want 2", got)
+	}
+
+	b.Status = common.StatusGenerating
+	table.Report(Event{Type: MapStatus, Map: b, Progress: &Progress{QueuePosition: 3, CurrentStep: "Queued"}})
+	c.Status = common.StatusGenerating
+	table.Report(Event{Type: MapStatus, Map: c})
+	now = start.Add(90 * time.Second)
+	c.Status = common.StatusComplete
+	table.Report(Event{Type: MapStatus, Map: c})
+	now = start.Add(125 * time.Second)
+	table.Report(Event{Type: LimitsChecked, Limits: &Limits{
+		Concurrent: Usage{Current: 1, Allowed: 2},
+		Monthly:    Usage{Current: 200, Allowed: 800},
+	}})
+	table.Report(Event{Type: LimitReached, Limit: LimitConcurrent})
+
+	want := strings.Join([]string{
+		"Concurrent [##########----------] 1/2   Monthly [#####---------------] 200/800",
+		"",
+		"SEED  SIZE  CONFIG      STAGING  STATUS      QUEUE  STEP    ELAPSED  MAP ID",
+		"1     4000  procedural  false    Pending     -      -       -        ",
+		"2     3500  test        true     Generating  3      Queued  2m5s     ",
+		"3     3000  procedural  false    Complete    -      -       1m30s    ",
+		"Cannot generate map: concurrent limit reached",
+		"",
+	}, "\n")
+	if got := lastDrawing(buf.String()); got != want {
+		t.Errorf("Table.Report() drawing =\n%s\nwant\n%s", got, want)
+	}
+	if !strings.Contains(buf.String(), "\x1b[6A\x1b[J") {
+		t.Errorf("Table.Report() did not erase the previous 6 line drawing: %q", buf.String())
+	}
+}
+
+func TestTable_Report_keepsRecentNotes(t *testing.T) {
+	var buf bytes.Buffer
+	table := NewTable(&buf)
+	for i := 0; i < maxNotes+3; i++ {
+		table.Report(Event{Type: LimitReached, Limit: LimitMonthly})
+	}
+	if got := len(table.notes); got != maxNotes {
+		t.Errorf("Table kept %d notes, want %d", got, maxNotes)
+	}
+}
+
+func TestTable_Start(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		buf bytes.Buffer
+	)
+	table := NewTable(writerFunc(func(p []byte) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return buf.Write(p)
+	}))
+
+	stop := table.Start(time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	stop()
+	stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Count(buf.String(), "SEED") < 2 {
+		t.Errorf("Table.Start() did not redraw: %q", buf.String())
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func Test_gauge(t *testing.T) {
+	tests := []struct {
+		name string
+		u    Usage
+		want string
+	}{
+		{name: "Empty", u: Usage{Current: 0, Allowed: 4}, want: "[--------------------] 0/4"},
+		{name: "Half", u: Usage{Current: 2, Allowed: 4}, want: "[##########----------] 2/4"},
+		{name: "Over", u: Usage{Current: 6, Allowed: 4}, want: "[####################] 6/4"},
+		{name: "No allowance", u: Usage{Current: 1, Allowed: 0}, want: "[--------------------] 1/0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := gauge(tt.u); got != tt.want {
+				t.Errorf("gauge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
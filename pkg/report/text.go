@@ -0,0 +1,61 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Text writes one line per event, the format rustmaps has always printed
+type Text struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewText creates a Text reporter writing to w
+func NewText(w io.Writer) *Text {
+	return &Text{w: w}
+}
+
+// Report implements Reporter
+func (t *Text) Report(e Event) {
+	msg := line(e)
+	if msg == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintln(t.w, msg)
+}
+
+// line describes e in plain text, empty when there is nothing to say
+func line(e Event) string {
+	switch e.Type {
+	case MapStatus:
+		if e.Map.FailureReason != "" && e.Map.IsFailed() {
+			return fmt.Sprintf("%s | Reason: %s", e.Map.String(), e.Map.FailureReason)
+		}
+		return e.Map.String()
+	case LimitReached:
+		return fmt.Sprintf("Cannot generate map: %s limit reached", e.Limit)
+	case DownloadStarted:
+		return fmt.Sprintf("Downloading %s", e.Map.String())
+	case DownloadFinished:
+		return fmt.Sprintf("Downloaded %s to %s", e.Map.String(), e.Path)
+	case DownloadSkipped:
+		msg := fmt.Sprintf("Cannot download %s", e.Map.String())
+		if e.Message != "" {
+			msg = fmt.Sprintf("%s\n%s", msg, e.Message)
+		}
+		return msg
+	case Stalled:
+		return fmt.Sprintf("Resubmitting %s: %s", e.Map.String(), e.Message)
+	case Error:
+		if e.Map != nil {
+			return fmt.Sprintf("Error %s for %s: %v", e.Message, e.Map.String(), e.Err)
+		}
+		return fmt.Sprintf("Error %s: %v", e.Message, e.Err)
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/maintc/rustmaps-cli/pkg/types"
+)
+
+func TestText_Report(t *testing.T) {
+	m := types.NewMap("123", 4000, "test", false)
+	m.Status = "Complete"
+
+	tests := []struct {
+		name  string
+		event Event
+		want  string
+	}{
+		{
+			name:  "Map status",
+			event: Event{Type: MapStatus, Map: m},
+			want:  "Seed: 123 | Size: 4000 | Config: 'test' | Status: 'Complete'\n",
+		},
+		{
+			name:  "Concurrent limit",
+			event: Event{Type: LimitReached, Limit: LimitConcurrent},
+			want:  "Cannot generate map: concurrent limit reached\n",
+		},
+		{
+			name:  "Download started",
+			event: Event{Type: DownloadStarted, Map: m, Path: "/tmp/maps"},
+			want:  "Downloading Seed: 123 | Size: 4000 | Config: 'test' | Status: 'Complete'\n",
+		},
+		{
+			name:  "Download finished",
+			event: Event{Type: DownloadFinished, Map: m, Path: "/tmp/maps"},
+			want:  "Downloaded Seed: 123 | Size: 4000 | Config: 'test' | Status: 'Complete' to /tmp/maps\n",
+		},
+		{
+			name:  "Download skipped",
+			event: Event{Type: DownloadSkipped, Map: m, Message: "open it instead"},
+			want:  "Cannot download Seed: 123 | Size: 4000 | Config: 'test' | Status: 'Complete'\nopen it instead\n",
+		},
+		{
+			name:  "Error",
+			event: Event{Type: Error, Message: "getting limits", Err: errors.New("boom")},
+			want:  "Error getting limits: boom\n",
+		},
+		{
+			name:  "Error for map",
+			event: Event{Type: Error, Map: m, Message: "getting status", Err: errors.New("boom")},
+			want:  "Error getting status for Seed: 123 | Size: 4000 | Config: 'test' | Status: 'Complete': boom\n",
+		},
+		{
+			name:  "Failed map",
+			event: Event{Type: MapStatus, Map: &types.Map{Seed: "1", Size: 3000, Status: "Failed", FailureReason: "stalled"}},
+			want:  "Seed: 1 | Size: 3000 | Config: '' | Status: 'Failed' | Reason: stalled\n",
+		},
+		{
+			name:  "Refused map",
+			event: Event{Type: MapStatus, Map: &types.Map{Seed: "1", Size: 3000, Status: "Forbidden", FailureReason: "rustmaps api: 403 Forbidden"}},
+			want:  "Seed: 1 | Size: 3000 | Config: '' | Status: 'Forbidden' | Reason: rustmaps api: 403 Forbidden\n",
+		},
+		{
+			name:  "Stalled",
+			event: Event{Type: Stalled, Map: m, Message: "no word from the generator for 20m0s"},
+			want:  "Resubmitting Seed: 123 | Size: 4000 | Config: 'test' | Status: 'Complete': no word from the generator for 20m0s\n",
+		},
+		{
+			name:  "Limits checked",
+			event: Event{Type: LimitsChecked, Limits: &Limits{}},
+			want:  "",
+		},
+		{
+			name:  "Unknown event",
+			event: Event{Type: "unknown"},
+			want:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			NewText(&buf).Report(tt.event)
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Text.Report() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package rustmaps
 
 import (
+	"context"
 	"fmt"
 
 	"go.uber.org/zap"
@@ -20,8 +21,8 @@ func (g *Generator) ValidateAuthentication(log *zap.Logger) error {
 	return nil
 }
 
-func (g *Generator) DetermineTier(log *zap.Logger) (string, bool) {
-	limits, err := g.rmcli.GetLimits(log)
+func (g *Generator) DetermineTier(ctx context.Context, log *zap.Logger) (string, bool) {
+	limits, err := g.rmcli.GetLimits(ctx, log)
 	if err != nil {
 		log.Error("Error getting limits", zap.Error(err))
 		return "", false
@@ -1,6 +1,7 @@
 package rustmaps
 
 import (
+	"context"
 	"path/filepath"
 	"testing"
 
@@ -99,7 +100,7 @@ func TestGenerator_DetermineTier(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, got1 := tt.generator.DetermineTier(tt.args.log)
+			got, got1 := tt.generator.DetermineTier(context.Background(), tt.args.log)
 			if got != tt.want {
 				t.Errorf("Generator.DetermineTier() got = %v, want %v", got, tt.want)
 			}
@@ -0,0 +1,39 @@
+package rustmaps
+
+import (
+	"context"
+
+	"github.com/maintc/rustmaps-cli/pkg/report"
+	"go.uber.org/zap"
+)
+
+// Budget counts the maps waiting to be submitted against the account's
+// monthly usage, the quota reserve and the maximum maps for the run, so a
+// run that cannot submit all of them can be refused before it starts
+func (g *Generator) Budget(ctx context.Context, log *zap.Logger) (report.Budget, error) {
+	limits, err := g.rmcli.GetLimits(ctx, log)
+	if err != nil {
+		return report.Budget{}, err
+	}
+
+	b := report.Budget{
+		Monthly: report.Usage{Current: limits.Data.Monthly.Current, Allowed: limits.Data.Monthly.Allowed},
+		Reserve: g.quotaReserve(),
+		MaxMaps: g.maxMaps,
+	}
+	for _, m := range g.maps {
+		if awaitingSubmission(m) {
+			b.Needed++
+		}
+	}
+	return b, nil
+}
+
+// quotaReserve is how many maps of the monthly quota are left unused, the
+// configured reserve unless it was overridden for the run
+func (g *Generator) quotaReserve() int {
+	if g.reserve != nil {
+		return *g.reserve
+	}
+	return g.config.QuotaReserve
+}
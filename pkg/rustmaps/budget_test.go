@@ -0,0 +1,62 @@
+package rustmaps
+
+import (
+	"context"
+	"testing"
+
+	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/report"
+	"github.com/maintc/rustmaps-cli/pkg/types"
+	"go.uber.org/zap"
+)
+
+func TestGenerator_Budget(t *testing.T) {
+	reserve := 5
+	tests := []struct {
+		name    string
+		g       *Generator
+		want    report.Budget
+		wantErr bool
+	}{
+		{
+			name: "Counts maps waiting to be submitted",
+			g: NewMockedGenerator(t, &Generator{
+				config:  types.Config{QuotaReserve: 20},
+				maxMaps: 10,
+				maps: []*types.Map{
+					{Seed: "1", Size: 4000, Status: common.StatusPending},
+					{Seed: "2", Size: 4000, Status: common.StatusRateLimited},
+					{Seed: "3", Size: 4000, Status: common.StatusGenerating},
+					{Seed: "4", Size: 4000, Status: common.StatusComplete},
+				},
+				rmcli: &MockedRustMapsCLI{MonthlyCurrent: 47, MonthlyAllowed: 800},
+			}),
+			want: report.Budget{Needed: 2, Monthly: report.Usage{Current: 47, Allowed: 800}, Reserve: 20, MaxMaps: 10},
+		},
+		{
+			name: "Overridden reserve",
+			g: NewMockedGenerator(t, &Generator{
+				config:  types.Config{QuotaReserve: 20},
+				reserve: &reserve,
+				rmcli:   &MockedRustMapsCLI{MonthlyAllowed: 800},
+			}),
+			want: report.Budget{Monthly: report.Usage{Allowed: 800}, Reserve: 5},
+		},
+		{
+			name:    "GetLimits error",
+			g:       NewMockedGenerator(t, &Generator{rmcli: &MockedRustMapsCLI{LimitsError: true}}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.g.Budget(context.Background(), zap.NewNop())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Generator.Budget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Generator.Budget() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,9 +2,13 @@ package rustmaps
 
 import (
 	"encoding/json"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/maintc/rustmaps-cli/pkg/api"
+	"github.com/maintc/rustmaps-cli/pkg/fsutil"
+	"github.com/maintc/rustmaps-cli/pkg/types"
 )
 
 // LoadConfig loads the configuration from disk
@@ -13,6 +17,9 @@ func (g *Generator) LoadConfig() error {
 	if err != nil {
 		if os.IsNotExist(err) {
 			// Create default config if it doesn't exist
+			if g.rmcli, err = g.newClient(); err != nil {
+				return err
+			}
 			return g.SaveConfig()
 		}
 		return err
@@ -22,16 +29,76 @@ func (g *Generator) LoadConfig() error {
 		return err
 	}
 
-	g.rmcli = api.NewRustMapsClient(g.config.APIKey)
+	if g.rmcli, err = g.newClient(); err != nil {
+		return err
+	}
+	return g.openStore()
+}
 
-	return nil
+// settings returns the loaded configuration with this run's overrides applied
+func (g *Generator) settings() types.Config {
+	cfg := g.config
+	if g.overrides.APIUrl != "" {
+		cfg.APIUrl = g.overrides.APIUrl
+	}
+	if g.overrides.RateLimit > 0 {
+		cfg.RateLimit = g.overrides.RateLimit
+	}
+	if g.overrides.RateBurst > 0 {
+		cfg.RateBurst = g.overrides.RateBurst
+	}
+	if g.overrides.Timeout > 0 {
+		cfg.Timeout = g.overrides.Timeout
+	}
+	if g.overrides.UserAgent != "" {
+		cfg.UserAgent = g.overrides.UserAgent
+	}
+	if g.overrides.Proxy != "" {
+		cfg.Proxy = g.overrides.Proxy
+	}
+	if g.overrides.CABundle != "" {
+		cfg.CABundle = g.overrides.CABundle
+	}
+	return cfg
+}
+
+// newClient builds an API client from the configuration, with any options
+// set on the generator applied on top. A configured proxy or CA bundle is
+// used for asset downloads too.
+func (g *Generator) newClient() (api.RustMapsClientBase, error) {
+	cfg := g.settings()
+
+	var opts []api.Option
+	if cfg.APIUrl != "" {
+		opts = append(opts, api.WithBaseURL(cfg.APIUrl))
+	}
+	if cfg.RateLimit > 0 || cfg.RateBurst > 0 {
+		opts = append(opts, api.WithRateLimiter(api.NewRateLimiter(cfg.RateLimit, cfg.RateBurst)))
+	}
+	if cfg.Timeout > 0 {
+		opts = append(opts, api.WithTimeout(time.Duration(cfg.Timeout)*time.Second))
+	}
+	if cfg.UserAgent != "" {
+		opts = append(opts, api.WithUserAgent(cfg.UserAgent))
+	}
+	if cfg.Proxy != "" || cfg.CABundle != "" {
+		transport, err := api.NewTransport(cfg.Proxy, cfg.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, api.WithHTTPClient(&http.Client{Transport: transport}))
+		g.httpClient = &http.Client{Transport: transport}
+	}
+	opts = append(opts, g.clientOptions...)
+	return api.NewRustMapsClient(cfg.APIKey, opts...), nil
 }
 
-// SaveConfig saves the current configuration to disk
+// SaveConfig saves the current configuration to disk, replacing the file
+// atomically
 func (g *Generator) SaveConfig() error {
 	data, err := json.MarshalIndent(g.config, "", "    ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(g.configPath, data, 0644)
+	return fsutil.WriteFile(g.configPath, data, 0644)
 }
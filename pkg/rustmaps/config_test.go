@@ -58,6 +58,9 @@ func TestGenerator_LoadConfig(t *testing.T) {
 			if err := tt.generator.LoadConfig(); (err != nil) != tt.wantErr {
 				t.Errorf("Generator.LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			if !tt.wantErr && tt.generator.rmcli == nil {
+				t.Errorf("Generator.LoadConfig() did not create an API client")
+			}
 		})
 	}
 }
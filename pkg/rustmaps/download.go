@@ -1,16 +1,20 @@
 package rustmaps
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"math"
 	"net/http"
 	"os"
 	"path/filepath"
 	"time"
 
+	"github.com/maintc/rustmaps-cli/pkg/api"
 	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/fsutil"
+	"github.com/maintc/rustmaps-cli/pkg/report"
+	"github.com/maintc/rustmaps-cli/pkg/types"
 	"go.uber.org/zap"
 )
 
@@ -21,6 +25,28 @@ type DownloadLinks struct {
 	ThumbnailURL string `json:"thumbnail_url"`
 }
 
+// DownloadFiles are the paths a map's assets were downloaded to
+type DownloadFiles struct {
+	Map           string
+	Image         string
+	ImageIcons    string
+	Thumbnail     string
+	DownloadLinks string
+	Specs         string
+}
+
+// DownloadResult is what the last Download did with a complete map
+type DownloadResult struct {
+	Map *types.Map
+	// URL is the map's page on rustmaps.com
+	URL   string
+	Links DownloadLinks
+	// Skipped is set when RustMaps does not allow the map to be downloaded,
+	// Files is empty then
+	Skipped bool
+	Files   DownloadFiles
+}
+
 func (g *Generator) OverrideDownloadsDir(log *zap.Logger, dir string) {
 	g.downloadsDir = dir
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -28,104 +54,120 @@ func (g *Generator) OverrideDownloadsDir(log *zap.Logger, dir string) {
 	}
 }
 
-// DownloadFile downloads a file using net/http
-func (g *Generator) DownloadFile(log *zap.Logger, url, target string) error {
-	maxRetries := 3
-	backoff := 5 * time.Second
-
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	var lastErr error
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		if attempt > 0 {
-			sleepDuration := backoff * time.Duration(math.Pow(2, float64(attempt-1)))
-			log.Info("Retrying download",
-				zap.Int("attempt", attempt),
-				zap.Duration("backoff", sleepDuration))
-			time.Sleep(sleepDuration)
-		}
+// downloadRetryPolicy retries asset downloads, which are served from a CDN
+// rather than the API and can take a while for large maps
+var downloadRetryPolicy = api.RetryPolicy{
+	MaxAttempts:    4,
+	BaseDelay:      5 * time.Second,
+	MaxDelay:       30 * time.Second,
+	AttemptTimeout: 2 * time.Minute,
+}
 
-		req, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			lastErr = err
-			log.Error("Error creating request", zap.Error(err))
-			continue
+// DownloadFile downloads a file using net/http. The whole download is
+// retried under the download retry policy, including a body cut off
+// midway, and target is only replaced once a download completed.
+func (g *Generator) DownloadFile(ctx context.Context, log *zap.Logger, url, target string) error {
+	attempts := max(g.downloadRetry.MaxAttempts, 1)
+	for attempt := 1; ; attempt++ {
+		retry, err := g.downloadOnce(ctx, log, url, target)
+		if err == nil || !retry || ctx.Err() != nil || attempt >= attempts {
+			return err
 		}
 
-		resp, err := client.Do(req)
-		if err != nil {
-			lastErr = err
-			log.Error("Error downloading file",
-				zap.Error(err),
-				zap.Int("attempt", attempt))
-			continue
+		delay := g.downloadRetry.Backoff(attempt)
+		log.Info("Retrying download",
+			zap.String("url", url),
+			zap.Int("attempt", attempt+1),
+			zap.Duration("backoff", delay),
+			zap.NamedError("last_error", err))
+		if err := sleep(ctx, delay); err != nil {
+			return err
 		}
+	}
+}
 
-		// Always close response body, but keep error for checking
-		defer resp.Body.Close()
+// downloadOnce makes a single attempt at downloading url to target,
+// reporting whether a failure is worth retrying
+func (g *Generator) downloadOnce(ctx context.Context, log *zap.Logger, url, target string) (retry bool, err error) {
+	if g.downloadRetry.AttemptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.downloadRetry.AttemptTimeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		log.Error("Error creating request", zap.Error(err))
+		return false, err
+	}
 
-		if resp.StatusCode != http.StatusOK {
-			lastErr = fmt.Errorf("error downloading file: %s", resp.Status)
-			log.Error("Error downloading file",
-				zap.String("status", resp.Status),
-				zap.Int("attempt", attempt))
-			continue
-		}
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		log.Error("Error downloading file", zap.String("url", url), zap.Error(err))
+		return true, err
+	}
+	defer resp.Body.Close()
 
-		file, err := os.Create(target)
-		if err != nil {
-			lastErr = err
-			log.Error("Error creating file", zap.Error(err))
-			return err // Don't retry file creation errors
-		}
-		defer file.Close()
-
-		_, err = io.Copy(file, resp.Body)
-		if err != nil {
-			lastErr = err
-			log.Error("Error writing file",
-				zap.Error(err),
-				zap.Int("attempt", attempt))
-			continue
-		}
+	if resp.StatusCode != http.StatusOK {
+		log.Error("Error downloading file", zap.String("url", url), zap.String("status", resp.Status))
+		return resp.StatusCode >= http.StatusInternalServerError, fmt.Errorf("error downloading file: %s", resp.Status)
+	}
 
-		// If we get here, the download was successful
-		log.Info("File downloaded successfully",
-			zap.String("url", url),
-			zap.String("target", target),
-			zap.Int("attempts", attempt+1))
-		return nil
+	body := &bodyReader{r: resp.Body}
+	if err := fsutil.WriteFrom(target, body, 0644); err != nil {
+		log.Error("Error writing file", zap.String("target", target), zap.Error(err))
+		// A body cut off midway is worth another try, a full disk is not
+		return body.err != nil, err
 	}
 
-	return fmt.Errorf("failed after %d attempts, last error: %v", maxRetries, lastErr)
+	log.Info("File downloaded successfully",
+		zap.String("url", url),
+		zap.String("target", target))
+	return false, nil
 }
 
-func (g *Generator) Download(log *zap.Logger, version string) error {
+// bodyReader remembers why reading a response body failed, to tell it
+// apart from failing to write the body out
+type bodyReader struct {
+	r   io.Reader
+	err error
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+	return n, err
+}
+
+func (g *Generator) Download(ctx context.Context, log *zap.Logger, version string) error {
 	if len(g.maps) == 0 {
 		log.Warn("No maps loaded")
 		return fmt.Errorf("no maps loaded")
 	}
 
+	g.downloads = nil
 	for _, m := range g.maps {
-		if m.Status != common.StatusComplete {
+		if !m.IsComplete() {
 			continue
 		}
 
-		if status, err := g.rmcli.GetStatus(log, m); err != nil {
+		if status, err := g.rmcli.GetStatus(ctx, log, statusRequest(m)); err != nil {
 			log.Error("Error downloading map", zap.String("seed", m.Seed), zap.Error(err))
 			return err
 		} else {
 			if !status.Data.CanDownload {
 				log.Warn("Cannot download map", zap.String("seed", m.Seed), zap.Int("size", m.Size))
-				fmt.Println()
+				g.downloads = append(g.downloads, DownloadResult{Map: m, URL: status.Data.URL, Skipped: true})
 				stagingFlag := ""
 				if m.Staging {
 					stagingFlag = " -b"
 				}
-				fmt.Printf("But you can open it in the browser: `rustmaps open -s '%s' -z %d -S '%s'%s`\n", m.Seed, m.Size, m.SavedConfig, stagingFlag)
-				fmt.Println()
+				g.reporter.Report(report.Event{
+					Type:    report.DownloadSkipped,
+					Map:     m,
+					Message: fmt.Sprintf("But you can open it in the browser: `rustmaps open -s '%s' -z %d -S '%s'%s`", m.Seed, m.Size, m.SavedConfig, stagingFlag),
+				})
 				continue
 			}
 			downloadsDir := filepath.Join(g.downloadsDir, version)
@@ -133,19 +175,10 @@ func (g *Generator) Download(log *zap.Logger, version string) error {
 				log.Error("Error creating downloads directory", zap.Error(err))
 				return err
 			}
-			savedConfig := m.SavedConfig
-			if savedConfig == "" {
-				savedConfig = "procedural"
-			}
-			prefix := fmt.Sprintf("%s_%d_%s_%t_%s", m.Seed, m.Size, savedConfig, m.Staging, m.MapID)
-			mapTarget := filepath.Join(downloadsDir, fmt.Sprintf("%s.map", prefix))
-			imageTarget := filepath.Join(downloadsDir, fmt.Sprintf("%s.png", prefix))
-			imageWithIconsTarget := filepath.Join(downloadsDir, fmt.Sprintf("%s_icons.png", prefix))
-			thumbnailTarget := filepath.Join(downloadsDir, fmt.Sprintf("%s_thumbnail.png", prefix))
-			downloadLinksTarget := filepath.Join(downloadsDir, fmt.Sprintf("%s_download_links.json", prefix))
-			mapSpecsTarget := filepath.Join(downloadsDir, fmt.Sprintf("%s_specs.json", prefix))
+			files := downloadFiles(downloadsDir, downloadPrefix(m))
 			// create a json file next to the rest that contains the download urls
 			log.Info("Downloading assets", zap.String("seed", m.Seed), zap.String("map_id", m.MapID))
+			g.reporter.Report(report.Event{Type: report.DownloadStarted, Map: m, Path: downloadsDir})
 			links := DownloadLinks{
 				MapURL:       status.Data.DownloadURL,
 				ImageURL:     status.Data.ImageURL,
@@ -157,8 +190,8 @@ func (g *Generator) Download(log *zap.Logger, version string) error {
 				log.Error("Error marshalling JSON", zap.Error(err))
 				return err
 			}
-			log.Info("Writing download links", zap.String("target", downloadLinksTarget))
-			if err := os.WriteFile(downloadLinksTarget, downloadLinksData, 0644); err != nil {
+			log.Info("Writing download links", zap.String("target", files.DownloadLinks))
+			if err := os.WriteFile(files.DownloadLinks, downloadLinksData, 0644); err != nil {
 				log.Error("Error writing JSON file", zap.Error(err))
 				return err
 			}
@@ -168,30 +201,59 @@ func (g *Generator) Download(log *zap.Logger, version string) error {
 				log.Error("Error marshalling JSON", zap.Error(err))
 				return err
 			}
-			log.Info("Writing map specs", zap.String("target", mapSpecsTarget))
-			if err := os.WriteFile(mapSpecsTarget, mapSpecsData, 0644); err != nil {
+			log.Info("Writing map specs", zap.String("target", files.Specs))
+			if err := os.WriteFile(files.Specs, mapSpecsData, 0644); err != nil {
 				log.Error("Error writing JSON file", zap.Error(err))
 				return err
 			}
 
-			if err := g.DownloadFile(log, status.Data.DownloadURL, mapTarget); err != nil {
+			if err := g.DownloadFile(ctx, log, status.Data.DownloadURL, files.Map); err != nil {
 				log.Error("Error downloading map", zap.String("seed", m.Seed), zap.Error(err))
 				return err
 			}
-			if err := g.DownloadFile(log, status.Data.ImageURL, imageTarget); err != nil {
+			if err := g.DownloadFile(ctx, log, status.Data.ImageURL, files.Image); err != nil {
 				log.Error("Error downloading image", zap.String("seed", m.Seed), zap.Error(err))
 				return err
 			}
-			if err := g.DownloadFile(log, status.Data.ImageIconURL, imageWithIconsTarget); err != nil {
+			if err := g.DownloadFile(ctx, log, status.Data.ImageIconURL, files.ImageIcons); err != nil {
 				log.Error("Error downloading image with icons", zap.String("seed", m.Seed), zap.Error(err))
 				return err
 			}
-			if err := g.DownloadFile(log, status.Data.ThumbnailURL, thumbnailTarget); err != nil {
+			if err := g.DownloadFile(ctx, log, status.Data.ThumbnailURL, files.Thumbnail); err != nil {
 				log.Error("Error downloading thumbnail", zap.String("seed", m.Seed), zap.Error(err))
 				return err
 			}
+			g.downloads = append(g.downloads, DownloadResult{
+				Map:   m,
+				URL:   status.Data.URL,
+				Links: links,
+				Files: files,
+			})
+			g.reporter.Report(report.Event{Type: report.DownloadFinished, Map: m, Path: downloadsDir})
+			g.transition(m, common.StatusDownloaded, EventDownloaded)
+			if err := g.mapStore().Save(m); err != nil {
+				log.Error("Error saving map file", zap.Error(err))
+			}
 		}
 	}
 
 	return nil
 }
+
+// downloadPrefix starts the name of every asset downloaded for m
+func downloadPrefix(m *types.Map) string {
+	return fmt.Sprintf("%s_%d_%s_%t_%s", m.Seed, m.Size, savedConfigName(m.SavedConfig), m.Staging, m.MapID)
+}
+
+// downloadFiles are the paths in dir the assets starting with prefix are
+// downloaded to
+func downloadFiles(dir, prefix string) DownloadFiles {
+	return DownloadFiles{
+		Map:           filepath.Join(dir, fmt.Sprintf("%s.map", prefix)),
+		Image:         filepath.Join(dir, fmt.Sprintf("%s.png", prefix)),
+		ImageIcons:    filepath.Join(dir, fmt.Sprintf("%s_icons.png", prefix)),
+		Thumbnail:     filepath.Join(dir, fmt.Sprintf("%s_thumbnail.png", prefix)),
+		DownloadLinks: filepath.Join(dir, fmt.Sprintf("%s_download_links.json", prefix)),
+		Specs:         filepath.Join(dir, fmt.Sprintf("%s_specs.json", prefix)),
+	}
+}
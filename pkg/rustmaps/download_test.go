@@ -1,9 +1,11 @@
 package rustmaps
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -65,8 +67,30 @@ func TestGenerator_OverrideDownloadsDir(t *testing.T) {
 }
 
 func TestGenerator_DownloadFile(t *testing.T) {
+	flaky, dropped := 0, 0
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/valid" {
+		if r.URL.Path == "/flaky" {
+			// fail the first request, then serve the file
+			flaky++
+			if flaky == 1 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("test"))
+		} else if r.URL.Path == "/dropped" {
+			// cut the connection off midway through the first body
+			dropped++
+			w.Header().Set("Content-Length", "8")
+			w.WriteHeader(http.StatusOK)
+			if dropped == 1 {
+				w.Write([]byte("test"))
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+				return
+			}
+			w.Write([]byte("testtest"))
+		} else if r.URL.Path == "/valid" {
 			// send file content for download
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusOK)
@@ -87,6 +111,8 @@ func TestGenerator_DownloadFile(t *testing.T) {
 		generator *Generator
 		args      args
 		wantErr   bool
+		// want is the downloaded content, when it matters
+		want string
 	}{
 		{
 			name: "Test DownloadFile",
@@ -108,6 +134,29 @@ func TestGenerator_DownloadFile(t *testing.T) {
 				target: filepath.Join(t.TempDir(), "test.json"),
 			},
 		},
+		{
+			name: "Test DownloadFile retried",
+			generator: NewMockedGenerator(t, &Generator{
+				rmcli: &MockedRustMapsCLI{ApiUrl: mockServer.URL, MockedServerUrl: mockServer.URL},
+			}),
+			args: args{
+				log:    zap.NewNop(),
+				url:    fmt.Sprintf("%s/%s", mockServer.URL, "flaky"),
+				target: filepath.Join(t.TempDir(), "test.json"),
+			},
+		},
+		{
+			name: "Test DownloadFile body cut off",
+			generator: NewMockedGenerator(t, &Generator{
+				rmcli: &MockedRustMapsCLI{ApiUrl: mockServer.URL, MockedServerUrl: mockServer.URL},
+			}),
+			args: args{
+				log:    zap.NewNop(),
+				url:    fmt.Sprintf("%s/%s", mockServer.URL, "dropped"),
+				target: filepath.Join(t.TempDir(), "test.map"),
+			},
+			want: "testtest",
+		},
 		{
 			name: "Test DownloadFile error",
 			generator: NewMockedGenerator(t, &Generator{
@@ -132,9 +181,19 @@ func TestGenerator_DownloadFile(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := tt.generator.DownloadFile(tt.args.log, tt.args.url, tt.args.target); (err != nil) != tt.wantErr {
+			if err := tt.generator.DownloadFile(context.Background(), tt.args.log, tt.args.url, tt.args.target); (err != nil) != tt.wantErr {
 				t.Errorf("Generator.DownloadFile() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			if tt.want != "" {
+				if got, _ := os.ReadFile(tt.args.target); string(got) != tt.want {
+					t.Errorf("Generator.DownloadFile() wrote %q, want %q", got, tt.want)
+				}
+			}
+			if tt.wantErr {
+				if _, err := os.Stat(tt.args.target); !os.IsNotExist(err) {
+					t.Errorf("Generator.DownloadFile() left %s behind", tt.args.target)
+				}
+			}
 		})
 	}
 }
@@ -157,6 +216,9 @@ func TestGenerator_Download(t *testing.T) {
 		generator *Generator
 		args      args
 		wantErr   bool
+		// wantDownloads and wantSkipped count the DownloadResults recorded
+		wantDownloads int
+		wantSkipped   int
 	}{
 		{
 			name: "Test Download",
@@ -178,8 +240,9 @@ func TestGenerator_Download(t *testing.T) {
 				},
 				rmcli: &MockedRustMapsCLI{ApiUrl: mockServer.URL, MockedServerUrl: mockServer.URL},
 			}),
-			args:    args{log: zap.NewNop(), version: "test"},
-			wantErr: false,
+			args:          args{log: zap.NewNop(), version: "test"},
+			wantErr:       false,
+			wantDownloads: 1,
 		},
 		{
 			name: "Test Download no maps",
@@ -223,8 +286,9 @@ func TestGenerator_Download(t *testing.T) {
 				},
 				rmcli: &MockedRustMapsCLI{ApiUrl: mockServer.URL, MockedServerUrl: mockServer.URL},
 			}),
-			args:    args{log: zap.NewNop(), version: "test"},
-			wantErr: false,
+			args:        args{log: zap.NewNop(), version: "test"},
+			wantErr:     false,
+			wantSkipped: 1,
 		},
 		{
 			name: "Test Download failed download",
@@ -247,9 +311,26 @@ func TestGenerator_Download(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := tt.generator.Download(tt.args.log, tt.args.version); (err != nil) != tt.wantErr {
+			if err := tt.generator.Download(context.Background(), tt.args.log, tt.args.version); (err != nil) != tt.wantErr {
 				t.Errorf("Generator.Download() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			downloads, skipped := 0, 0
+			for _, d := range tt.generator.Downloads() {
+				if d.Skipped {
+					skipped++
+					continue
+				}
+				downloads++
+				if d.Map.Status != common.StatusDownloaded {
+					t.Errorf("Generator.Download() left %s, want it %s", d.Map.String(), common.StatusDownloaded)
+				}
+				if _, err := os.Stat(d.Files.Map); err != nil {
+					t.Errorf("Generator.Download() recorded %s which was not written: %v", d.Files.Map, err)
+				}
+			}
+			if downloads != tt.wantDownloads || skipped != tt.wantSkipped {
+				t.Errorf("Generator.Downloads() = %d downloaded, %d skipped, want %d, %d", downloads, skipped, tt.wantDownloads, tt.wantSkipped)
+			}
 		})
 	}
 }
@@ -1,64 +1,292 @@
 package rustmaps
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
 	"time"
 
+	"github.com/maintc/rustmaps-cli/pkg/api"
 	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/types"
 	"go.uber.org/zap"
 )
 
-func (g *Generator) Generate(log *zap.Logger) bool {
+// Run drives generation until every map is finished or the run cannot
+// continue, waiting between passes for as long as the scheduler says
+// nothing is due. It returns ctx's error when cancelled, otherwise the
+// error Err reports.
+func (g *Generator) Run(ctx context.Context, log *zap.Logger) error {
+	if g.started.IsZero() {
+		g.started = time.Now()
+	}
+	for {
+		wait, more := g.Step(ctx, log)
+		if !more {
+			break
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return g.err
+}
+
+// Step runs one pass of syncing and submitting the maps that are due and
+// returns how long until the next pass is due. It returns false once there
+// is nothing left to do or the run cannot continue, Err reports why in the
+// latter case.
+func (g *Generator) Step(ctx context.Context, log *zap.Logger) (time.Duration, bool) {
+	g.err = nil
+
+	if ctx.Err() != nil {
+		log.Info("Generation cancelled", zap.Error(ctx.Err()))
+		return 0, false
+	}
 
 	if err := g.ValidateAuthentication(log); err != nil {
 		log.Error("Error validating authentication", zap.Error(err))
-		return false
+		g.err = err
+		return 0, false
 	}
 
 	if !(g.Pending() || g.Generating()) {
 		log.Info("All maps are complete")
-		return false
+		return 0, false
 	}
 
+	now := time.Now()
 	for _, m := range g.maps {
-		if m.Status == common.StatusComplete && m.ShouldSync() {
-			if err := g.SyncStatus(log, m); err != nil {
+		if m.IsComplete() && m.ShouldSync() {
+			if err := g.SyncStatus(ctx, log, m); err != nil {
 				log.Error("Error syncing status", zap.String("seed", m.Seed))
-			}
-
-			if m.Status == common.StatusNotFound {
-				m.Status = common.StatusPending
+				if g.refused(log, err) {
+					return 0, false
+				}
 			}
 		}
 
-		if m.Status == common.StatusGenerating {
-			if err := g.SyncStatus(log, m); err != nil {
+		if m.Status == common.StatusGenerating && g.sched.pollDue(m, now) {
+			g.sched.schedulePoll(m, now.Add(g.backoffTime))
+			if err := g.SyncStatus(ctx, log, m); err != nil {
 				log.Error("Error syncing status", zap.String("seed", m.Seed))
+				if g.refused(log, err) {
+					return 0, false
+				}
 				continue
 			}
+			if m.Status == common.StatusGenerating {
+				g.checkStalled(log, m)
+			}
 		}
 	}
 
-	if !(g.Pending() && g.CanGenerate(log)) {
-		time.Sleep(g.backoffTime)
-		return true
+	if g.Pending() && g.sched.submitDue(now) {
+		if !g.submitPending(ctx, log, now) {
+			return 0, false
+		}
 	}
 
-	for _, m := range g.maps {
-		if m.Status == common.StatusPending {
-			if m.SavedConfig == "" {
-				g.rmcli.GenerateProcedural(log, m)
-			} else {
-				g.rmcli.GenerateCustom(log, m)
-			}
+	return g.sched.next(time.Now(), g.maps, g.Pending()), true
+}
 
-			if err := m.SaveJSON(g.importsDir); err != nil {
-				log.Error("Error saving map file", zap.Error(err))
-			}
+// submitPending submits as many pending maps as there are free slots and
+// schedules the next attempt. It returns false when the run cannot
+// continue, with g.err set.
+func (g *Generator) submitPending(ctx context.Context, log *zap.Logger, now time.Time) bool {
+	slots, err := g.availableSlots(ctx, log)
+	if g.refused(log, err) {
+		return false
+	}
+	spent := errors.Is(err, ErrQuotaExhausted) || errors.Is(err, ErrBudgetSpent)
+	if err != nil && !spent {
+		// The client already retried, waiting for slots that may never be
+		// reported would hang the run
+		g.limitsFailures++
+		if g.limitsFailures >= maxLimitsFailures {
+			log.Error("Limits unavailable, stopping", zap.Int("attempts", g.limitsFailures), zap.Error(err))
+			g.err = fmt.Errorf("%w: %w", ErrLimitsUnavailable, err)
+			return false
+		}
+	} else {
+		g.limitsFailures = 0
+	}
+	if spent && !g.Generating() {
+		// Maps already generating are waited for, but nothing else can be
+		// submitted until the quota resets or the next run
+		log.Error("Quota spent, stopping", zap.Error(err))
+		g.err = err
+		return false
+	}
+	if slots == 0 {
+		if g.waitingSince.IsZero() {
+			g.waitingSince = now
+		}
+		waited := now.Sub(g.waitingSince)
+		if g.maxWaitForQuota > 0 && waited >= g.maxWaitForQuota {
+			log.Error("No free slot, stopping", zap.Duration("waited", waited))
+			g.err = ErrQuotaWait
+			return false
+		}
+		backoff := g.backoffTime
+		if g.maxWaitForQuota > 0 {
+			backoff = min(backoff, g.maxWaitForQuota-waited)
+		}
+		g.sched.scheduleSubmit(now.Add(backoff))
+		return true
+	}
+	g.waitingSince = time.Time{}
 
+	var batch []*types.Map
+	for _, m := range g.maps {
+		if len(batch) == slots {
 			break
 		}
+		if awaitingSubmission(m) {
+			batch = append(batch, m)
+		}
+	}
+
+	if ctx.Err() != nil {
+		return false
+	}
+	for i, err := range g.submitAll(ctx, log, batch) {
+		switch {
+		case errors.Is(err, api.ErrUnauthorized):
+			// Every other request would be rejected too
+			g.refused(log, err)
+			return false
+		case errors.Is(err, api.ErrConflict), errors.Is(err, api.ErrRateLimited):
+			// The map is already generating or will be resubmitted
+		case err != nil:
+			log.Warn("Map submission failed", zap.Error(err))
+		}
+		// RustMaps just reported on the map, no need to ask again right away
+		g.sched.schedulePoll(batch[i], now.Add(g.backoffTime))
 	}
 
-	time.Sleep(2 * time.Second)
+	g.sched.scheduleSubmit(now.Add(g.pollInterval))
+	return true
+}
+
+var (
+	// ErrQuotaExhausted stops the run when the monthly quota is used up,
+	// down to the quota reserve, while maps are still waiting to be
+	// submitted and none are generating
+	ErrQuotaExhausted = errors.New("monthly quota exhausted")
+	// ErrBudgetSpent stops the run once it generated the maximum number of
+	// maps set for it while maps are still waiting to be submitted
+	ErrBudgetSpent = errors.New("maximum maps for the run generated")
+	// ErrQuotaWait stops the run when no slot freed up within the maximum
+	// wait for quota
+	ErrQuotaWait = errors.New("gave up waiting for a free generation slot")
+	// ErrLimitsUnavailable stops the run when the limits could not be
+	// fetched maxLimitsFailures passes in a row
+	ErrLimitsUnavailable = errors.New("could not get the generation limits")
+)
+
+// maxLimitsFailures is how many passes in a row the limits may fail to be
+// fetched before the run stops
+const maxLimitsFailures = 5
+
+// refused reports whether err means RustMaps turned the account away, in
+// which case every other request would be too and the run stops with g.err
+// set
+func (g *Generator) refused(log *zap.Logger, err error) bool {
+	if !errors.Is(err, api.ErrUnauthorized) && !errors.Is(err, api.ErrForbidden) {
+		return false
+	}
+	log.Error("RustMaps refused the request, stopping", zap.Error(err))
+	g.err = err
 	return true
 }
+
+// Err returns the error that stopped the last Step, if any
+func (g *Generator) Err() error {
+	return g.err
+}
+
+// submitAll submits the given maps concurrently, one worker per map, and
+// returns each submission's error in the same order. Every request still
+// goes through the client's shared rate limiter.
+func (g *Generator) submitAll(ctx context.Context, log *zap.Logger, maps []*types.Map) []error {
+	errs := make([]error, len(maps))
+	var wg sync.WaitGroup
+	for i, m := range maps {
+		wg.Add(1)
+		go func(i int, m *types.Map) {
+			defer wg.Done()
+			errs[i] = g.submit(ctx, log, m)
+		}(i, m)
+	}
+	wg.Wait()
+	return errs
+}
+
+// submit sends a single map to RustMaps and persists the result
+func (g *Generator) submit(ctx context.Context, log *zap.Logger, m *types.Map) error {
+	// Once a submission starts it must not be abandoned halfway, otherwise
+	// the server may accept the map without us ever recording its ID
+	submitCtx := context.WithoutCancel(ctx)
+	g.transition(m, common.StatusSubmitted, EventSubmit)
+	var (
+		resp *api.RustMapsGenerateResponse
+		err  error
+	)
+	if m.SavedConfig == "" {
+		resp, err = g.rmcli.GenerateProcedural(submitCtx, log, generateRequest(m))
+	} else {
+		resp, err = g.rmcli.GenerateCustom(submitCtx, log, generateRequest(m))
+	}
+	g.applySubmission(m, resp, err)
+
+	if saveErr := g.mapStore().Save(m); saveErr != nil {
+		log.Error("Error saving map file", zap.Error(saveErr))
+	}
+	return err
+}
+
+// applySubmission moves a submitted m to the state matching the outcome
+// of a generate call. Errors without a matching state, such as network
+// failures, send m back to Pending so it is submitted again.
+func (g *Generator) applySubmission(m *types.Map, resp *api.RustMapsGenerateResponse, err error) {
+	switch {
+	case err == nil:
+		m.MapID = resp.Data.MapID
+		recordProgress(m, resp.Data)
+		if resp.Meta.StatusCode == http.StatusOK {
+			// The map already existed
+			g.transition(m, common.StatusComplete, EventExists)
+		} else {
+			m.MarkSubmitted(time.Now())
+			g.generated.Add(1)
+			g.transition(m, common.StatusGenerating, EventAccepted)
+		}
+	case errors.Is(err, api.ErrConflict):
+		g.transition(m, common.StatusGenerating, EventAccepted)
+	case errors.Is(err, api.ErrRateLimited):
+		g.transition(m, common.StatusRateLimited, EventRejected)
+	case errors.Is(err, api.ErrStagingNotEnabled):
+		g.refuse(m, common.StatusStagingNotEnabled, err)
+	case errors.Is(err, api.ErrBadRequest):
+		g.refuse(m, common.StatusBadRequest, err)
+	case errors.Is(err, api.ErrUnauthorized):
+		g.refuse(m, common.StatusUnauthorized, err)
+	case errors.Is(err, api.ErrForbidden):
+		g.refuse(m, common.StatusForbidden, err)
+	default:
+		g.transition(m, common.StatusPending, EventRetry)
+	}
+}
+
+// refuse moves a submitted m to status, keeping err as the reason RustMaps
+// refused it
+func (g *Generator) refuse(m *types.Map, status string, err error) {
+	m.FailureReason = err.Error()
+	g.transition(m, status, EventRejected)
+}
@@ -1,17 +1,22 @@
 package rustmaps
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/maintc/rustmaps-cli/pkg/api"
 	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/report"
 	"github.com/maintc/rustmaps-cli/pkg/types"
 
 	"go.uber.org/zap"
 )
 
-func TestGenerator_Generate(t *testing.T) {
+func TestGenerator_Step(t *testing.T) {
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// send generic json 200
 		w.WriteHeader(http.StatusOK)
@@ -19,13 +24,20 @@ func TestGenerator_Generate(t *testing.T) {
 		w.Write([]byte(`{"status": "success"}`))
 	}))
 	type args struct {
+		ctx context.Context
 		log *zap.Logger
 	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
 	tests := []struct {
 		name      string
 		generator *Generator
 		args      args
 		want      bool
+		// wantSubmitted is the number of maps handed to the API client
+		wantSubmitted int32
+		// wantErr is the error Err reports afterwards
+		wantErr error
 	}{
 		{
 			name: "Test Generate",
@@ -58,7 +70,11 @@ func TestGenerator_Generate(t *testing.T) {
 					},
 				},
 				target: "",
-				rmcli:  &MockedRustMapsCLI{},
+				rmcli: &MockedRustMapsCLI{
+					ConcurrentCurrent: 1,
+					ConcurrentAllowed: 1,
+					MonthlyAllowed:    800,
+				},
 			}),
 			args: args{
 				log: zap.NewNop(),
@@ -136,8 +152,204 @@ func TestGenerator_Generate(t *testing.T) {
 			args: args{
 				log: zap.NewNop(),
 			},
+			want:          true,
+			wantSubmitted: 1,
+		},
+		{
+			name: "Test Generate submits up to the concurrent limit",
+			generator: NewMockedGenerator(t, &Generator{
+				config: types.Config{
+					APIKey: "test",
+					Tier:   "Premium",
+				},
+				maps: []*types.Map{
+					{Status: common.StatusPending, Seed: "1", Size: 4000},
+					{Status: common.StatusPending, Seed: "2", Size: 4000},
+					{Status: common.StatusPending, Seed: "3", Size: 4000},
+				},
+				target: "",
+				rmcli: &MockedRustMapsCLI{
+					MockedServerUrl:   mockServer.URL,
+					ConcurrentCurrent: 1,
+					ConcurrentAllowed: 3,
+					MonthlyAllowed:    800,
+				},
+			}),
+			args: args{
+				log: zap.NewNop(),
+			},
+			want:          true,
+			wantSubmitted: 2,
+		},
+		{
+			name: "Test Generate submits up to parallel",
+			generator: NewMockedGenerator(t, &Generator{
+				config: types.Config{
+					APIKey: "test",
+					Tier:   "Premium",
+				},
+				maps: []*types.Map{
+					{Status: common.StatusPending, Seed: "1", Size: 4000},
+					{Status: common.StatusPending, Seed: "2", Size: 4000},
+					{Status: common.StatusPending, Seed: "3", Size: 4000},
+				},
+				target:   "",
+				parallel: 1,
+				rmcli: &MockedRustMapsCLI{
+					MockedServerUrl:   mockServer.URL,
+					ConcurrentAllowed: 8,
+					MonthlyAllowed:    800,
+				},
+			}),
+			args: args{
+				log: zap.NewNop(),
+			},
+			want:          true,
+			wantSubmitted: 1,
+		},
+		{
+			name: "Test Generate cancelled",
+			generator: NewMockedGenerator(t, &Generator{
+				config: types.Config{
+					APIKey: "test",
+					Tier:   "Premium",
+				},
+				maps: []*types.Map{
+					{
+						Status:      common.StatusPending,
+						SavedConfig: "",
+						Seed:        "test",
+						Size:        4000,
+						Staging:     false,
+					},
+				},
+				target: "",
+				rmcli: &MockedRustMapsCLI{
+					MockedServerUrl:   mockServer.URL,
+					ConcurrentAllowed: 8,
+					MonthlyAllowed:    800,
+				},
+			}),
+			args: args{
+				ctx: cancelled,
+				log: zap.NewNop(),
+			},
+			want: false,
+		},
+		{
+			name: "Test Generate stops on unauthorized",
+			generator: NewMockedGenerator(t, &Generator{
+				config: types.Config{
+					APIKey: "test",
+					Tier:   "Premium",
+				},
+				maps: []*types.Map{
+					{Status: common.StatusPending, Seed: "1", Size: 4000},
+				},
+				rmcli: &MockedRustMapsCLI{
+					ConcurrentAllowed: 8,
+					MonthlyAllowed:    800,
+					GenerateError:     &api.Error{StatusCode: http.StatusUnauthorized},
+				},
+			}),
+			args: args{
+				log: zap.NewNop(),
+			},
+			want:          false,
+			wantSubmitted: 1,
+			wantErr:       api.ErrUnauthorized,
+		},
+		{
+			name: "Test Generate continues on conflict",
+			generator: NewMockedGenerator(t, &Generator{
+				config: types.Config{
+					APIKey: "test",
+					Tier:   "Premium",
+				},
+				maps: []*types.Map{
+					{Status: common.StatusPending, Seed: "1", Size: 4000},
+				},
+				rmcli: &MockedRustMapsCLI{
+					ConcurrentAllowed: 8,
+					MonthlyAllowed:    800,
+					GenerateError:     &api.Error{StatusCode: http.StatusConflict},
+				},
+			}),
+			args: args{
+				log: zap.NewNop(),
+			},
+			want:          true,
+			wantSubmitted: 1,
+		},
+		{
+			name: "Test Generate stops when the monthly quota is exhausted",
+			generator: NewMockedGenerator(t, &Generator{
+				config: types.Config{
+					APIKey: "test",
+					Tier:   "Premium",
+				},
+				maps: []*types.Map{
+					{Status: common.StatusPending, Seed: "1", Size: 4000},
+				},
+				rmcli: &MockedRustMapsCLI{
+					ConcurrentAllowed: 8,
+					MonthlyCurrent:    800,
+					MonthlyAllowed:    800,
+				},
+			}),
+			args: args{
+				log: zap.NewNop(),
+			},
+			want:    false,
+			wantErr: ErrQuotaExhausted,
+		},
+		{
+			name: "Test Generate waits for generating maps when the monthly quota is exhausted",
+			generator: NewMockedGenerator(t, &Generator{
+				config: types.Config{
+					APIKey: "test",
+					Tier:   "Premium",
+				},
+				maps: []*types.Map{
+					{Status: common.StatusPending, Seed: "1", Size: 4000},
+					{Status: common.StatusGenerating, Seed: "3", Size: 4000},
+				},
+				rmcli: &MockedRustMapsCLI{
+					Status:            common.StatusGenerating,
+					ConcurrentAllowed: 8,
+					MonthlyCurrent:    800,
+					MonthlyAllowed:    800,
+				},
+			}),
+			args: args{
+				log: zap.NewNop(),
+			},
 			want: true,
 		},
+		{
+			name: "Test Generate gives up waiting for a slot",
+			generator: NewMockedGenerator(t, &Generator{
+				config: types.Config{
+					APIKey: "test",
+					Tier:   "Premium",
+				},
+				maps: []*types.Map{
+					{Status: common.StatusPending, Seed: "1", Size: 4000},
+				},
+				rmcli: &MockedRustMapsCLI{
+					ConcurrentCurrent: 8,
+					ConcurrentAllowed: 8,
+					MonthlyAllowed:    800,
+				},
+				maxWaitForQuota: time.Minute,
+				waitingSince:    time.Now().Add(-time.Hour),
+			}),
+			args: args{
+				log: zap.NewNop(),
+			},
+			want:    false,
+			wantErr: ErrQuotaWait,
+		},
 		// {
 		// 	name: "Test Generate genrate custom",
 		// 	generator: NewMockedGenerator(t, &Generator{
@@ -169,8 +381,300 @@ func TestGenerator_Generate(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := tt.generator.Generate(tt.args.log); got != tt.want {
-				t.Errorf("Generator.Generate() = %v, want %v", got, tt.want)
+			ctx := tt.args.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			if _, got := tt.generator.Step(ctx, tt.args.log); got != tt.want {
+				t.Errorf("Generator.Step() = %v, want %v", got, tt.want)
+			}
+			if err := tt.generator.Err(); tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Generator.Err() = %v, want %v", err, tt.wantErr)
+			}
+			if mock, ok := tt.generator.rmcli.(*MockedRustMapsCLI); ok {
+				if got := mock.submitted.Load(); got != tt.wantSubmitted {
+					t.Errorf("Generator.Step() submitted %v maps, want %v", got, tt.wantSubmitted)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerator_Run(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		generator *Generator
+		wantErr   error
+	}{
+		{
+			name: "Runs until complete",
+			ctx:  context.Background(),
+			generator: NewMockedGenerator(t, &Generator{
+				config: types.Config{APIKey: "test", Tier: "Premium"},
+				maps: []*types.Map{
+					{Status: common.StatusPending, Seed: "1", Size: 4000, Filename: "1_4000.json"},
+					{Status: common.StatusGenerating, Seed: "3", Size: 4000, Filename: "3_4000.json"},
+				},
+				rmcli: &MockedRustMapsCLI{
+					ConcurrentAllowed: 8,
+					MonthlyAllowed:    800,
+				},
+			}),
+		},
+		{
+			name: "Cancelled",
+			ctx:  cancelled,
+			generator: NewMockedGenerator(t, &Generator{
+				config: types.Config{APIKey: "test", Tier: "Premium"},
+				maps: []*types.Map{
+					{Status: common.StatusPending, Seed: "1", Size: 4000},
+				},
+				rmcli: &MockedRustMapsCLI{},
+			}),
+			wantErr: context.Canceled,
+		},
+		{
+			name: "Stops when the monthly quota is exhausted",
+			ctx:  context.Background(),
+			generator: NewMockedGenerator(t, &Generator{
+				config: types.Config{APIKey: "test", Tier: "Premium"},
+				maps: []*types.Map{
+					{Status: common.StatusPending, Seed: "1", Size: 4000},
+				},
+				rmcli: &MockedRustMapsCLI{
+					ConcurrentAllowed: 8,
+					MonthlyCurrent:    800,
+					MonthlyAllowed:    800,
+				},
+			}),
+			wantErr: ErrQuotaExhausted,
+		},
+		{
+			name: "Stops at the quota reserve",
+			ctx:  context.Background(),
+			generator: NewMockedGenerator(t, &Generator{
+				config: types.Config{APIKey: "test", Tier: "Premium", QuotaReserve: 20},
+				maps: []*types.Map{
+					{Status: common.StatusPending, Seed: "1", Size: 4000},
+				},
+				rmcli: &MockedRustMapsCLI{
+					ConcurrentAllowed: 8,
+					MonthlyCurrent:    780,
+					MonthlyAllowed:    800,
+				},
+			}),
+			wantErr: ErrQuotaExhausted,
+		},
+		{
+			name: "Stops once max maps were generated",
+			ctx:  context.Background(),
+			generator: func() *Generator {
+				g := NewMockedGenerator(t, &Generator{
+					config:  types.Config{APIKey: "test", Tier: "Premium"},
+					maxMaps: 1,
+					maps: []*types.Map{
+						{Status: common.StatusComplete, Seed: "1", Size: 4000},
+						{Status: common.StatusPending, Seed: "2", Size: 4000},
+					},
+					rmcli: &MockedRustMapsCLI{
+						ConcurrentAllowed: 8,
+						MonthlyAllowed:    800,
+					},
+				})
+				g.generated.Store(1)
+				return g
+			}(),
+			wantErr: ErrBudgetSpent,
+		},
+		{
+			name: "Stops when the limits are refused",
+			ctx:  context.Background(),
+			generator: NewMockedGenerator(t, &Generator{
+				config: types.Config{APIKey: "test", Tier: "Premium"},
+				maps: []*types.Map{
+					{Status: common.StatusPending, Seed: "1", Size: 4000},
+				},
+				rmcli: &MockedRustMapsCLI{
+					LimitsErr: &api.Error{StatusCode: http.StatusUnauthorized},
+				},
+			}),
+			wantErr: api.ErrUnauthorized,
+		},
+		{
+			name: "Stops when the limits keep failing",
+			ctx:  context.Background(),
+			generator: NewMockedGenerator(t, &Generator{
+				config: types.Config{APIKey: "test", Tier: "Premium"},
+				maps: []*types.Map{
+					{Status: common.StatusPending, Seed: "1", Size: 4000},
+				},
+				rmcli: &MockedRustMapsCLI{LimitsError: true},
+			}),
+			wantErr: ErrLimitsUnavailable,
+		},
+		{
+			name: "Stops when a status poll is refused",
+			ctx:  context.Background(),
+			generator: NewMockedGenerator(t, &Generator{
+				config: types.Config{APIKey: "test", Tier: "Premium"},
+				maps: []*types.Map{
+					{Status: common.StatusGenerating, Seed: "3", Size: 4000, Filename: "3_4000.json"},
+				},
+				rmcli: &MockedRustMapsCLI{Status: common.StatusForbidden},
+			}),
+			wantErr: api.ErrForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.generator.Run(tt.ctx, zap.NewNop()); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Generator.Run() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			for _, m := range tt.generator.maps {
+				if m.Status != common.StatusComplete {
+					t.Errorf("Generator.Run() left %s, want it complete", m.String())
+				}
+			}
+		})
+	}
+}
+
+func TestGenerator_Step_schedule(t *testing.T) {
+	g := NewMockedGenerator(t, &Generator{
+		config: types.Config{APIKey: "test", Tier: "Premium"},
+		maps: []*types.Map{
+			{Status: common.StatusGenerating, Seed: "3", Size: 4000, Filename: "3_4000.json"},
+		},
+		rmcli: &MockedRustMapsCLI{Status: common.StatusGenerating},
+	})
+	g.backoffTime = time.Minute
+
+	wait, more := g.Step(context.Background(), zap.NewNop())
+	if !more || wait <= 0 || wait > time.Minute {
+		t.Errorf("Generator.Step() = %v, %v, want to wait up to a minute for the next poll", wait, more)
+	}
+	m := g.maps[0]
+	if g.sched.pollDue(m, time.Now()) {
+		t.Errorf("Generator.Step() polled %s but did not schedule the next poll", m.String())
+	}
+}
+
+func Test_applySubmission(t *testing.T) {
+	type args struct {
+		resp *api.RustMapsGenerateResponse
+		err  error
+	}
+	tests := []struct {
+		name      string
+		args      args
+		want      string
+		wantMapID string
+	}{
+		{
+			name: "Queued",
+			args: args{
+				resp: &api.RustMapsGenerateResponse{
+					Meta: api.RustMapsGenerateResponseMeta{StatusCode: http.StatusCreated},
+					Data: api.RustMapsGenerateResponseData{MapID: "abc"},
+				},
+			},
+			want:      common.StatusGenerating,
+			wantMapID: "abc",
+		},
+		{
+			name: "Already generated",
+			args: args{
+				resp: &api.RustMapsGenerateResponse{
+					Meta: api.RustMapsGenerateResponseMeta{StatusCode: http.StatusOK},
+					Data: api.RustMapsGenerateResponseData{MapID: "abc"},
+				},
+			},
+			want:      common.StatusComplete,
+			wantMapID: "abc",
+		},
+		{
+			name: "Conflict",
+			args: args{err: &api.Error{StatusCode: http.StatusConflict}},
+			want: common.StatusGenerating,
+		},
+		{
+			name: "Rate limited",
+			args: args{err: &api.Error{StatusCode: http.StatusTooManyRequests}},
+			want: common.StatusRateLimited,
+		},
+		{
+			name: "Staging not enabled",
+			args: args{err: &api.Error{StatusCode: http.StatusBadRequest, Errors: []string{"Staging is not enabled"}}},
+			want: common.StatusStagingNotEnabled,
+		},
+		{
+			name: "Bad request",
+			args: args{err: &api.Error{StatusCode: http.StatusBadRequest}},
+			want: common.StatusBadRequest,
+		},
+		{
+			name: "Unauthorized",
+			args: args{err: &api.Error{StatusCode: http.StatusUnauthorized}},
+			want: common.StatusUnauthorized,
+		},
+		{
+			name: "Forbidden",
+			args: args{err: &api.Error{StatusCode: http.StatusForbidden}},
+			want: common.StatusForbidden,
+		},
+		{
+			name: "Network error",
+			args: args{err: errors.New("connection reset")},
+			want: common.StatusPending,
+		},
+		{
+			name: "Server error",
+			args: args{err: &api.Error{StatusCode: http.StatusBadGateway}},
+			want: common.StatusPending,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []report.Event
+			g := NewMockedGenerator(t, &Generator{
+				reporter: report.Func(func(e report.Event) { events = append(events, e) }),
+			})
+			m := types.NewMap("1", 4000, "", false)
+			m.Status = common.StatusSubmitted
+			g.applySubmission(m, tt.args.resp, tt.args.err)
+			if m.Status != tt.want {
+				t.Errorf("applySubmission() status = %v, want %v", m.Status, tt.want)
+			}
+			if m.MapID != tt.wantMapID {
+				t.Errorf("applySubmission() map ID = %v, want %v", m.MapID, tt.wantMapID)
+			}
+			if len(events) != 1 {
+				t.Fatalf("applySubmission() reported %d events, want 1", len(events))
+			}
+			if events[0].Type != report.MapStatus || events[0].Map != m {
+				t.Errorf("applySubmission() reported %+v, want a status event for the map", events[0])
+			}
+			if len(m.History) != 1 || m.History[0].From != common.StatusSubmitted || m.History[0].To != tt.want {
+				t.Errorf("applySubmission() history = %+v, want a move from Submitted to %s", m.History, tt.want)
+			}
+			if (events[0].Progress != nil) != (tt.want == common.StatusGenerating) {
+				t.Errorf("applySubmission() progress = %+v, want it only for generating maps", events[0].Progress)
+			}
+			created := tt.args.err == nil && tt.args.resp.Meta.StatusCode == http.StatusCreated
+			if (m.SubmittedAt != "") != created {
+				t.Errorf("applySubmission() submitted at = %q, want it set only for new maps", m.SubmittedAt)
+			}
+			if got := g.generated.Load() == 1; got != created {
+				t.Errorf("applySubmission() counted %d generated maps, want one only for new maps", g.generated.Load())
+			}
+			if (m.FailureReason != "") != m.IsFailed() {
+				t.Errorf("applySubmission() failure reason = %q, want it only for refused maps", m.FailureReason)
 			}
 		})
 	}
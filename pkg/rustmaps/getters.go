@@ -1,6 +1,10 @@
 package rustmaps
 
-import "github.com/maintc/rustmaps-cli/pkg/types"
+import (
+	"path/filepath"
+
+	"github.com/maintc/rustmaps-cli/pkg/types"
+)
 
 func (g *Generator) GetDownloadsDir() string {
 	return g.downloadsDir
@@ -14,6 +18,19 @@ func (g *Generator) GetLogPath() string {
 	return g.logPath
 }
 
+// GetDatabasePath returns where the SQLite store keeps its database
+func (g *Generator) GetDatabasePath() string {
+	return filepath.Join(g.baseDir, "maps.db")
+}
+
+// GetStore returns which store keeps map state, StoreJSON or StoreSQLite
+func (g *Generator) GetStore() string {
+	if g.config.Store == "" {
+		return StoreJSON
+	}
+	return g.config.Store
+}
+
 func (g *Generator) GetConfigPath() string {
 	return g.configPath
 }
@@ -21,3 +38,12 @@ func (g *Generator) GetConfigPath() string {
 func (g *Generator) GetMaps() []*types.Map {
 	return g.maps
 }
+
+func (g *Generator) GetTier() string {
+	return g.config.Tier
+}
+
+// Downloads returns what the last Download call did with each complete map
+func (g *Generator) Downloads() []DownloadResult {
+	return g.downloads
+}
@@ -1,21 +1,103 @@
 package rustmaps
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"strings"
 	"time"
 
+	"github.com/maintc/rustmaps-cli/pkg/api"
 	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/report"
+	"github.com/maintc/rustmaps-cli/pkg/types"
 )
 
 func (g *Generator) IsApiKeySet() bool {
 	return g.config.APIKey != ""
 }
 
+// awaitingSubmission reports whether m still has to be submitted, which
+// includes maps whose last submission was turned away by a 429
+func awaitingSubmission(m *types.Map) bool {
+	return m.Status == common.StatusPending || m.Status == common.StatusRateLimited
+}
+
+// newStateMachine creates the state machine for g, reporting every
+// change of state
+func (g *Generator) newStateMachine() *StateMachine {
+	sm := NewStateMachine()
+	sm.OnTransition(func(m *types.Map, _ types.Transition) {
+		g.reporter.Report(report.Event{Type: report.MapStatus, Map: m, Progress: progress(m)})
+	})
+	return sm
+}
+
+// OnTransition calls hook after every change of a map's state. Hooks must
+// be added before generating and be safe for concurrent use.
+func (g *Generator) OnTransition(hook TransitionHook) {
+	g.machine.OnTransition(hook)
+}
+
+// transition moves m to status because of event. Illegal transitions are
+// reported as errors and leave m as it was.
+func (g *Generator) transition(m *types.Map, status, event string) {
+	from := m.Status
+	if err := g.machine.Transition(m, status, event); err != nil {
+		g.reporter.Report(report.Event{Type: report.Error, Map: m, Message: "changing status", Err: err})
+		return
+	}
+	if from == status {
+		// The hooks only hear about changes, but the progress may be new
+		g.reporter.Report(report.Event{Type: report.MapStatus, Map: m, Progress: progress(m)})
+	}
+}
+
+// recordProgress stores what RustMaps said about the generation of m
+func recordProgress(m *types.Map, d api.RustMapsGenerateResponseData) {
+	m.SetProgress(d.QueuePosition, d.State, d.CurrentStep, d.LastGeneratorPingUtc)
+}
+
+// progress returns the generation progress of m for reporting, nil unless
+// it is generating
+func progress(m *types.Map) *report.Progress {
+	if m.Status != common.StatusGenerating {
+		return nil
+	}
+	p := &report.Progress{
+		QueuePosition: m.QueuePosition,
+		State:         m.State,
+		CurrentStep:   m.CurrentStep,
+	}
+	if ping, err := time.Parse(time.RFC3339, m.LastGeneratorPing); err == nil {
+		p.LastPing = ping
+	}
+	return p
+}
+
+// generateRequest describes m to the API
+func generateRequest(m *types.Map) api.GenerateRequest {
+	return api.GenerateRequest{
+		Seed:       m.Seed,
+		Size:       m.Size,
+		Staging:    m.Staging,
+		ConfigName: m.SavedConfig,
+	}
+}
+
+// statusRequest identifies m to the API
+func statusRequest(m *types.Map) api.StatusRequest {
+	return api.StatusRequest{
+		MapID:   m.MapID,
+		Seed:    m.Seed,
+		Size:    m.Size,
+		Staging: m.Staging,
+	}
+}
+
 func (g *Generator) Pending() bool {
 	for _, m := range g.maps {
-		if m.Status == common.StatusPending {
+		if awaitingSubmission(m) {
 			return true
 		}
 	}
@@ -31,6 +113,18 @@ func (g *Generator) Generating() bool {
 	return false
 }
 
+// Unfinished returns the maps that are still pending, submitted or
+// generating
+func (g *Generator) Unfinished() []*types.Map {
+	var maps []*types.Map
+	for _, m := range g.maps {
+		if awaitingSubmission(m) || m.Status == common.StatusSubmitted || m.Status == common.StatusGenerating {
+			maps = append(maps, m)
+		}
+	}
+	return maps
+}
+
 func (g *Generator) ContainCustomMaps() bool {
 	for _, m := range g.maps {
 		if m.SavedConfig != "" {
@@ -54,6 +148,19 @@ func (g *Generator) GetRandomSeed() string {
 	return fmt.Sprintf("%d", seed)
 }
 
+// sleep pauses for d, returning early with the context's error if it is
+// cancelled first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 func parseInt(s string) int {
 	var i int
 	fmt.Sscanf(s, "%d", &i)
@@ -3,6 +3,7 @@ package rustmaps
 import (
 	"testing"
 
+	"github.com/maintc/rustmaps-cli/pkg/api"
 	"github.com/maintc/rustmaps-cli/pkg/common"
 	"github.com/maintc/rustmaps-cli/pkg/types"
 )
@@ -56,6 +57,17 @@ func TestGenerator_Pending(t *testing.T) {
 			}),
 			want: true,
 		},
+		{
+			name: "Rate limited maps are still pending",
+			generator: NewMockedGenerator(t, &Generator{
+				maps: []*types.Map{
+					{
+						Status: common.StatusRateLimited,
+					},
+				},
+			}),
+			want: true,
+		},
 		{
 			name: "Get no pending maps",
 			generator: NewMockedGenerator(t, &Generator{
@@ -229,3 +241,89 @@ func Test_parseBool(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerator_Unfinished(t *testing.T) {
+	tests := []struct {
+		name      string
+		generator *Generator
+		want      int
+	}{
+		{
+			name: "Pending and generating maps are unfinished",
+			generator: NewMockedGenerator(t, &Generator{
+				maps: []*types.Map{
+					{
+						Status: common.StatusPending,
+					},
+					{
+						Status: common.StatusGenerating,
+					},
+					{
+						Status: common.StatusComplete,
+					},
+				},
+			}),
+			want: 2,
+		},
+		{
+			name: "No unfinished maps",
+			generator: NewMockedGenerator(t, &Generator{
+				maps: []*types.Map{
+					{
+						Status: common.StatusComplete,
+					},
+				},
+			}),
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.generator.Unfinished(); len(got) != tt.want {
+				t.Errorf("Generator.Unfinished() = %v, want %v", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func Test_generateRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		m    *types.Map
+		want api.GenerateRequest
+	}{
+		{
+			name: "Custom staging map",
+			m:    &types.Map{Seed: "1", Size: 4000, SavedConfig: "default", Staging: true, MapID: "abc"},
+			want: api.GenerateRequest{Seed: "1", Size: 4000, ConfigName: "default", Staging: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := generateRequest(tt.m); got != tt.want {
+				t.Errorf("generateRequest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_statusRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		m    *types.Map
+		want api.StatusRequest
+	}{
+		{
+			name: "Map with ID",
+			m:    &types.Map{Seed: "1", Size: 4000, SavedConfig: "default", MapID: "abc"},
+			want: api.StatusRequest{Seed: "1", Size: 4000, MapID: "abc"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statusRequest(tt.m); got != tt.want {
+				t.Errorf("statusRequest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
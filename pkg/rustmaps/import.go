@@ -2,13 +2,15 @@ package rustmaps
 
 import (
 	"encoding/csv"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/fsutil"
+	"github.com/maintc/rustmaps-cli/pkg/report"
 	"github.com/maintc/rustmaps-cli/pkg/types"
 	"go.uber.org/zap"
 )
@@ -133,7 +135,11 @@ func (g *Generator) ValidateCSV(log *zap.Logger, mapsPath string) error {
 	return nil
 }
 
-// Import imports a CSV file containing map definitions
+// Import imports a CSV file containing map definitions, merging each map
+// with its saved state. Each map is locked for as long as the generator
+// runs, so another rustmaps process cannot work on the same map at the same
+// time. Saved state that cannot be decoded is reported and the map starts
+// over.
 func (g *Generator) Import(log *zap.Logger, force bool) error {
 
 	// if err := g.ValidateCSV(log, mapsPath); err != nil {
@@ -141,55 +147,148 @@ func (g *Generator) Import(log *zap.Logger, force bool) error {
 	// 	return err
 	// }
 
+	return g.importMaps(log, force, importLocked)
+}
+
+// ImportShared merges each map with its saved state like Import, but maps
+// that another rustmaps process is working on are loaded without being
+// locked, and maps without saved state are neither locked nor saved and
+// have no status. Refresh only saves the maps it locked.
+func (g *Generator) ImportShared(log *zap.Logger) error {
+	return g.importMaps(log, false, importShared)
+}
+
+// Inspect merges each map with its saved state without locking or saving
+// any of them, so maps that were never saved stay that way
+func (g *Generator) Inspect(log *zap.Logger) error {
+	return g.importMaps(log, false, importReadOnly)
+}
+
+// importMode is how importMaps locks the maps it loads
+type importMode int
+
+const (
+	// importLocked locks every map, failing when another process holds one
+	importLocked importMode = iota
+	// importShared locks the saved maps no other process holds and loads
+	// the rest read-only
+	importShared
+	// importReadOnly locks and saves nothing
+	importReadOnly
+)
+
+func (g *Generator) importMaps(log *zap.Logger, force bool, mode importMode) error {
 	for _, m := range g.maps {
 
 		if m.Filename == "" {
 			m.SetFilename()
 		}
 
-		path := filepath.Join(g.importsDir, m.Filename)
+		if mode == importShared {
+			// Looking at a map must not start tracking it, it has no
+			// local status until generate saves it
+			saved, err := g.mapStore().Load(m.Filename)
+			if err == nil && saved == nil {
+				log.Debug("Map not tracked locally", zap.String("map", m.String()))
+				m.Status = ""
+				continue
+			}
+		}
+
+		if mode != importReadOnly {
+			if err := g.lockEntry(m); mode == importShared && errors.Is(err, ErrMapLocked) {
+				log.Debug("Map in use, loading its state read-only", zap.String("map", m.String()))
+			} else if err != nil {
+				log.Error("Error locking map file", zap.Error(err), zap.String("map", m.String()))
+				return err
+			}
+		}
 
 		if !force {
-			if _, err := os.Stat(path); err == nil {
-				log.Debug("Map file already exists, loading existing file", zap.String("path", path))
-
-				// Read and deserialize the existing JSON file
-				file, err := os.Open(path)
-				if err != nil {
-					log.Error("Error opening existing file", zap.Error(err), zap.String("path", path))
-					return err
-				}
-				defer file.Close()
-
-				var existingMap types.Map // Replace with the actual type of your map structure
-				if err := json.NewDecoder(file).Decode(&existingMap); err != nil {
-					log.Error("Error decoding existing map file", zap.Error(err), zap.String("path", path))
-					return err
-				}
+			existingMap, err := g.mapStore().Load(m.Filename)
+			switch {
+			case errors.Is(err, ErrCorrupt):
+				// Left truncated by a crash or a full disk, starting the
+				// map over beats failing the whole run
+				log.Warn("Corrupt saved state", zap.Error(err), zap.String("map", m.String()))
+				g.reporter.Report(report.Event{Type: report.Error, Map: m, Message: "loading saved state", Err: err})
+			case err != nil:
+				log.Error("Error loading saved state", zap.Error(err), zap.String("map", m.String()))
+				return err
+			case existingMap != nil:
+				log.Debug("Map already saved, loading its state", zap.String("map", m.String()))
 
 				// Merge the fields from existingMap into m
-				m.MergeFrom(existingMap)
+				m.MergeFrom(*existingMap)
+				if m.Status == common.StatusSubmitted {
+					// The run stopped before hearing back, submitting
+					// again finds out what became of it
+					g.transition(m, common.StatusPending, EventRetry)
+				}
 				continue
 			}
 		}
 
-		// export json to file
-		file, err := os.Create(path)
-		if err != nil {
-			log.Error("Error creating file", zap.Error(err), zap.String("path", path))
-			return err
+		if !g.locked(m) {
+			continue
 		}
-		defer file.Close()
-
-		enc := json.NewEncoder(file)
-		enc.SetIndent("", "  ")
-		if err := enc.Encode(m); err != nil {
-			log.Error("Error encoding map", zap.Error(err), zap.String("map", m.String()), zap.String("path", path))
+		if err := g.mapStore().Save(m); err != nil {
+			log.Error("Error saving map", zap.Error(err), zap.String("map", m.String()))
 			return err
 		}
 
-		log.Debug("Exported map", zap.String("map", m.String()), zap.String("path", path))
+		log.Debug("Exported map", zap.String("map", m.String()))
 	}
 
 	return nil
 }
+
+// ErrMapLocked is returned by Import when another rustmaps process is
+// working on one of the maps
+var ErrMapLocked = errors.New("map is in use by another rustmaps process")
+
+// lockEntry locks m until Close. The lock is taken on a file of its own
+// under the imports directory, whichever store the map is saved in.
+func (g *Generator) lockEntry(m *types.Map) error {
+	if _, ok := g.locks[m.Filename]; ok {
+		return nil
+	}
+
+	dir := filepath.Join(g.importsDir, ".locks")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	lock, err := fsutil.TryLock(filepath.Join(dir, m.Filename+".lock"))
+	if errors.Is(err, fsutil.ErrLocked) {
+		return fmt.Errorf("%s: %w", m.String(), ErrMapLocked)
+	}
+	if err != nil {
+		return err
+	}
+
+	if g.locks == nil {
+		g.locks = make(map[string]*fsutil.Lock)
+	}
+	g.locks[m.Filename] = lock
+	return nil
+}
+
+// locked reports whether g holds the lock on m
+func (g *Generator) locked(m *types.Map) bool {
+	_, ok := g.locks[m.Filename]
+	return ok
+}
+
+// Close releases the maps locked by Import, so other processes may work on
+// them, and closes the store. Exiting the process releases the maps too.
+func (g *Generator) Close() error {
+	var errs []error
+	for name, lock := range g.locks {
+		errs = append(errs, lock.Unlock())
+		delete(g.locks, name)
+	}
+	if g.store != nil {
+		errs = append(errs, g.store.Close())
+	}
+	return errors.Join(errs...)
+}
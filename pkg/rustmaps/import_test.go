@@ -1,8 +1,14 @@
 package rustmaps
 
 import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/report"
 	"github.com/maintc/rustmaps-cli/pkg/types"
 	"go.uber.org/zap"
 )
@@ -213,3 +219,85 @@ func TestGenerator_Import(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerator_Import_submitted(t *testing.T) {
+	g := NewMockedGenerator(t, &Generator{})
+	saved := types.NewMap("123", 4000, "", false)
+	saved.SetFilename()
+	saved.Status = common.StatusSubmitted
+	if err := saved.SaveJSON(g.importsDir); err != nil {
+		t.Fatal(err)
+	}
+
+	m := types.NewMap("123", 4000, "", false)
+	g.maps = []*types.Map{m}
+	if err := g.Import(zap.NewNop(), false); err != nil {
+		t.Fatalf("Generator.Import() error = %v", err)
+	}
+	if m.Status != common.StatusPending {
+		t.Errorf("Generator.Import() status = %v, want %v", m.Status, common.StatusPending)
+	}
+	if len(m.History) != 1 || m.History[0].Event != EventRetry {
+		t.Errorf("Generator.Import() history = %+v, want a retry", m.History)
+	}
+}
+
+func TestGenerator_Import_corrupt(t *testing.T) {
+	var events []report.Event
+	g := NewMockedGenerator(t, &Generator{
+		reporter: report.Func(func(e report.Event) { events = append(events, e) }),
+	})
+	m := types.NewMap("123", 4000, "", false)
+	m.SetFilename()
+	path := filepath.Join(g.importsDir, m.Filename)
+	// Truncated by a crash mid-write
+	if err := os.WriteFile(path, []byte(`{"seed": "123", "size": 40`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g.maps = []*types.Map{m}
+	if err := g.Import(zap.NewNop(), false); err != nil {
+		t.Fatalf("Generator.Import() error = %v", err)
+	}
+	if m.Status != common.StatusPending {
+		t.Errorf("Generator.Import() status = %v, want %v", m.Status, common.StatusPending)
+	}
+	if len(events) != 1 || events[0].Type != report.Error || events[0].Map != m {
+		t.Errorf("Generator.Import() reported %+v, want one error for the map", events)
+	}
+
+	quarantined, _ := filepath.Glob(path + ".corrupt-*")
+	if len(quarantined) != 1 {
+		t.Errorf("Generator.Import() quarantined %v, want one file", quarantined)
+	}
+	var saved types.Map
+	if data, err := os.ReadFile(path); err != nil || json.Unmarshal(data, &saved) != nil || saved.Seed != "123" {
+		t.Errorf("Generator.Import() saved %+v, want the map starting over", saved)
+	}
+}
+
+func TestGenerator_Import_locked(t *testing.T) {
+	first := NewMockedGenerator(t, &Generator{})
+	second := NewMockedGenerator(t, &Generator{importsDir: first.importsDir})
+	first.maps = []*types.Map{types.NewMap("123", 4000, "", false)}
+	second.maps = []*types.Map{types.NewMap("123", 4000, "", false), types.NewMap("456", 4000, "", false)}
+
+	if err := first.Import(zap.NewNop(), false); err != nil {
+		t.Fatalf("Generator.Import() error = %v", err)
+	}
+	// Importing again keeps the locks already held
+	if err := first.Import(zap.NewNop(), false); err != nil {
+		t.Fatalf("Generator.Import() again error = %v", err)
+	}
+	if err := second.Import(zap.NewNop(), false); !errors.Is(err, ErrMapLocked) {
+		t.Fatalf("Generator.Import() of a locked map error = %v, want %v", err, ErrMapLocked)
+	}
+
+	if err := first.Close(); err != nil {
+		t.Fatalf("Generator.Close() error = %v", err)
+	}
+	if err := second.Import(zap.NewNop(), false); err != nil {
+		t.Fatalf("Generator.Import() after Close error = %v", err)
+	}
+	second.Close()
+}
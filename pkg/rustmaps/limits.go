@@ -0,0 +1,51 @@
+package rustmaps
+
+import (
+	"context"
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/report"
+	"go.uber.org/zap"
+)
+
+// Quota fetches the account's limits and puts them next to the maps the
+// saved state says were submitted this month, with a forecast of when the
+// monthly allowance runs out. RustMaps does not say when the quota resets,
+// it is taken to reset at the start of each month in UTC.
+func (g *Generator) Quota(ctx context.Context, log *zap.Logger, now time.Time) (report.Quota, error) {
+	limits, err := g.rmcli.GetLimits(ctx, log)
+	if err != nil {
+		return report.Quota{}, err
+	}
+
+	start, reset := quotaMonth(now)
+	generated, err := g.QueryMaps(Query{Since: start})
+	if err != nil {
+		return report.Quota{}, err
+	}
+
+	tier, ok := tierLimits[limits.Data.Monthly.Allowed]
+	if !ok {
+		tier = g.GetTier()
+	}
+	q := report.Quota{
+		Tier: tier,
+		Limits: report.Limits{
+			Concurrent: report.Usage{Current: limits.Data.Concurrent.Current, Allowed: limits.Data.Concurrent.Allowed},
+			Monthly:    report.Usage{Current: limits.Data.Monthly.Current, Allowed: limits.Data.Monthly.Allowed},
+		},
+		Reserve:   g.quotaReserve(),
+		Generated: len(generated),
+		Resets:    reset,
+	}
+	q.Forecast = report.NewForecast(q.Monthly, start, reset, now)
+	return q, nil
+}
+
+// quotaMonth returns when the month of quota containing now started and
+// when it resets
+func quotaMonth(now time.Time) (start, reset time.Time) {
+	now = now.UTC()
+	start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
@@ -0,0 +1,85 @@
+package rustmaps
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/types"
+	"go.uber.org/zap"
+)
+
+func TestQuotaMonth(t *testing.T) {
+	tests := []struct {
+		name      string
+		now       time.Time
+		wantStart time.Time
+		wantReset time.Time
+	}{
+		{
+			name:      "Mid month",
+			now:       time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC),
+			wantStart: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+			wantReset: time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "December",
+			now:       time.Date(2026, 12, 31, 23, 0, 0, 0, time.UTC),
+			wantStart: time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC),
+			wantReset: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "Already next month in UTC",
+			now:       time.Date(2026, 10, 31, 20, 0, 0, 0, time.FixedZone("EST", -5*3600)),
+			wantStart: time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+			wantReset: time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, reset := quotaMonth(tt.now)
+			if !start.Equal(tt.wantStart) || !reset.Equal(tt.wantReset) {
+				t.Errorf("quotaMonth() = %v, %v, want %v, %v", start, reset, tt.wantStart, tt.wantReset)
+			}
+		})
+	}
+}
+
+func TestGenerator_Quota(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	rmcli := &MockedRustMapsCLI{ConcurrentCurrent: 1, ConcurrentAllowed: 3, MonthlyCurrent: 160, MonthlyAllowed: 800}
+	g := NewMockedGenerator(t, &Generator{rmcli: rmcli, config: types.Config{Tier: "Premium", QuotaReserve: 20}})
+	for _, m := range []*types.Map{
+		{Seed: "1", Size: 4000, Status: common.StatusComplete, SubmittedAt: "2026-10-02T10:00:00Z"},
+		{Seed: "2", Size: 4000, Status: common.StatusComplete, SubmittedAt: "2026-09-30T23:00:00Z"},
+		{Seed: "3", Size: 4000, Status: common.StatusPending},
+	} {
+		m.SetFilename()
+		if err := g.mapStore().Save(m); err != nil {
+			t.Fatalf("Store.Save() error = %v", err)
+		}
+	}
+
+	q, err := g.Quota(context.Background(), zap.NewNop(), now)
+	if err != nil {
+		t.Fatalf("Generator.Quota() error = %v", err)
+	}
+	if q.Tier != "Premium" || q.Concurrent.Current != 1 || q.Monthly.Current != 160 || q.Reserve != 20 {
+		t.Errorf("Generator.Quota() = %+v, want the Premium limits and reserve", q)
+	}
+	if q.Generated != 1 {
+		t.Errorf("Generator.Quota() generated = %d, want 1 submitted this month", q.Generated)
+	}
+	if want := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC); !q.Resets.Equal(want) {
+		t.Errorf("Generator.Quota() resets = %v, want %v", q.Resets, want)
+	}
+	if q.Forecast.PerDay != 9.7 {
+		t.Errorf("Generator.Quota() forecast = %+v, want 9.7 a day", q.Forecast)
+	}
+
+	rmcli.LimitsError = true
+	if _, err := g.Quota(context.Background(), zap.NewNop(), now); err == nil {
+		t.Error("Generator.Quota() error = nil when the limits cannot be fetched")
+	}
+}
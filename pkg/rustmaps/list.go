@@ -0,0 +1,119 @@
+package rustmaps
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/types"
+)
+
+// Orders accepted by SortMaps
+const (
+	SortSeed      = "seed"
+	SortSize      = "size"
+	SortConfig    = "config"
+	SortStatus    = "status"
+	SortSubmitted = "submitted"
+	SortCompleted = "completed"
+)
+
+// SortOrders lists every order SortMaps accepts
+var SortOrders = []string{SortSeed, SortSize, SortConfig, SortStatus, SortSubmitted, SortCompleted}
+
+// SortMaps orders maps by the given order, ties by filename. Times sort
+// oldest first, after the maps without one.
+func SortMaps(maps []*types.Map, order string) error {
+	var compare func(a, b *types.Map) int
+	switch order {
+	case SortSeed:
+		compare = func(a, b *types.Map) int { return compareSeeds(a.Seed, b.Seed) }
+	case SortSize:
+		compare = func(a, b *types.Map) int { return a.Size - b.Size }
+	case SortConfig:
+		compare = func(a, b *types.Map) int {
+			return strings.Compare(savedConfigName(a.SavedConfig), savedConfigName(b.SavedConfig))
+		}
+	case SortStatus:
+		compare = func(a, b *types.Map) int { return strings.Compare(a.Status, b.Status) }
+	case SortSubmitted:
+		compare = func(a, b *types.Map) int { return parseTime(a.SubmittedAt).Compare(parseTime(b.SubmittedAt)) }
+	case SortCompleted:
+		compare = func(a, b *types.Map) int { return parseTime(a.CompletedAt).Compare(parseTime(b.CompletedAt)) }
+	default:
+		return fmt.Errorf("invalid sort order %q, must be one of %s", order, strings.Join(SortOrders, ", "))
+	}
+	sort.SliceStable(maps, func(i, j int) bool {
+		if c := compare(maps[i], maps[j]); c != 0 {
+			return c < 0
+		}
+		return maps[i].Filename < maps[j].Filename
+	})
+	return nil
+}
+
+// compareSeeds orders seeds by their value, so 9 comes before 10. Seeds
+// that are not numbers are compared as strings.
+func compareSeeds(a, b string) int {
+	x, errA := strconv.ParseInt(a, 10, 64)
+	y, errB := strconv.ParseInt(b, 10, 64)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	return cmp.Compare(x, y)
+}
+
+// parseTime parses an RFC3339 time, the zero time when there is none
+func parseTime(rfc3339 string) time.Time {
+	t, _ := time.Parse(time.RFC3339, rfc3339)
+	return t
+}
+
+// LocalDownloads finds the newest download of each of maps in the
+// downloads directory, by their map file. Maps never downloaded there are
+// left out.
+func (g *Generator) LocalDownloads(maps []*types.Map) (map[*types.Map]DownloadFiles, error) {
+	versions, err := os.ReadDir(g.downloadsDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Versions are named by the time of the download, so later ones
+	// replace earlier downloads of the same map
+	newest := make(map[string]string)
+	for _, version := range versions {
+		if !version.IsDir() {
+			continue
+		}
+		dir := filepath.Join(g.downloadsDir, version.Name())
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			if prefix, ok := strings.CutSuffix(f.Name(), ".map"); ok {
+				newest[prefix] = dir
+			}
+		}
+	}
+
+	downloads := make(map[*types.Map]DownloadFiles)
+	for _, m := range maps {
+		if m.MapID == "" {
+			continue
+		}
+		prefix := downloadPrefix(m)
+		if dir, ok := newest[prefix]; ok {
+			downloads[m] = downloadFiles(dir, prefix)
+		}
+	}
+	return downloads, nil
+}
@@ -0,0 +1,113 @@
+package rustmaps
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/maintc/rustmaps-cli/pkg/types"
+)
+
+func TestSortMaps(t *testing.T) {
+	newMaps := func() []*types.Map {
+		maps := []*types.Map{
+			{Seed: "10", Size: 4000, SavedConfig: "mycfg", Status: "Pending"},
+			{Seed: "1", Size: 4500, Status: "Complete", SubmittedAt: "2026-10-02T10:00:00+02:00", CompletedAt: "2026-10-02T10:05:00+02:00"},
+			{Seed: "2", Size: 3500, Status: "Generating", SubmittedAt: "2026-10-02T09:00:00Z"},
+		}
+		for _, m := range maps {
+			m.SetFilename()
+		}
+		return maps
+	}
+	tests := []struct {
+		order   string
+		want    []string
+		wantErr bool
+	}{
+		{order: SortSeed, want: []string{"1", "2", "10"}},
+		{order: SortSize, want: []string{"2", "10", "1"}},
+		{order: SortConfig, want: []string{"10", "1", "2"}},
+		{order: SortStatus, want: []string{"1", "2", "10"}},
+		{order: SortSubmitted, want: []string{"10", "1", "2"}},
+		{order: SortCompleted, want: []string{"10", "2", "1"}},
+		{order: "colour", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			maps := newMaps()
+			if err := SortMaps(maps, tt.order); (err != nil) != tt.wantErr {
+				t.Fatalf("SortMaps() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var got []string
+			for _, m := range maps {
+				got = append(got, m.Seed)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SortMaps() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_compareSeeds(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want int
+	}{
+		{name: "Numbers", a: "9", b: "10", want: -1},
+		{name: "Negative", a: "-5", b: "3", want: -1},
+		{name: "Equal", a: "42", b: "42", want: 0},
+		{name: "Not a number", a: "b", b: "a", want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compareSeeds(tt.a, tt.b); got != tt.want {
+				t.Errorf("compareSeeds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerator_LocalDownloads(t *testing.T) {
+	g := NewMockedGenerator(t, &Generator{})
+	downloaded := &types.Map{Seed: "1", Size: 4000, MapID: "abc"}
+	redownloaded := &types.Map{Seed: "2", Size: 4000, SavedConfig: "mycfg", MapID: "def"}
+	notDownloaded := &types.Map{Seed: "3", Size: 4000, MapID: "ghi"}
+	noID := &types.Map{Seed: "4", Size: 4000}
+
+	for dir, maps := range map[string][]*types.Map{
+		"2026-10-01_10-00-00": {downloaded, redownloaded},
+		"2026-10-02_10-00-00": {redownloaded},
+	} {
+		for _, m := range maps {
+			files := downloadFiles(filepath.Join(g.downloadsDir, dir), downloadPrefix(m))
+			os.MkdirAll(filepath.Dir(files.Map), 0755)
+			if err := os.WriteFile(files.Map, []byte("map"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	got, err := g.LocalDownloads([]*types.Map{downloaded, redownloaded, notDownloaded, noID})
+	if err != nil {
+		t.Fatalf("Generator.LocalDownloads() error = %v", err)
+	}
+	want := map[*types.Map]DownloadFiles{
+		downloaded:   downloadFiles(filepath.Join(g.downloadsDir, "2026-10-01_10-00-00"), "1_4000_procedural_false_abc"),
+		redownloaded: downloadFiles(filepath.Join(g.downloadsDir, "2026-10-02_10-00-00"), "2_4000_mycfg_false_def"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Generator.LocalDownloads() = %v, want %v", got, want)
+	}
+
+	g.downloadsDir = filepath.Join(g.downloadsDir, "missing")
+	if got, err := g.LocalDownloads([]*types.Map{downloaded}); err != nil || len(got) != 0 {
+		t.Errorf("Generator.LocalDownloads() without a downloads directory = %v, %v, want nothing", got, err)
+	}
+}
@@ -2,12 +2,18 @@
 package rustmaps
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"time"
 
 	"github.com/maintc/rustmaps-cli/pkg/api"
+	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/fsutil"
+	"github.com/maintc/rustmaps-cli/pkg/report"
 	"github.com/maintc/rustmaps-cli/pkg/types"
 
 	"go.uber.org/zap"
@@ -35,14 +41,69 @@ type Generator struct {
 	logPath      string
 	baseDir      string
 	backoffTime  time.Duration
+	pollInterval time.Duration
+	parallel     int
+	// overrides replace configured values for this run without being saved
+	overrides types.Config
+	// clientOptions are applied last when building the API client
+	clientOptions []api.Option
+	// httpClient downloads map assets, each download is retried under
+	// downloadRetry
+	httpClient    *http.Client
+	downloadRetry api.RetryPolicy
+	// err is why the last Step stopped the run
+	err error
+	// machine moves maps between states and reports every change
+	machine *StateMachine
+	// sched decides when each pass of the run is due
+	sched *scheduler
+	// reporter receives progress events
+	reporter report.Reporter
+	// store keeps the state of every map between runs, the imports
+	// directory when nil
+	store Store
+	// locks are the state files Import locked, by file name
+	locks map[string]*fsutil.Lock
+	// downloads is what the last Download call did
+	downloads []DownloadResult
+	// generationTimeout and stallTimeout decide when a generating map is
+	// given up on, it is resubmitted up to maxResubmits times before failing
+	generationTimeout time.Duration
+	stallTimeout      time.Duration
+	maxResubmits      int
+	// maxWaitForQuota is how long the run waits for a free slot before
+	// giving up, 0 waits forever. waitingSince is when the wait began.
+	maxWaitForQuota time.Duration
+	waitingSince    time.Time
+	// limitsFailures counts the passes in a row the limits could not be
+	// fetched, the run stops at maxLimitsFailures
+	limitsFailures int
+	// maxMaps caps how many maps RustMaps may start generating for the
+	// run, 0 means no cap. reserve replaces the configured quota reserve
+	// when set.
+	maxMaps int
+	reserve *int
+	// started is when Run was first called, generated counts the maps
+	// RustMaps started generating since
+	started   time.Time
+	generated atomic.Int32
 }
 
 // NewGenerator creates a new Generator instance
 func NewGenerator(baseDir *string) (*Generator, error) {
 	g := &Generator{
-		config:      types.Config{Tier: "Free"},
-		backoffTime: 30 * time.Second,
+		config:            types.Config{Tier: "Free"},
+		backoffTime:       30 * time.Second,
+		pollInterval:      2 * time.Second,
+		httpClient:        &http.Client{},
+		downloadRetry:     downloadRetryPolicy,
+		reporter:          report.NewText(os.Stdout),
+		generationTimeout: DefaultGenerationTimeout,
+		stallTimeout:      DefaultStallTimeout,
+		maxResubmits:      DefaultMaxResubmits,
+		sched:             newScheduler(),
 	}
+	g.machine = g.newStateMachine()
 
 	if baseDir == nil {
 		var err error
@@ -74,49 +135,133 @@ func (g *Generator) InitDirs() error {
 	return nil
 }
 
-func (g *Generator) CanGenerate(log *zap.Logger) bool {
-	limits, err := g.rmcli.GetLimits(log)
+func (g *Generator) CanGenerate(ctx context.Context, log *zap.Logger) bool {
+	return g.AvailableSlots(ctx, log) > 0
+}
+
+// AvailableSlots returns how many maps can be submitted right now without
+// exceeding the account's concurrent or monthly limits, capped by the
+// parallel setting when one is configured. The quota reserve and the
+// maximum maps for the run are kept to.
+func (g *Generator) AvailableSlots(ctx context.Context, log *zap.Logger) int {
+	slots, _ := g.availableSlots(ctx, log)
+	return slots
+}
+
+// availableSlots is AvailableSlots, also returning ErrQuotaExhausted when
+// the monthly quota is used up down to the reserve, or ErrBudgetSpent when
+// the run generated as many maps as it may. When the limits cannot be
+// fetched there are no slots and the error is returned instead.
+func (g *Generator) availableSlots(ctx context.Context, log *zap.Logger) (slots int, spent error) {
+	limits, err := g.rmcli.GetLimits(ctx, log)
 	if err != nil {
-		fmt.Printf("Error getting limits: %v\n", err)
-		return false
+		g.reporter.Report(report.Event{Type: report.Error, Message: "getting limits", Err: err})
+		return 0, err
 	}
 
-	var canGenerateConcurrent = limits.Data.Concurrent.Current < limits.Data.Concurrent.Allowed
-	var canGenerateMonthly = limits.Data.Monthly.Current < limits.Data.Monthly.Allowed
+	g.reporter.Report(report.Event{
+		Type: report.LimitsChecked,
+		Limits: &report.Limits{
+			Concurrent: report.Usage{Current: limits.Data.Concurrent.Current, Allowed: limits.Data.Concurrent.Allowed},
+			Monthly:    report.Usage{Current: limits.Data.Monthly.Current, Allowed: limits.Data.Monthly.Allowed},
+		},
+	})
+
+	concurrent := limits.Data.Concurrent.Allowed - limits.Data.Concurrent.Current
+	monthly := limits.Data.Monthly.Allowed - limits.Data.Monthly.Current - g.quotaReserve()
 
-	if !canGenerateConcurrent {
-		fmt.Println("Cannot generate map: concurrent limit reached")
+	if concurrent <= 0 {
+		g.reporter.Report(report.Event{Type: report.LimitReached, Limit: report.LimitConcurrent})
 	}
 
-	if !canGenerateMonthly {
-		fmt.Println("Cannot generate map: monthly limit reached")
+	if monthly <= 0 {
+		g.reporter.Report(report.Event{Type: report.LimitReached, Limit: report.LimitMonthly})
+		spent = ErrQuotaExhausted
 	}
 
-	return canGenerateConcurrent && canGenerateMonthly
+	slots = min(concurrent, monthly)
+	if g.maxMaps > 0 {
+		left := g.maxMaps - int(g.generated.Load())
+		if left <= 0 && spent == nil {
+			g.reporter.Report(report.Event{Type: report.LimitReached, Limit: report.LimitBudget})
+			spent = ErrBudgetSpent
+		}
+		slots = min(slots, left)
+	}
+	if g.parallel > 0 {
+		slots = min(slots, g.parallel)
+	}
+	return max(slots, 0), spent
+}
+
+// GetLimits returns the account's current generation limits
+func (g *Generator) GetLimits(ctx context.Context, log *zap.Logger) (*api.RustMapsLimitsResponse, error) {
+	return g.rmcli.GetLimits(ctx, log)
 }
 
-func (g *Generator) GetStatus(log *zap.Logger, m *types.Map) (*api.RustMapsStatusResponse, error) {
-	status, err := g.rmcli.GetStatus(log, m)
+func (g *Generator) GetStatus(ctx context.Context, log *zap.Logger, m *types.Map) (*api.RustMapsStatusResponse, error) {
+	status, err := g.rmcli.GetStatus(ctx, log, statusRequest(m))
 	if err != nil {
-		fmt.Printf("Error getting status: %v\n", err)
+		g.reporter.Report(report.Event{Type: report.Error, Map: m, Message: "getting status", Err: err})
 		return nil, err
 	}
 
 	return status, nil
 }
 
-func (g *Generator) SyncStatus(log *zap.Logger, m *types.Map) error {
-	status, err := g.rmcli.GetStatus(log, m)
+func (g *Generator) SyncStatus(ctx context.Context, log *zap.Logger, m *types.Map) error {
+	status, err := g.rmcli.GetStatus(ctx, log, statusRequest(m))
 	if err != nil {
-		fmt.Printf("Error getting status: %v\n", err)
+		g.reporter.Report(report.Event{Type: report.Error, Map: m, Message: "getting status", Err: err})
 		return err
 	}
 
-	m.ReportStatus(status.Meta.Status)
-	m.SaveJSON(g.importsDir)
+	if status.Generation != nil {
+		recordProgress(m, *status.Generation)
+	}
+	switch status.Meta.Status {
+	case common.StatusGenerating:
+		g.transition(m, common.StatusGenerating, EventProgress)
+	case common.StatusComplete:
+		if m.Status == common.StatusDownloaded {
+			// Still there, nothing new to record
+			g.transition(m, common.StatusDownloaded, EventCompleted)
+		} else {
+			g.transition(m, common.StatusComplete, EventCompleted)
+		}
+	case common.StatusNotFound:
+		// Submit it again
+		g.transition(m, common.StatusPending, EventLost)
+	case common.StatusUnauthorized:
+		return api.ErrUnauthorized
+	case common.StatusForbidden:
+		return api.ErrForbidden
+	default:
+		return fmt.Errorf("unexpected status %q for %s", status.Meta.Status, m.String())
+	}
+	if err := g.mapStore().Save(m); err != nil {
+		log.Error("Error saving map", zap.Error(err), zap.String("map", m.String()))
+		return err
+	}
 	return nil
 }
 
+// SaveState persists every loaded map to the store so an interrupted run
+// can pick up where it left off
+func (g *Generator) SaveState(log *zap.Logger) error {
+	var lastErr error
+	for _, m := range g.maps {
+		if m.Filename == "" {
+			m.SetFilename()
+		}
+		if err := g.mapStore().Save(m); err != nil {
+			log.Error("Error saving map file", zap.String("map", m.String()), zap.Error(err))
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
 func (g *Generator) AddMap(m *types.Map) {
 	m.SetFilename()
 	g.maps = append(g.maps, m)
@@ -2,12 +2,19 @@
 package rustmaps
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/maintc/rustmaps-cli/pkg/api"
+	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/report"
 	"github.com/maintc/rustmaps-cli/pkg/types"
 	"go.uber.org/zap"
 )
@@ -25,6 +32,12 @@ func NewMockedGenerator(t *testing.T, other *Generator) *Generator {
 		downloadsDir: t.TempDir(),
 		logPath:      filepath.Join(t.TempDir(), "generator.log"),
 		backoffTime:  0,
+		httpClient:   &http.Client{},
+		downloadRetry: api.RetryPolicy{
+			MaxAttempts: 2,
+			BaseDelay:   time.Millisecond,
+			MaxDelay:    time.Millisecond,
+		},
 	}
 
 	if other.configPath != "" {
@@ -48,6 +61,20 @@ func NewMockedGenerator(t *testing.T, other *Generator) *Generator {
 	mocked.rmcli = other.rmcli
 	mocked.target = other.target
 	mocked.baseDir = other.baseDir
+	mocked.parallel = other.parallel
+	mocked.generationTimeout = other.generationTimeout
+	mocked.stallTimeout = other.stallTimeout
+	mocked.maxResubmits = other.maxResubmits
+	mocked.maxWaitForQuota = other.maxWaitForQuota
+	mocked.waitingSince = other.waitingSince
+	mocked.maxMaps = other.maxMaps
+	mocked.reserve = other.reserve
+	mocked.reporter = report.Discard
+	if other.reporter != nil {
+		mocked.reporter = other.reporter
+	}
+	mocked.sched = newScheduler()
+	mocked.machine = mocked.newStateMachine()
 
 	return mocked
 }
@@ -63,9 +90,20 @@ type MockedRustMapsCLI struct {
 	MonthlyCurrent    int
 	MonthlyAllowed    int
 	LimitsError       bool
+	// LimitsErr is returned by GetLimits when set
+	LimitsErr error
+	// Status is reported by GetStatus, Complete when empty
+	Status string
+	// Generation is returned by GetStatus as the generation progress
+	Generation *api.RustMapsGenerateResponseData
+	// MapID is reported by GetStatus as the ID of every map
+	MapID string
+	// GenerateError is returned by every generate call
+	GenerateError error
+	submitted     atomic.Int32
 }
 
-func (c *MockedRustMapsCLI) GetStatus(log *zap.Logger, m *types.Map) (*api.RustMapsStatusResponse, error) {
+func (c *MockedRustMapsCLI) GetStatus(ctx context.Context, log *zap.Logger, m api.StatusRequest) (*api.RustMapsStatusResponse, error) {
 	canDownload := true
 	switch m.Seed {
 	case "0":
@@ -74,17 +112,26 @@ func (c *MockedRustMapsCLI) GetStatus(log *zap.Logger, m *types.Map) (*api.RustM
 		canDownload = false
 	}
 
+	switch c.Status {
+	case common.StatusUnauthorized:
+		return nil, &api.Error{StatusCode: http.StatusUnauthorized}
+	case common.StatusForbidden:
+		return nil, &api.Error{StatusCode: http.StatusForbidden}
+	}
+
 	if c.MockedServerUrl == "" {
 		c.MockedServerUrl = "http://localhost"
 	}
 
 	return &api.RustMapsStatusResponse{
+		Generation: c.Generation,
 		Meta: api.RustMapsStatusResponseMeta{
-			Status:     m.Status,
+			Status:     c.status(),
 			StatusCode: 200,
 			Errors:     []string{},
 		},
 		Data: api.RustMapsStatusResponseData{
+			ID:           c.MapID,
 			Seed:         parseInt(m.Seed),
 			Size:         m.Size,
 			CanDownload:  canDownload,
@@ -98,7 +145,10 @@ func (c *MockedRustMapsCLI) GetStatus(log *zap.Logger, m *types.Map) (*api.RustM
 	}, nil
 }
 
-func (c *MockedRustMapsCLI) GetLimits(log *zap.Logger) (*api.RustMapsLimitsResponse, error) {
+func (c *MockedRustMapsCLI) GetLimits(ctx context.Context, log *zap.Logger) (*api.RustMapsLimitsResponse, error) {
+	if c.LimitsErr != nil {
+		return nil, c.LimitsErr
+	}
 	if c.LimitsError {
 		return nil, fmt.Errorf("error")
 	}
@@ -121,7 +171,19 @@ func (c *MockedRustMapsCLI) GetLimits(log *zap.Logger) (*api.RustMapsLimitsRespo
 	}, nil
 }
 
-func (c *MockedRustMapsCLI) GenerateProcedural(log *zap.Logger, m *types.Map) (*api.RustMapsGenerateResponse, error) {
+// status is the status GetStatus reports for every map
+func (c *MockedRustMapsCLI) status() string {
+	if c.Status == "" {
+		return common.StatusComplete
+	}
+	return c.Status
+}
+
+func (c *MockedRustMapsCLI) GenerateProcedural(ctx context.Context, log *zap.Logger, m api.GenerateRequest) (*api.RustMapsGenerateResponse, error) {
+	c.submitted.Add(1)
+	if c.GenerateError != nil {
+		return nil, c.GenerateError
+	}
 	return &api.RustMapsGenerateResponse{
 		Meta: api.RustMapsGenerateResponseMeta{
 			Status:     "complete",
@@ -237,13 +299,210 @@ func TestGenerator_CanGenerate(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := tt.generator.CanGenerate(tt.args.log); got != tt.want {
+			if got := tt.generator.CanGenerate(context.Background(), tt.args.log); got != tt.want {
 				t.Errorf("Generator.CanGenerate() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
+func TestGenerator_AvailableSlots(t *testing.T) {
+	type args struct {
+		log *zap.Logger
+	}
+	tests := []struct {
+		name      string
+		generator *Generator
+		args      args
+		want      int
+	}{
+		{
+			name: "Slots limited by concurrent limit",
+			generator: NewMockedGenerator(t, &Generator{
+				rmcli: &MockedRustMapsCLI{
+					ConcurrentCurrent: 1,
+					ConcurrentAllowed: 8,
+					MonthlyCurrent:    0,
+					MonthlyAllowed:    800,
+				},
+			}),
+			args: args{
+				log: zap.NewNop(),
+			},
+			want: 7,
+		},
+		{
+			name: "Slots limited by monthly limit",
+			generator: NewMockedGenerator(t, &Generator{
+				rmcli: &MockedRustMapsCLI{
+					ConcurrentCurrent: 0,
+					ConcurrentAllowed: 8,
+					MonthlyCurrent:    798,
+					MonthlyAllowed:    800,
+				},
+			}),
+			args: args{
+				log: zap.NewNop(),
+			},
+			want: 2,
+		},
+		{
+			name: "Slots limited by parallel",
+			generator: NewMockedGenerator(t, &Generator{
+				parallel: 3,
+				rmcli: &MockedRustMapsCLI{
+					ConcurrentCurrent: 0,
+					ConcurrentAllowed: 8,
+					MonthlyCurrent:    0,
+					MonthlyAllowed:    800,
+				},
+			}),
+			args: args{
+				log: zap.NewNop(),
+			},
+			want: 3,
+		},
+		{
+			name: "Slots limited by the quota reserve",
+			generator: NewMockedGenerator(t, &Generator{
+				config: types.Config{QuotaReserve: 20},
+				rmcli: &MockedRustMapsCLI{
+					ConcurrentCurrent: 0,
+					ConcurrentAllowed: 8,
+					MonthlyCurrent:    775,
+					MonthlyAllowed:    800,
+				},
+			}),
+			args: args{
+				log: zap.NewNop(),
+			},
+			want: 5,
+		},
+		{
+			name: "Overridden quota reserve",
+			generator: NewMockedGenerator(t, &Generator{
+				config:  types.Config{QuotaReserve: 20},
+				reserve: new(int),
+				rmcli: &MockedRustMapsCLI{
+					ConcurrentCurrent: 0,
+					ConcurrentAllowed: 8,
+					MonthlyCurrent:    795,
+					MonthlyAllowed:    800,
+				},
+			}),
+			args: args{
+				log: zap.NewNop(),
+			},
+			want: 5,
+		},
+		{
+			name: "Slots limited by max maps",
+			generator: NewMockedGenerator(t, &Generator{
+				maxMaps: 2,
+				rmcli: &MockedRustMapsCLI{
+					ConcurrentCurrent: 0,
+					ConcurrentAllowed: 8,
+					MonthlyCurrent:    0,
+					MonthlyAllowed:    800,
+				},
+			}),
+			args: args{
+				log: zap.NewNop(),
+			},
+			want: 2,
+		},
+		{
+			name: "No slots when over the limit",
+			generator: NewMockedGenerator(t, &Generator{
+				rmcli: &MockedRustMapsCLI{
+					ConcurrentCurrent: 5,
+					ConcurrentAllowed: 3,
+					MonthlyCurrent:    0,
+					MonthlyAllowed:    250,
+				},
+			}),
+			args: args{
+				log: zap.NewNop(),
+			},
+			want: 0,
+		},
+		{
+			name: "No slots on GetLimits error",
+			generator: NewMockedGenerator(t, &Generator{
+				rmcli: &MockedRustMapsCLI{
+					LimitsError: true,
+				},
+			}),
+			args: args{
+				log: zap.NewNop(),
+			},
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.generator.AvailableSlots(context.Background(), tt.args.log); got != tt.want {
+				t.Errorf("Generator.AvailableSlots() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerator_AvailableSlots_reports(t *testing.T) {
+	tests := []struct {
+		name    string
+		rmcli   *MockedRustMapsCLI
+		reserve int
+		maxMaps int
+		want    []report.EventType
+	}{
+		{
+			name:  "Limits with room",
+			rmcli: &MockedRustMapsCLI{ConcurrentAllowed: 2, MonthlyAllowed: 800},
+			want:  []report.EventType{report.LimitsChecked},
+		},
+		{
+			name:  "Both limits reached",
+			rmcli: &MockedRustMapsCLI{ConcurrentCurrent: 2, ConcurrentAllowed: 2, MonthlyCurrent: 800, MonthlyAllowed: 800},
+			want:  []report.EventType{report.LimitsChecked, report.LimitReached, report.LimitReached},
+		},
+		{
+			name:    "Quota reserve reached",
+			rmcli:   &MockedRustMapsCLI{ConcurrentAllowed: 2, MonthlyCurrent: 780, MonthlyAllowed: 800},
+			reserve: 20,
+			want:    []report.EventType{report.LimitsChecked, report.LimitReached},
+		},
+		{
+			name:    "Max maps generated",
+			rmcli:   &MockedRustMapsCLI{ConcurrentAllowed: 2, MonthlyAllowed: 800},
+			maxMaps: 1,
+			want:    []report.EventType{report.LimitsChecked, report.LimitReached},
+		},
+		{
+			name:  "GetLimits error",
+			rmcli: &MockedRustMapsCLI{LimitsError: true},
+			want:  []report.EventType{report.Error},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []report.EventType
+			g := NewMockedGenerator(t, &Generator{
+				config:   types.Config{QuotaReserve: tt.reserve},
+				maxMaps:  tt.maxMaps,
+				rmcli:    tt.rmcli,
+				reporter: report.Func(func(e report.Event) { got = append(got, e.Type) }),
+			})
+			// A max maps of 1 is used up
+			g.generated.Store(1)
+			g.AvailableSlots(context.Background(), zap.NewNop())
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Generator.AvailableSlots() reported %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestGenerator_GetStatus(t *testing.T) {
 	type args struct {
 		log *zap.Logger
@@ -284,7 +543,7 @@ func TestGenerator_GetStatus(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := tt.generator.GetStatus(tt.args.log, tt.args.m)
+			got, err := tt.generator.GetStatus(context.Background(), tt.args.log, tt.args.m)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Generator.GetStatus() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -306,14 +565,198 @@ func TestGenerator_SyncStatus(t *testing.T) {
 		generator *Generator
 		args      args
 		wantErr   bool
+		want      types.Map
 	}{
-		// TODO: Add test cases.
+		{
+			name: "Generating with progress",
+			generator: NewMockedGenerator(t, &Generator{
+				rmcli: &MockedRustMapsCLI{
+					Status: common.StatusGenerating,
+					Generation: &api.RustMapsGenerateResponseData{
+						QueuePosition:        2,
+						State:                "Generating",
+						CurrentStep:          "Placing monuments",
+						LastGeneratorPingUtc: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+					},
+				},
+			}),
+			args: args{
+				log: zap.NewNop(),
+				m:   &types.Map{Seed: "1", Size: 4000, MapID: "abc", Filename: "1_4000.json", Status: common.StatusGenerating},
+			},
+			want: types.Map{
+				Status:            common.StatusGenerating,
+				QueuePosition:     2,
+				State:             "Generating",
+				CurrentStep:       "Placing monuments",
+				LastGeneratorPing: "2024-01-02T03:04:05Z",
+			},
+		},
+		{
+			name: "Complete after submission",
+			generator: NewMockedGenerator(t, &Generator{
+				rmcli: &MockedRustMapsCLI{},
+			}),
+			args: args{
+				log: zap.NewNop(),
+				m: &types.Map{
+					Seed:        "1",
+					Size:        4000,
+					MapID:       "abc",
+					Filename:    "1_4000.json",
+					Status:      common.StatusGenerating,
+					SubmittedAt: time.Now().Add(-time.Minute).Format(time.RFC3339),
+				},
+			},
+			want: types.Map{Status: common.StatusComplete, GenerationSeconds: 60},
+		},
+		{
+			name: "Lost by RustMaps",
+			generator: NewMockedGenerator(t, &Generator{
+				rmcli: &MockedRustMapsCLI{Status: common.StatusNotFound},
+			}),
+			args: args{
+				log: zap.NewNop(),
+				m:   &types.Map{Seed: "1", Size: 4000, MapID: "abc", Filename: "1_4000.json", Status: common.StatusComplete},
+			},
+			want: types.Map{Status: common.StatusPending},
+		},
+		{
+			name: "Downloaded stays downloaded",
+			generator: NewMockedGenerator(t, &Generator{
+				rmcli: &MockedRustMapsCLI{},
+			}),
+			args: args{
+				log: zap.NewNop(),
+				m:   &types.Map{Seed: "1", Size: 4000, MapID: "abc", Filename: "1_4000.json", Status: common.StatusDownloaded},
+			},
+			want: types.Map{Status: common.StatusDownloaded},
+		},
+		{
+			name: "API key forbidden",
+			generator: NewMockedGenerator(t, &Generator{
+				rmcli: &MockedRustMapsCLI{Status: common.StatusForbidden},
+			}),
+			args: args{
+				log: zap.NewNop(),
+				m:   &types.Map{Seed: "1", Size: 4000, MapID: "abc", Filename: "1_4000.json", Status: common.StatusGenerating},
+			},
+			wantErr: true,
+			want:    types.Map{Status: common.StatusGenerating},
+		},
+		{
+			name: "Unexpected status",
+			generator: NewMockedGenerator(t, &Generator{
+				rmcli: &MockedRustMapsCLI{Status: common.StatusFailed},
+			}),
+			args: args{
+				log: zap.NewNop(),
+				m:   &types.Map{Seed: "1", Size: 4000, MapID: "abc", Filename: "1_4000.json", Status: common.StatusGenerating},
+			},
+			wantErr: true,
+			want:    types.Map{Status: common.StatusGenerating},
+		},
+		{
+			name: "Save error",
+			generator: NewMockedGenerator(t, &Generator{
+				rmcli:      &MockedRustMapsCLI{},
+				importsDir: filepath.Join(t.TempDir(), "missing"),
+			}),
+			args: args{
+				log: zap.NewNop(),
+				m:   &types.Map{Seed: "1", Size: 4000, MapID: "abc", Filename: "1_4000.json", Status: common.StatusGenerating},
+			},
+			wantErr: true,
+			want:    types.Map{Status: common.StatusComplete},
+		},
+		{
+			name: "GetStatus error",
+			generator: NewMockedGenerator(t, &Generator{
+				rmcli: &MockedRustMapsCLI{},
+			}),
+			args: args{
+				log: zap.NewNop(),
+				m:   &types.Map{Seed: "0", Size: 4000, Status: common.StatusGenerating},
+			},
+			wantErr: true,
+			want:    types.Map{Status: common.StatusGenerating},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := tt.generator.SyncStatus(tt.args.log, tt.args.m); (err != nil) != tt.wantErr {
+			if err := tt.generator.SyncStatus(context.Background(), tt.args.log, tt.args.m); (err != nil) != tt.wantErr {
 				t.Errorf("Generator.SyncStatus() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			m := tt.args.m
+			if m.Status != tt.want.Status || m.QueuePosition != tt.want.QueuePosition || m.State != tt.want.State ||
+				m.CurrentStep != tt.want.CurrentStep || m.LastGeneratorPing != tt.want.LastGeneratorPing {
+				t.Errorf("Generator.SyncStatus() map = %+v, want %+v", m, tt.want)
+			}
+			// allow a second either way for the clock ticking over
+			if diff := m.GenerationSeconds - tt.want.GenerationSeconds; diff < -1 || diff > 1 {
+				t.Errorf("Generator.SyncStatus() generation seconds = %d, want %d", m.GenerationSeconds, tt.want.GenerationSeconds)
+			}
+		})
+	}
+}
+
+func TestGenerator_SaveState(t *testing.T) {
+	type args struct {
+		log *zap.Logger
+	}
+	tests := []struct {
+		name      string
+		generator *Generator
+		args      args
+		wantErr   bool
+	}{
+		{
+			name: "Save state",
+			generator: NewMockedGenerator(t, &Generator{
+				maps: []*types.Map{
+					{
+						Seed:   "1",
+						Size:   4000,
+						MapID:  "123",
+						Status: common.StatusGenerating,
+					},
+				},
+			}),
+			args: args{
+				log: zap.NewNop(),
+			},
+		},
+		{
+			name: "Save state fail",
+			generator: NewMockedGenerator(t, &Generator{
+				importsDir: "/tmp/asdfjasdlf/sadf432323",
+				maps: []*types.Map{
+					{
+						Seed:   "1",
+						Size:   4000,
+						Status: common.StatusPending,
+					},
+				},
+			}),
+			args: args{
+				log: zap.NewNop(),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.generator.SaveState(tt.args.log); (err != nil) != tt.wantErr {
+				t.Errorf("Generator.SaveState() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			for _, m := range tt.generator.maps {
+				if _, err := os.Stat(filepath.Join(tt.generator.importsDir, m.Filename)); err != nil {
+					t.Errorf("Generator.SaveState() did not write %s: %v", m.Filename, err)
+				}
+			}
 		})
 	}
 }
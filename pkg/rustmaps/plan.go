@@ -0,0 +1,83 @@
+package rustmaps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/maintc/rustmaps-cli/pkg/api"
+	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/report"
+	"github.com/maintc/rustmaps-cli/pkg/types"
+	"go.uber.org/zap"
+)
+
+// Plan works out what a run would do with the loaded maps without
+// submitting or changing any of them. Maps that may need submitting are
+// looked up on RustMaps, which may already have them. With force every map
+// is considered again, like a forced run would. Only an API key RustMaps
+// rejects stops the plan, other lookup errors plan the map for submission.
+// The quota reserve and maximum maps for the run are part of the plan.
+func (g *Generator) Plan(ctx context.Context, log *zap.Logger, force bool) (report.Plan, error) {
+	plan := report.Plan{Reserve: g.quotaReserve(), MaxMaps: g.maxMaps}
+
+	if limits, err := g.rmcli.GetLimits(ctx, log); err != nil {
+		log.Warn("Error getting limits", zap.Error(err))
+	} else {
+		plan.Monthly = &report.Usage{Current: limits.Data.Monthly.Current, Allowed: limits.Data.Monthly.Allowed}
+	}
+
+	for _, m := range g.maps {
+		if !force {
+			switch {
+			case m.IsComplete():
+				plan.Add(report.NewMapRecord(m), report.ActionComplete, "")
+				continue
+			case m.IsFailed():
+				reason := m.FailureReason
+				if reason == "" {
+					reason = m.Status
+				}
+				plan.Add(report.NewMapRecord(m), report.ActionSkip, reason)
+				continue
+			}
+		}
+
+		action, reason, err := g.planLookup(ctx, log, m)
+		if err != nil {
+			return report.Plan{}, err
+		}
+		plan.Add(report.NewMapRecord(m), action, reason)
+	}
+
+	return plan, nil
+}
+
+// planLookup decides what a run would do with m from what RustMaps knows
+// about it
+func (g *Generator) planLookup(ctx context.Context, log *zap.Logger, m *types.Map) (action, reason string, err error) {
+	status, err := g.rmcli.GetStatus(ctx, log, statusRequest(m))
+	if errors.Is(err, api.ErrUnauthorized) {
+		return "", "", err
+	}
+	if err != nil {
+		log.Warn("Error getting status", zap.String("seed", m.Seed), zap.Error(err))
+		return report.ActionSubmit, fmt.Sprintf("could not check RustMaps: %v", err), nil
+	}
+
+	switch status.Meta.Status {
+	case common.StatusComplete:
+		if m.IsComplete() {
+			return report.ActionComplete, "", nil
+		}
+		return report.ActionComplete, "already on RustMaps", nil
+	case common.StatusGenerating:
+		return report.ActionWait, "", nil
+	case common.StatusNotFound:
+		if m.Status == common.StatusGenerating || m.IsComplete() {
+			return report.ActionSubmit, "lost by RustMaps", nil
+		}
+		return report.ActionSubmit, "", nil
+	}
+	return report.ActionSubmit, fmt.Sprintf("RustMaps reported %s", status.Meta.Status), nil
+}
@@ -0,0 +1,154 @@
+package rustmaps
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/maintc/rustmaps-cli/pkg/api"
+	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/report"
+	"github.com/maintc/rustmaps-cli/pkg/types"
+	"go.uber.org/zap"
+)
+
+func TestGenerator_Plan(t *testing.T) {
+	tests := []struct {
+		name        string
+		m           *types.Map
+		rmcli       *MockedRustMapsCLI
+		force       bool
+		wantErr     error
+		wantAction  string
+		wantReason  string
+		wantQuota   int
+		wantMonthly bool
+	}{
+		{
+			name:        "Complete locally",
+			m:           &types.Map{Seed: "1", Size: 4000, Status: common.StatusDownloaded},
+			rmcli:       &MockedRustMapsCLI{Status: common.StatusNotFound, MonthlyAllowed: 800},
+			wantAction:  report.ActionComplete,
+			wantMonthly: true,
+		},
+		{
+			name:        "Failed locally",
+			m:           &types.Map{Seed: "1", Size: 4000, Status: common.StatusFailed, FailureReason: "stalled"},
+			rmcli:       &MockedRustMapsCLI{Status: common.StatusNotFound, MonthlyAllowed: 800},
+			wantAction:  report.ActionSkip,
+			wantReason:  "stalled",
+			wantMonthly: true,
+		},
+		{
+			name:        "Failed locally with force",
+			m:           &types.Map{Seed: "1", Size: 4000, Status: common.StatusFailed, FailureReason: "stalled"},
+			rmcli:       &MockedRustMapsCLI{Status: common.StatusNotFound, MonthlyAllowed: 800},
+			force:       true,
+			wantAction:  report.ActionSubmit,
+			wantQuota:   1,
+			wantMonthly: true,
+		},
+		{
+			name:        "Already on RustMaps",
+			m:           types.NewMap("1", 4000, "", false),
+			rmcli:       &MockedRustMapsCLI{MonthlyAllowed: 800},
+			wantAction:  report.ActionComplete,
+			wantReason:  "already on RustMaps",
+			wantMonthly: true,
+		},
+		{
+			name:        "Already generating",
+			m:           types.NewMap("1", 4000, "", false),
+			rmcli:       &MockedRustMapsCLI{Status: common.StatusGenerating, MonthlyAllowed: 800},
+			wantAction:  report.ActionWait,
+			wantMonthly: true,
+		},
+		{
+			name:        "New map",
+			m:           types.NewMap("1", 4000, "", false),
+			rmcli:       &MockedRustMapsCLI{Status: common.StatusNotFound, MonthlyAllowed: 800},
+			wantAction:  report.ActionSubmit,
+			wantQuota:   1,
+			wantMonthly: true,
+		},
+		{
+			name:        "Lost by RustMaps",
+			m:           &types.Map{Seed: "1", Size: 4000, Status: common.StatusGenerating},
+			rmcli:       &MockedRustMapsCLI{Status: common.StatusNotFound, MonthlyAllowed: 800},
+			wantAction:  report.ActionSubmit,
+			wantReason:  "lost by RustMaps",
+			wantQuota:   1,
+			wantMonthly: true,
+		},
+		{
+			name:       "Status lookup fails",
+			m:          types.NewMap("0", 4000, "", false),
+			rmcli:      &MockedRustMapsCLI{LimitsError: true},
+			wantAction: report.ActionSubmit,
+			wantReason: "could not check RustMaps: error",
+			wantQuota:  1,
+		},
+		{
+			name:    "API key rejected",
+			m:       types.NewMap("1", 4000, "", false),
+			rmcli:   &MockedRustMapsCLI{Status: common.StatusUnauthorized},
+			wantErr: api.ErrUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewMockedGenerator(t, &Generator{maps: []*types.Map{tt.m}, rmcli: tt.rmcli})
+			status := tt.m.Status
+
+			plan, err := g.Plan(context.Background(), zap.NewNop(), tt.force)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Generator.Plan() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.m.Status != status || tt.rmcli.submitted.Load() != 0 {
+				t.Errorf("Generator.Plan() changed %s or submitted it", tt.m.String())
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if len(plan.Maps) != 1 || plan.Maps[0].Action != tt.wantAction || plan.Maps[0].Reason != tt.wantReason {
+				t.Fatalf("Generator.Plan() maps = %+v, want one to %s because %q", plan.Maps, tt.wantAction, tt.wantReason)
+			}
+			if plan.Quota != tt.wantQuota {
+				t.Errorf("Generator.Plan() quota = %d, want %d", plan.Quota, tt.wantQuota)
+			}
+			if (plan.Monthly != nil) != tt.wantMonthly {
+				t.Errorf("Generator.Plan() monthly = %+v, want it set %v", plan.Monthly, tt.wantMonthly)
+			}
+		})
+	}
+}
+
+func TestGenerator_Plan_inspected(t *testing.T) {
+	rmcli := &MockedRustMapsCLI{ConcurrentAllowed: 3, MonthlyAllowed: 800}
+	g := NewMockedGenerator(t, &Generator{rmcli: rmcli})
+	saved := types.NewMap("123", 4000, "", false)
+	saved.SetFilename()
+	saved.Status = common.StatusSubmitted
+	if err := g.mapStore().Save(saved); err != nil {
+		t.Fatalf("Store.Save() error = %v", err)
+	}
+
+	g.maps = []*types.Map{types.NewMap("123", 4000, "", false), types.NewMap("456", 4000, "", false)}
+	if err := g.Inspect(zap.NewNop()); err != nil {
+		t.Fatalf("Generator.Inspect() error = %v", err)
+	}
+	if _, err := g.Plan(context.Background(), zap.NewNop(), true); err != nil {
+		t.Fatalf("Generator.Plan() error = %v", err)
+	}
+
+	maps, err := g.QueryMaps(Query{})
+	if err != nil {
+		t.Fatalf("Generator.QueryMaps() error = %v", err)
+	}
+	if len(maps) != 1 || maps[0].Seed != "123" || maps[0].Status != common.StatusSubmitted {
+		t.Errorf("Generator.Plan() left the store with %+v, want only the map saved before", maps)
+	}
+	if len(g.locks) != 0 {
+		t.Errorf("Generator.Inspect() locked %d map(s), want none", len(g.locks))
+	}
+}
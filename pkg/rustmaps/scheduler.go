@@ -0,0 +1,74 @@
+package rustmaps
+
+import (
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/types"
+)
+
+// scheduler decides when the generator next has work to do. Every
+// generating map is polled on its own timer and submissions wait for their
+// own retry time, so a pass only runs once something is due.
+type scheduler struct {
+	// polls is when each map's status is next checked, maps without an
+	// entry are checked straight away
+	polls map[*types.Map]time.Time
+	// submitAt is the earliest time maps may be submitted again
+	submitAt time.Time
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{polls: make(map[*types.Map]time.Time)}
+}
+
+// pollDue reports whether m's status should be checked at now
+func (s *scheduler) pollDue(m *types.Map, now time.Time) bool {
+	at, ok := s.polls[m]
+	return !ok || !now.Before(at)
+}
+
+// schedulePoll checks m's status again at at
+func (s *scheduler) schedulePoll(m *types.Map, at time.Time) {
+	s.polls[m] = at
+}
+
+// submitDue reports whether maps may be submitted at now
+func (s *scheduler) submitDue(now time.Time) bool {
+	return !now.Before(s.submitAt)
+}
+
+// scheduleSubmit holds submissions back until at
+func (s *scheduler) scheduleSubmit(at time.Time) {
+	s.submitAt = at
+}
+
+// next returns how long after now the earliest work among maps is due:
+// polling a generating map, or submitting when pending is set
+func (s *scheduler) next(now time.Time, maps []*types.Map, pending bool) time.Duration {
+	var earliest time.Time
+	due := func(at time.Time) {
+		if earliest.IsZero() || at.Before(earliest) {
+			earliest = at
+		}
+	}
+
+	for _, m := range maps {
+		if m.Status != common.StatusGenerating {
+			continue
+		}
+		at, ok := s.polls[m]
+		if !ok {
+			return 0
+		}
+		due(at)
+	}
+	if pending {
+		due(s.submitAt)
+	}
+
+	if earliest.IsZero() {
+		return 0
+	}
+	return max(earliest.Sub(now), 0)
+}
@@ -0,0 +1,96 @@
+package rustmaps
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/types"
+)
+
+func Test_scheduler_pollDue(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	m := &types.Map{Status: common.StatusGenerating}
+	s := newScheduler()
+	if !s.pollDue(m, now) {
+		t.Errorf("scheduler.pollDue() = false for a map never polled, want true")
+	}
+	s.schedulePoll(m, now.Add(time.Minute))
+	if s.pollDue(m, now) {
+		t.Errorf("scheduler.pollDue() = true before the poll is due, want false")
+	}
+	if !s.pollDue(m, now.Add(time.Minute)) {
+		t.Errorf("scheduler.pollDue() = false once the poll is due, want true")
+	}
+}
+
+func Test_scheduler_next(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	generating := &types.Map{Status: common.StatusGenerating}
+	other := &types.Map{Status: common.StatusGenerating}
+	complete := &types.Map{Status: common.StatusComplete}
+	tests := []struct {
+		name     string
+		maps     []*types.Map
+		polls    map[*types.Map]time.Time
+		submitAt time.Time
+		pending  bool
+		want     time.Duration
+	}{
+		{
+			name: "Nothing scheduled",
+			maps: []*types.Map{complete},
+			want: 0,
+		},
+		{
+			name: "Map never polled",
+			maps: []*types.Map{generating},
+			want: 0,
+		},
+		{
+			name:  "Earliest poll",
+			maps:  []*types.Map{generating, other},
+			polls: map[*types.Map]time.Time{generating: now.Add(30 * time.Second), other: now.Add(10 * time.Second)},
+			want:  10 * time.Second,
+		},
+		{
+			name:     "Submission before polls",
+			maps:     []*types.Map{generating},
+			polls:    map[*types.Map]time.Time{generating: now.Add(30 * time.Second)},
+			submitAt: now.Add(2 * time.Second),
+			pending:  true,
+			want:     2 * time.Second,
+		},
+		{
+			name:     "Submission ignored without pending maps",
+			maps:     []*types.Map{generating},
+			polls:    map[*types.Map]time.Time{generating: now.Add(30 * time.Second)},
+			submitAt: now.Add(2 * time.Second),
+			want:     30 * time.Second,
+		},
+		{
+			name:  "Overdue",
+			maps:  []*types.Map{generating},
+			polls: map[*types.Map]time.Time{generating: now.Add(-time.Minute)},
+			want:  0,
+		},
+		{
+			name:  "Only generating maps are polled",
+			maps:  []*types.Map{complete, generating},
+			polls: map[*types.Map]time.Time{complete: now.Add(time.Second), generating: now.Add(time.Minute)},
+			want:  time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newScheduler()
+			for m, at := range tt.polls {
+				s.schedulePoll(m, at)
+			}
+			s.scheduleSubmit(tt.submitAt)
+			if got := s.next(now, tt.maps, tt.pending); got != tt.want {
+				t.Errorf("scheduler.next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,5 +1,12 @@
 package rustmaps
 
+import (
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/api"
+	"github.com/maintc/rustmaps-cli/pkg/report"
+)
+
 func (g *Generator) SetApiKey(apiKey string) {
 	g.config.APIKey = apiKey
 	g.rmcli.SetApiKey(apiKey)
@@ -8,3 +15,107 @@ func (g *Generator) SetApiKey(apiKey string) {
 func (g *Generator) SetTier(tier string) {
 	g.config.Tier = tier
 }
+
+// SetDownloadsDir points the generator at another downloads directory
+// without creating it, for looking up what was downloaded there.
+// OverrideDownloadsDir creates it for downloading into.
+func (g *Generator) SetDownloadsDir(dir string) {
+	g.downloadsDir = dir
+}
+
+// SetParallel caps how many maps are submitted at once, 0 means up to the
+// account's concurrent limit
+func (g *Generator) SetParallel(parallel int) {
+	g.parallel = parallel
+}
+
+// SetStallPolicy decides when a generating map is given up on: after
+// generationTimeout in total, or stallTimeout without a ping from RustMaps'
+// generator. A zero timeout disables that check. Given up maps are submitted
+// again up to maxResubmits times, then marked Failed.
+func (g *Generator) SetStallPolicy(generationTimeout, stallTimeout time.Duration, maxResubmits int) {
+	g.generationTimeout = generationTimeout
+	g.stallTimeout = stallTimeout
+	g.maxResubmits = maxResubmits
+}
+
+// SetMaxWaitForQuota stops Generate with ErrQuotaWait once it has waited
+// this long for a free slot, 0 waits forever
+func (g *Generator) SetMaxWaitForQuota(d time.Duration) {
+	g.maxWaitForQuota = d
+}
+
+// SetMaxMaps stops Generate with ErrBudgetSpent once RustMaps started
+// generating this many maps for the run, 0 means no limit
+func (g *Generator) SetMaxMaps(maxMaps int) {
+	g.maxMaps = maxMaps
+}
+
+// OverrideQuotaReserve replaces the configured quota reserve for this run
+// without saving it, 0 uses the whole monthly quota
+func (g *Generator) OverrideQuotaReserve(reserve int) {
+	g.reserve = &reserve
+}
+
+// SetStore keeps map state in s instead of the configured store, closing
+// the store in use
+func (g *Generator) SetStore(s Store) error {
+	var err error
+	if g.store != nil {
+		err = g.store.Close()
+	}
+	g.store = s
+	return err
+}
+
+// SetReporter sends progress events to r instead of printing them as text.
+// A nil r discards them.
+func (g *Generator) SetReporter(r report.Reporter) {
+	if r == nil {
+		r = report.Discard
+	}
+	g.reporter = r
+}
+
+// OverrideAPIUrl replaces the configured API URL for this run without saving it
+func (g *Generator) OverrideAPIUrl(apiUrl string) error {
+	g.overrides.APIUrl = apiUrl
+	return g.rebuildClient()
+}
+
+// OverrideRateLimit replaces the configured API rate limit for this run
+// without saving it. Non-positive values keep the configured or default value.
+func (g *Generator) OverrideRateLimit(callsPerMinute, burst int) error {
+	g.overrides.RateLimit = callsPerMinute
+	g.overrides.RateBurst = burst
+	return g.rebuildClient()
+}
+
+// OverrideHTTP replaces the configured HTTP settings for this run without
+// saving them. Zero values keep the configured or default value.
+func (g *Generator) OverrideHTTP(timeout time.Duration, userAgent, proxy, caBundle string) error {
+	g.overrides.Timeout = 0
+	if timeout > 0 {
+		g.overrides.Timeout = max(int(timeout.Round(time.Second)/time.Second), 1)
+	}
+	g.overrides.UserAgent = userAgent
+	g.overrides.Proxy = proxy
+	g.overrides.CABundle = caBundle
+	return g.rebuildClient()
+}
+
+// SetClientOptions applies opts on top of the configuration, for example to
+// inject a test transport
+func (g *Generator) SetClientOptions(opts ...api.Option) error {
+	g.clientOptions = opts
+	return g.rebuildClient()
+}
+
+func (g *Generator) rebuildClient() error {
+	client, err := g.newClient()
+	if err != nil {
+		return err
+	}
+	g.rmcli = client
+	return nil
+}
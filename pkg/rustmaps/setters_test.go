@@ -1,9 +1,14 @@
 package rustmaps
 
 import (
+	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/maintc/rustmaps-cli/pkg/api"
+	"github.com/maintc/rustmaps-cli/pkg/types"
 )
 
 func TestGenerator_SetApiKey(t *testing.T) {
@@ -64,3 +69,201 @@ func TestGenerator_SetTier(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerator_SetParallel(t *testing.T) {
+	type args struct {
+		parallel int
+	}
+	tests := []struct {
+		name      string
+		generator *Generator
+		args      args
+	}{
+		{
+			name:      "Set parallel",
+			generator: NewMockedGenerator(t, &Generator{}),
+			args: args{
+				parallel: 4,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.generator.SetParallel(tt.args.parallel)
+			if tt.generator.parallel != tt.args.parallel {
+				t.Errorf("Generator.SetParallel() = %v, want %v", tt.generator.parallel, tt.args.parallel)
+			}
+		})
+	}
+}
+
+func TestGenerator_SetDownloadsDir(t *testing.T) {
+	g := NewMockedGenerator(t, &Generator{})
+	dir := filepath.Join(t.TempDir(), "downloads")
+	g.SetDownloadsDir(dir)
+	if g.GetDownloadsDir() != dir {
+		t.Errorf("Generator.SetDownloadsDir() = %v, want %v", g.GetDownloadsDir(), dir)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("Generator.SetDownloadsDir() created %s", dir)
+	}
+}
+
+func TestGenerator_OverrideRateLimit(t *testing.T) {
+	type args struct {
+		callsPerMinute int
+		burst          int
+	}
+	tests := []struct {
+		name      string
+		generator *Generator
+		args      args
+	}{
+		{
+			name:      "Override rate limit",
+			generator: NewMockedGenerator(t, &Generator{}),
+			args: args{
+				callsPerMinute: 120,
+				burst:          5,
+			},
+		},
+		{
+			name: "Override burst only",
+			generator: NewMockedGenerator(t, &Generator{
+				config: types.Config{
+					RateLimit: 30,
+				},
+			}),
+			args: args{
+				burst: 5,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.generator.config
+			if err := tt.generator.OverrideRateLimit(tt.args.callsPerMinute, tt.args.burst); err != nil {
+				t.Errorf("Generator.OverrideRateLimit() error = %v", err)
+			}
+			if tt.generator.rmcli == nil {
+				t.Errorf("Generator.OverrideRateLimit() did not rebuild the client")
+			}
+			if tt.generator.config != config {
+				t.Errorf("Generator.OverrideRateLimit() changed the config to %v", tt.generator.config)
+			}
+		})
+	}
+}
+
+func TestGenerator_OverrideHTTP(t *testing.T) {
+	type args struct {
+		timeout   time.Duration
+		userAgent string
+		proxy     string
+		caBundle  string
+	}
+	tests := []struct {
+		name      string
+		generator *Generator
+		args      args
+		want      types.Config
+		wantErr   bool
+	}{
+		{
+			name:      "Override all",
+			generator: NewMockedGenerator(t, &Generator{}),
+			args: args{
+				timeout:   30 * time.Second,
+				userAgent: "mainloot/1.0",
+				proxy:     "http://proxy.example.com:3128",
+			},
+			want: types.Config{
+				Timeout:   30,
+				UserAgent: "mainloot/1.0",
+				Proxy:     "http://proxy.example.com:3128",
+			},
+		},
+		{
+			name: "Keep configured values",
+			generator: NewMockedGenerator(t, &Generator{
+				config: types.Config{
+					Timeout:   20,
+					UserAgent: "configured",
+				},
+			}),
+			args: args{
+				timeout: 500 * time.Millisecond,
+			},
+			want: types.Config{
+				Timeout:   1,
+				UserAgent: "configured",
+			},
+		},
+		{
+			name:      "Invalid proxy",
+			generator: NewMockedGenerator(t, &Generator{}),
+			args: args{
+				proxy: "proxy.example.com",
+			},
+			wantErr: true,
+		},
+		{
+			name:      "Missing CA bundle",
+			generator: NewMockedGenerator(t, &Generator{}),
+			args: args{
+				caBundle: filepath.Join(t.TempDir(), "missing.pem"),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.generator.config
+			err := tt.generator.OverrideHTTP(tt.args.timeout, tt.args.userAgent, tt.args.proxy, tt.args.caBundle)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Generator.OverrideHTTP() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.generator.config != config {
+				t.Errorf("Generator.OverrideHTTP() changed the config to %v", tt.generator.config)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := tt.generator.settings(); got != tt.want {
+				t.Errorf("Generator.settings() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerator_SetClientOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		generator *Generator
+		opts      []api.Option
+	}{
+		{
+			name:      "Inject HTTP client",
+			generator: NewMockedGenerator(t, &Generator{}),
+			opts: []api.Option{
+				api.WithHTTPClient(&http.Client{}),
+				api.WithBaseURL("http://localhost:8080"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.generator.SetClientOptions(tt.opts...); err != nil {
+				t.Errorf("Generator.SetClientOptions() error = %v", err)
+			}
+			client, ok := tt.generator.rmcli.(*api.RustMapsClient)
+			if !ok {
+				t.Fatalf("Generator.SetClientOptions() client = %T", tt.generator.rmcli)
+			}
+			if client.ApiUrl != "http://localhost:8080" {
+				t.Errorf("Generator.SetClientOptions() ApiUrl = %v, want %v", client.ApiUrl, "http://localhost:8080")
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package rustmaps
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/types"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema is the database at sqliteVersion. The columns are what maps
+// are queried by, state is the whole map as JSON. submitted_at is the time
+// Query.Since and Query.Until bound, see datedAt.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS maps (
+	filename     TEXT PRIMARY KEY,
+	seed         TEXT NOT NULL,
+	size         INTEGER NOT NULL,
+	saved_config TEXT NOT NULL,
+	staging      INTEGER NOT NULL,
+	map_id       TEXT NOT NULL,
+	status       TEXT NOT NULL,
+	submitted_at TEXT NOT NULL,
+	state        TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS maps_seed ON maps (seed);
+CREATE INDEX IF NOT EXISTS maps_map_id ON maps (map_id);
+CREATE INDEX IF NOT EXISTS maps_status ON maps (status);
+CREATE INDEX IF NOT EXISTS maps_submitted_at ON maps (submitted_at);
+`
+
+// sqliteVersion is the schema version, kept in the database's user_version.
+// Version 2 falls back to the completion time in submitted_at.
+const sqliteVersion = 2
+
+// SQLiteStore keeps every map in a single SQLite database, indexed for
+// queries. Several processes may use the same database.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLiteStore opens the database at path, creating it if needed
+func OpenSQLiteStore(path string) (*SQLiteStore, error) {
+	// Wait for other processes' writes instead of failing with SQLITE_BUSY
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating schema in %s: %w", path, err)
+	}
+	s := &SQLiteStore{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrating %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// migrate brings a database written by an older version up to
+// sqliteVersion
+func (s *SQLiteStore) migrate() error {
+	var version int
+	if err := s.db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	if version >= sqliteVersion {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Version 1 left submitted_at empty for maps only known to be complete
+	rows, err := tx.Query(`SELECT filename, state FROM maps WHERE submitted_at = ''`)
+	if err != nil {
+		return err
+	}
+	dated := map[string]string{}
+	for rows.Next() {
+		var filename, state string
+		if err := rows.Scan(&filename, &state); err != nil {
+			rows.Close()
+			return err
+		}
+		var m types.Map
+		if json.Unmarshal([]byte(state), &m) != nil {
+			continue
+		}
+		if at := utcTime(datedAt(&m)); at != "" {
+			dated[filename] = at
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for filename, at := range dated {
+		if _, err := tx.Exec(`UPDATE maps SET submitted_at = ? WHERE filename = ?`, at, filename); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, sqliteVersion)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Load returns the saved state of the map with the given filename
+func (s *SQLiteStore) Load(filename string) (*types.Map, error) {
+	var state string
+	err := s.db.QueryRow(`SELECT state FROM maps WHERE filename = ?`, filename).Scan(&state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var m types.Map
+	if err := json.Unmarshal([]byte(state), &m); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	return &m, nil
+}
+
+// Save inserts or replaces the map
+func (s *SQLiteStore) Save(m *types.Map) error {
+	if m.Filename == "" {
+		return fmt.Errorf("map does not have its filename set")
+	}
+	state, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to encode map to JSON: %w", err)
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO maps (filename, seed, size, saved_config, staging, map_id, status, submitted_at, state)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (filename) DO UPDATE SET
+			seed = excluded.seed,
+			size = excluded.size,
+			saved_config = excluded.saved_config,
+			staging = excluded.staging,
+			map_id = excluded.map_id,
+			status = excluded.status,
+			submitted_at = excluded.submitted_at,
+			state = excluded.state`,
+		m.Filename, m.Seed, m.Size, m.SavedConfig, m.Staging, m.MapID, m.Status, utcTime(datedAt(m)), string(state))
+	return err
+}
+
+// Query returns the saved maps q matches
+func (s *SQLiteStore) Query(q Query) ([]*types.Map, error) {
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, arg any) {
+		where = append(where, cond)
+		args = append(args, arg)
+	}
+	if q.Seed != "" {
+		add("seed = ?", q.Seed)
+	}
+	if q.Size != 0 {
+		add("size = ?", q.Size)
+	}
+	if q.SavedConfig == "procedural" {
+		add("saved_config = ?", "")
+	} else if q.SavedConfig != "" {
+		add("saved_config = ?", q.SavedConfig)
+	}
+	if q.Staging != nil {
+		add("staging = ?", *q.Staging)
+	}
+	if q.Status != "" {
+		add("status = ?", q.Status)
+	}
+	if q.MapID != "" {
+		add("map_id = ?", q.MapID)
+	}
+	if !q.Since.IsZero() || !q.Until.IsZero() {
+		where = append(where, "submitted_at != ''")
+	}
+	if !q.Since.IsZero() {
+		add("submitted_at >= ?", q.Since.UTC().Format(time.RFC3339))
+	}
+	if !q.Until.IsZero() {
+		add("submitted_at < ?", q.Until.UTC().Format(time.RFC3339))
+	}
+
+	query := `SELECT state FROM maps`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	rows, err := s.db.Query(query+` ORDER BY filename`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var maps []*types.Map
+	for rows.Next() {
+		var state string
+		if err := rows.Scan(&state); err != nil {
+			return nil, err
+		}
+		var m types.Map
+		if json.Unmarshal([]byte(state), &m) != nil {
+			continue
+		}
+		maps = append(maps, &m)
+	}
+	return maps, rows.Err()
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// utcTime converts an RFC3339 time to UTC, so stored times sort in order
+// whatever zone they were recorded in. Anything else becomes "".
+func utcTime(rfc3339 string) string {
+	t, err := time.Parse(time.RFC3339, rfc3339)
+	if err != nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
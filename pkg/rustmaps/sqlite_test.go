@@ -0,0 +1,91 @@
+package rustmaps
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/types"
+)
+
+func TestOpenSQLiteStore_reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "maps.db")
+	s, err := OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("OpenSQLiteStore() error = %v", err)
+	}
+	m := types.NewMap("1", 4000, "", false)
+	m.SetFilename()
+	if err := s.Save(m); err != nil {
+		t.Fatalf("SQLiteStore.Save() error = %v", err)
+	}
+	s.Close()
+
+	s, err = OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("OpenSQLiteStore() again error = %v", err)
+	}
+	defer s.Close()
+	if got, err := s.Load(m.Filename); err != nil || got == nil || got.Seed != "1" {
+		t.Errorf("SQLiteStore.Load() after reopening = %v, %v, want the saved map", got, err)
+	}
+}
+
+func TestOpenSQLiteStore_migrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "maps.db")
+	s, err := OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("OpenSQLiteStore() error = %v", err)
+	}
+	m := &types.Map{Seed: "1", Size: 4000, CompletedAt: "2026-09-10T10:00:00+02:00"}
+	m.SetFilename()
+	if err := s.Save(m); err != nil {
+		t.Fatalf("SQLiteStore.Save() error = %v", err)
+	}
+	// As version 1 saved it
+	if _, err := s.db.Exec(`UPDATE maps SET submitted_at = ''; PRAGMA user_version = 1`); err != nil {
+		t.Fatalf("downgrading the database: %v", err)
+	}
+	s.Close()
+
+	s, err = OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("OpenSQLiteStore() again error = %v", err)
+	}
+	defer s.Close()
+	got, err := s.Query(Query{Since: time.Date(2026, 9, 10, 0, 0, 0, 0, time.UTC)})
+	if err != nil || len(got) != 1 {
+		t.Errorf("SQLiteStore.Query() after migrating = %v, %v, want the completed map", got, err)
+	}
+}
+
+func TestSQLiteStore_Save_noFilename(t *testing.T) {
+	s, err := OpenSQLiteStore(filepath.Join(t.TempDir(), "maps.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLiteStore() error = %v", err)
+	}
+	defer s.Close()
+	if err := s.Save(types.NewMap("1", 4000, "", false)); err == nil {
+		t.Error("SQLiteStore.Save() without a filename error = nil, want an error")
+	}
+}
+
+func Test_utcTime(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "UTC", in: "2026-10-02T10:00:00Z", want: "2026-10-02T10:00:00Z"},
+		{name: "Offset", in: "2026-10-02T10:00:00+02:00", want: "2026-10-02T08:00:00Z"},
+		{name: "Empty", in: "", want: ""},
+		{name: "Invalid", in: "yesterday", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := utcTime(tt.in); got != tt.want {
+				t.Errorf("utcTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package rustmaps
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/report"
+	"github.com/maintc/rustmaps-cli/pkg/types"
+	"go.uber.org/zap"
+)
+
+// Defaults for giving up on maps that never finish generating
+const (
+	DefaultGenerationTimeout = time.Hour
+	DefaultStallTimeout      = 15 * time.Minute
+	DefaultMaxResubmits      = 2
+)
+
+// checkStalled gives up on a generating map when it has taken longer than
+// the generation timeout, or RustMaps' generator has not pinged it for the
+// stall timeout. The map is submitted again up to maxResubmits times, then
+// marked Failed.
+func (g *Generator) checkStalled(log *zap.Logger, m *types.Map) {
+	now := time.Now()
+	if m.SubmittedAt == "" {
+		// Generating since before submissions were timed, start the clock now
+		m.MarkSubmitted(now)
+		return
+	}
+
+	reason := stallReason(m, now, g.generationTimeout, g.stallTimeout)
+	if reason == "" {
+		return
+	}
+
+	if m.Resubmits >= g.maxResubmits {
+		log.Warn("Map stalled, giving up", zap.String("seed", m.Seed), zap.Int("size", m.Size), zap.String("reason", reason))
+		g.fail(m, reason)
+	} else {
+		m.Resubmits++
+		log.Warn("Map stalled, resubmitting", zap.String("seed", m.Seed), zap.Int("size", m.Size),
+			zap.String("reason", reason), zap.Int("resubmits", m.Resubmits))
+		g.reporter.Report(report.Event{Type: report.Stalled, Map: m, Message: reason})
+		// Give the new attempt a full window of its own
+		m.MarkSubmitted(now)
+		m.SetProgress(0, "", "", time.Time{})
+		g.transition(m, common.StatusPending, EventStalled)
+	}
+
+	if err := g.mapStore().Save(m); err != nil {
+		log.Error("Error saving map file", zap.Error(err))
+	}
+}
+
+// stallReason says why m should be given up on at now, empty when it
+// should not. A zero timeout disables that check.
+func stallReason(m *types.Map, now time.Time, generationTimeout, stallTimeout time.Duration) string {
+	if generationTimeout > 0 {
+		if submitted, err := time.Parse(time.RFC3339, m.SubmittedAt); err == nil && now.Sub(submitted) > generationTimeout {
+			return fmt.Sprintf("still generating after %s", generationTimeout)
+		}
+	}
+
+	// Queued maps are not being worked on, so they are not pinged
+	if stallTimeout > 0 && m.QueuePosition == 0 {
+		if ping, err := time.Parse(time.RFC3339, m.LastGeneratorPing); err == nil && now.Sub(ping) > stallTimeout {
+			return fmt.Sprintf("no word from the generator for %s", now.Sub(ping).Truncate(time.Second))
+		}
+	}
+
+	return ""
+}
+
+// fail marks m as Failed for reason
+func (g *Generator) fail(m *types.Map, reason string) {
+	m.FailureReason = reason
+	g.transition(m, common.StatusFailed, EventFailed)
+}
+
+// Failed returns the maps that were given up on or refused by RustMaps
+func (g *Generator) Failed() []*types.Map {
+	var maps []*types.Map
+	for _, m := range g.maps {
+		if m.IsFailed() {
+			maps = append(maps, m)
+		}
+	}
+	return maps
+}
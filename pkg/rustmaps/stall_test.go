@@ -0,0 +1,172 @@
+package rustmaps
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/report"
+	"github.com/maintc/rustmaps-cli/pkg/types"
+	"go.uber.org/zap"
+)
+
+func Test_stallReason(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) string { return now.Add(-d).Format(time.RFC3339) }
+
+	type args struct {
+		m                 *types.Map
+		generationTimeout time.Duration
+		stallTimeout      time.Duration
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "Within both timeouts",
+			args: args{
+				m:                 &types.Map{SubmittedAt: ago(time.Minute), LastGeneratorPing: ago(time.Second)},
+				generationTimeout: time.Hour,
+				stallTimeout:      time.Minute,
+			},
+			want: "",
+		},
+		{
+			name: "Generation timeout",
+			args: args{
+				m:                 &types.Map{SubmittedAt: ago(2 * time.Hour), LastGeneratorPing: ago(time.Second)},
+				generationTimeout: time.Hour,
+				stallTimeout:      time.Minute,
+			},
+			want: "still generating after 1h0m0s",
+		},
+		{
+			name: "No ping",
+			args: args{
+				m:                 &types.Map{SubmittedAt: ago(10 * time.Minute), LastGeneratorPing: ago(5 * time.Minute)},
+				generationTimeout: time.Hour,
+				stallTimeout:      time.Minute,
+			},
+			want: "no word from the generator for 5m0s",
+		},
+		{
+			name: "Queued maps are not pinged",
+			args: args{
+				m:                 &types.Map{SubmittedAt: ago(10 * time.Minute), LastGeneratorPing: ago(5 * time.Minute), QueuePosition: 4},
+				generationTimeout: time.Hour,
+				stallTimeout:      time.Minute,
+			},
+			want: "",
+		},
+		{
+			name: "Timeouts disabled",
+			args: args{
+				m: &types.Map{SubmittedAt: ago(48 * time.Hour), LastGeneratorPing: ago(48 * time.Hour)},
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stallReason(tt.args.m, now, tt.args.generationTimeout, tt.args.stallTimeout); got != tt.want {
+				t.Errorf("stallReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerator_checkStalled(t *testing.T) {
+	ago := func(d time.Duration) string { return time.Now().Add(-d).Format(time.RFC3339) }
+
+	tests := []struct {
+		name          string
+		m             *types.Map
+		maxResubmits  int
+		wantStatus    string
+		wantResubmits int
+		wantReason    bool
+		wantEvents    []report.EventType
+	}{
+		{
+			name:       "Still within the timeout",
+			m:          &types.Map{Status: common.StatusGenerating, SubmittedAt: ago(time.Minute)},
+			wantStatus: common.StatusGenerating,
+		},
+		{
+			name:       "Untimed maps start their clock",
+			m:          &types.Map{Status: common.StatusGenerating},
+			wantStatus: common.StatusGenerating,
+		},
+		{
+			name:          "Resubmitted",
+			m:             &types.Map{Status: common.StatusGenerating, SubmittedAt: ago(2 * time.Hour), CurrentStep: "Placing monuments"},
+			maxResubmits:  1,
+			wantStatus:    common.StatusPending,
+			wantResubmits: 1,
+			wantEvents:    []report.EventType{report.Stalled, report.MapStatus},
+		},
+		{
+			name:          "Failed after the last resubmit",
+			m:             &types.Map{Status: common.StatusGenerating, SubmittedAt: ago(2 * time.Hour), Resubmits: 1},
+			maxResubmits:  1,
+			wantStatus:    common.StatusFailed,
+			wantResubmits: 1,
+			wantReason:    true,
+			wantEvents:    []report.EventType{report.MapStatus},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []report.EventType
+			g := NewMockedGenerator(t, &Generator{
+				generationTimeout: time.Hour,
+				maxResubmits:      tt.maxResubmits,
+				reporter:          report.Func(func(e report.Event) { events = append(events, e.Type) }),
+			})
+			tt.m.Seed, tt.m.Size, tt.m.Filename = "1", 4000, "1_4000.json"
+
+			g.checkStalled(zap.NewNop(), tt.m)
+
+			if tt.m.Status != tt.wantStatus {
+				t.Errorf("Generator.checkStalled() status = %v, want %v", tt.m.Status, tt.wantStatus)
+			}
+			if tt.m.Resubmits != tt.wantResubmits {
+				t.Errorf("Generator.checkStalled() resubmits = %v, want %v", tt.m.Resubmits, tt.wantResubmits)
+			}
+			if (tt.m.FailureReason != "") != tt.wantReason {
+				t.Errorf("Generator.checkStalled() reason = %q, want one %v", tt.m.FailureReason, tt.wantReason)
+			}
+			if tt.m.SubmittedAt == "" {
+				t.Errorf("Generator.checkStalled() left the map untimed")
+			}
+			if len(events) != len(tt.wantEvents) {
+				t.Fatalf("Generator.checkStalled() reported %v, want %v", events, tt.wantEvents)
+			}
+			for i := range events {
+				if events[i] != tt.wantEvents[i] {
+					t.Errorf("Generator.checkStalled() reported %v, want %v", events, tt.wantEvents)
+				}
+			}
+			if tt.wantStatus == common.StatusPending && tt.m.CurrentStep != "" {
+				t.Errorf("Generator.checkStalled() kept the old progress %q", tt.m.CurrentStep)
+			}
+		})
+	}
+}
+
+func TestGenerator_Failed(t *testing.T) {
+	failed := &types.Map{Seed: "1", Status: common.StatusFailed}
+	g := NewMockedGenerator(t, &Generator{
+		maps: []*types.Map{
+			{Seed: "2", Status: common.StatusComplete},
+			failed,
+			{Seed: "3", Status: common.StatusGenerating},
+		},
+	})
+	got := g.Failed()
+	if len(got) != 1 || got[0] != failed {
+		t.Errorf("Generator.Failed() = %v, want [%v]", got, failed)
+	}
+}
@@ -0,0 +1,140 @@
+package rustmaps
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/types"
+)
+
+// Events that move a map between states, recorded in its history
+const (
+	// EventSubmit is sent as a map is handed to RustMaps
+	EventSubmit = "submit"
+	// EventAccepted is sent when RustMaps queued a submitted map, or was
+	// already generating it
+	EventAccepted = "accepted"
+	// EventExists is sent when RustMaps already had a submitted map
+	EventExists = "exists"
+	// EventRejected is sent when RustMaps turned a submission away
+	EventRejected = "rejected"
+	// EventRetry is sent when a submission did not get through and the map
+	// waits to be submitted again
+	EventRetry = "retry"
+	// EventProgress is sent when a generating map is still generating, it
+	// only refreshes the progress and is never recorded
+	EventProgress = "progress"
+	// EventCompleted is sent when RustMaps finished generating a map
+	EventCompleted = "completed"
+	// EventStalled is sent when a generating map is given up on and will be
+	// submitted again
+	EventStalled = "stalled"
+	// EventFailed is sent when a map is given up on for good
+	EventFailed = "failed"
+	// EventLost is sent when RustMaps no longer knows a map it had
+	EventLost = "lost"
+	// EventDownloaded is sent once a complete map's files are downloaded
+	EventDownloaded = "downloaded"
+	// EventReset is sent when a map is started over, whatever its state
+	EventReset = "reset"
+)
+
+// ErrIllegalTransition is returned for a status change the state machine
+// does not allow
+var ErrIllegalTransition = errors.New("illegal transition")
+
+// transitions lists the states each state may move to. Any state may move
+// to itself, and to Pending through Reset.
+var transitions = map[string][]string{
+	common.StatusPending:     {common.StatusSubmitted},
+	common.StatusRateLimited: {common.StatusSubmitted},
+	common.StatusSubmitted: {
+		common.StatusGenerating,
+		common.StatusComplete,
+		common.StatusPending,
+		common.StatusRateLimited,
+		common.StatusStagingNotEnabled,
+		common.StatusBadRequest,
+		common.StatusUnauthorized,
+		common.StatusForbidden,
+	},
+	common.StatusGenerating: {common.StatusComplete, common.StatusFailed, common.StatusPending},
+	common.StatusComplete:   {common.StatusDownloaded, common.StatusPending},
+	common.StatusDownloaded: {common.StatusPending},
+}
+
+// TransitionHook is called after a map changed state
+type TransitionHook func(m *types.Map, t types.Transition)
+
+// StateMachine moves maps between states, only along legal transitions,
+// recording each change in the map's history and calling the hooks
+type StateMachine struct {
+	hooks []TransitionHook
+	now   func() time.Time
+}
+
+// NewStateMachine creates a StateMachine without hooks
+func NewStateMachine() *StateMachine {
+	return &StateMachine{now: time.Now}
+}
+
+// OnTransition adds a hook called after every change of state. Hooks must
+// be added before the machine is used and be safe for concurrent use.
+func (s *StateMachine) OnTransition(hook TransitionHook) {
+	s.hooks = append(s.hooks, hook)
+}
+
+// CanTransition reports whether a map may move from one state to another
+func CanTransition(from, to string) bool {
+	from = normalizeStatus(from)
+	if from == to {
+		return true
+	}
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition moves m to the state to because of event. Moving to the
+// current state only marks the map as synced. An illegal transition leaves
+// m untouched and returns ErrIllegalTransition.
+func (s *StateMachine) Transition(m *types.Map, to, event string) error {
+	if !CanTransition(m.Status, to) {
+		return fmt.Errorf("%w from %q to %q on %s", ErrIllegalTransition, m.Status, to, event)
+	}
+	s.apply(m, to, event)
+	return nil
+}
+
+// Reset moves m back to Pending from any state so it is submitted again
+func (s *StateMachine) Reset(m *types.Map) {
+	s.apply(m, common.StatusPending, EventReset)
+}
+
+func (s *StateMachine) apply(m *types.Map, to, event string) {
+	from := normalizeStatus(m.Status)
+	m.SetStatus(to)
+	if from == to {
+		return
+	}
+
+	t := types.Transition{From: from, To: to, Event: event, At: s.now().Format(time.RFC3339)}
+	m.RecordTransition(t)
+	for _, hook := range s.hooks {
+		hook(m, t)
+	}
+}
+
+// normalizeStatus treats maps without a status as Pending, like maps
+// loaded from a CSV without a status column
+func normalizeStatus(status string) string {
+	if status == "" {
+		return common.StatusPending
+	}
+	return status
+}
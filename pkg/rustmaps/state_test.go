@@ -0,0 +1,263 @@
+package rustmaps
+
+import (
+	"errors"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/types"
+)
+
+// allStatuses is every status a map can have
+var allStatuses = []string{
+	common.StatusPending,
+	common.StatusSubmitted,
+	common.StatusGenerating,
+	common.StatusComplete,
+	common.StatusDownloaded,
+	common.StatusFailed,
+	common.StatusRateLimited,
+	common.StatusStagingNotEnabled,
+	common.StatusBadRequest,
+	common.StatusUnauthorized,
+	common.StatusForbidden,
+	common.StatusNotFound,
+}
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+		want bool
+	}{
+		{from: common.StatusPending, to: common.StatusSubmitted, want: true},
+		{from: "", to: common.StatusSubmitted, want: true},
+		{from: common.StatusRateLimited, to: common.StatusSubmitted, want: true},
+		{from: common.StatusSubmitted, to: common.StatusGenerating, want: true},
+		{from: common.StatusSubmitted, to: common.StatusComplete, want: true},
+		{from: common.StatusSubmitted, to: common.StatusBadRequest, want: true},
+		{from: common.StatusGenerating, to: common.StatusComplete, want: true},
+		{from: common.StatusGenerating, to: common.StatusFailed, want: true},
+		{from: common.StatusGenerating, to: common.StatusPending, want: true},
+		{from: common.StatusComplete, to: common.StatusDownloaded, want: true},
+		{from: common.StatusGenerating, to: common.StatusGenerating, want: true},
+		{from: common.StatusPending, to: common.StatusGenerating, want: false},
+		{from: common.StatusPending, to: common.StatusComplete, want: false},
+		{from: common.StatusGenerating, to: common.StatusDownloaded, want: false},
+		{from: common.StatusComplete, to: common.StatusGenerating, want: false},
+		{from: common.StatusFailed, to: common.StatusSubmitted, want: false},
+		{from: common.StatusDownloaded, to: common.StatusComplete, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from+" to "+tt.to, func(t *testing.T) {
+			if got := CanTransition(tt.from, tt.to); got != tt.want {
+				t.Errorf("CanTransition(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStateMachine_Transition(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name        string
+		status      string
+		to          string
+		event       string
+		wantErr     error
+		wantStatus  string
+		wantHistory []types.Transition
+	}{
+		{
+			name:       "Legal",
+			status:     common.StatusPending,
+			to:         common.StatusSubmitted,
+			event:      EventSubmit,
+			wantStatus: common.StatusSubmitted,
+			wantHistory: []types.Transition{
+				{From: common.StatusPending, To: common.StatusSubmitted, Event: EventSubmit, At: "2024-01-02T03:04:05Z"},
+			},
+		},
+		{
+			name:       "No status counts as Pending",
+			status:     "",
+			to:         common.StatusSubmitted,
+			event:      EventSubmit,
+			wantStatus: common.StatusSubmitted,
+			wantHistory: []types.Transition{
+				{From: common.StatusPending, To: common.StatusSubmitted, Event: EventSubmit, At: "2024-01-02T03:04:05Z"},
+			},
+		},
+		{
+			name:       "Same state is not recorded",
+			status:     common.StatusGenerating,
+			to:         common.StatusGenerating,
+			event:      EventProgress,
+			wantStatus: common.StatusGenerating,
+		},
+		{
+			name:       "Illegal",
+			status:     common.StatusPending,
+			to:         common.StatusComplete,
+			event:      EventCompleted,
+			wantErr:    ErrIllegalTransition,
+			wantStatus: common.StatusPending,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := NewStateMachine()
+			sm.now = func() time.Time { return at }
+			var hooked []types.Transition
+			sm.OnTransition(func(m *types.Map, t types.Transition) { hooked = append(hooked, t) })
+
+			m := &types.Map{Status: tt.status}
+			if err := sm.Transition(m, tt.to, tt.event); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("StateMachine.Transition() error = %v, want %v", err, tt.wantErr)
+			}
+			if m.Status != tt.wantStatus {
+				t.Errorf("StateMachine.Transition() status = %v, want %v", m.Status, tt.wantStatus)
+			}
+			if len(m.History) != len(tt.wantHistory) || len(hooked) != len(tt.wantHistory) {
+				t.Fatalf("StateMachine.Transition() history = %+v, hooks got %+v, want %+v", m.History, hooked, tt.wantHistory)
+			}
+			for i := range tt.wantHistory {
+				if m.History[i] != tt.wantHistory[i] || hooked[i] != tt.wantHistory[i] {
+					t.Errorf("StateMachine.Transition() history[%d] = %+v, hook got %+v, want %+v", i, m.History[i], hooked[i], tt.wantHistory[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStateMachine_Reset(t *testing.T) {
+	for _, status := range allStatuses {
+		t.Run(status, func(t *testing.T) {
+			sm := NewStateMachine()
+			m := &types.Map{Status: status}
+			sm.Reset(m)
+			if m.Status != common.StatusPending {
+				t.Errorf("StateMachine.Reset() status = %v, want %v", m.Status, common.StatusPending)
+			}
+			if status != common.StatusPending && (len(m.History) != 1 || m.History[0].Event != EventReset) {
+				t.Errorf("StateMachine.Reset() history = %+v, want a single reset", m.History)
+			}
+		})
+	}
+}
+
+// step is a transition a property test attempts, picked from quick's
+// random bytes
+type step struct {
+	To    uint8
+	Reset bool
+}
+
+// TestStateMachine_properties drives maps through random sequences of
+// transitions and checks what must always hold
+func TestStateMachine_properties(t *testing.T) {
+	property := func(steps []step) bool {
+		sm := NewStateMachine()
+		hooked := 0
+		sm.OnTransition(func(m *types.Map, t types.Transition) { hooked++ })
+
+		m := types.NewMap("1", 4000, "", false)
+		for _, s := range steps {
+			before := m.Status
+			history := len(m.History)
+			to := allStatuses[int(s.To)%len(allStatuses)]
+
+			if s.Reset {
+				sm.Reset(m)
+				if m.Status != common.StatusPending {
+					t.Logf("Reset from %s left %s", before, m.Status)
+					return false
+				}
+				continue
+			}
+
+			err := sm.Transition(m, to, "test")
+			if legal := CanTransition(before, to); legal != (err == nil) {
+				t.Logf("%s to %s: legal %v but error %v", before, to, legal, err)
+				return false
+			}
+			if err != nil && (m.Status != before || len(m.History) != history) {
+				t.Logf("Illegal %s to %s changed the map to %s", before, to, m.Status)
+				return false
+			}
+		}
+
+		// The history is a chain of legal moves ending in the current state
+		if len(m.History) > types.MaxHistory {
+			t.Logf("History grew to %d entries", len(m.History))
+			return false
+		}
+		for i, h := range m.History {
+			if h.From == h.To {
+				t.Logf("History[%d] stays in %s", i, h.From)
+				return false
+			}
+			if h.Event != EventReset && !CanTransition(h.From, h.To) {
+				t.Logf("History[%d] %s to %s is illegal", i, h.From, h.To)
+				return false
+			}
+			if i > 0 && m.History[i-1].To != h.From {
+				t.Logf("History[%d] starts at %s after %s", i, h.From, m.History[i-1].To)
+				return false
+			}
+		}
+		if n := len(m.History); n > 0 && m.History[n-1].To != m.Status {
+			t.Logf("History ends at %s but the map is %s", m.History[n-1].To, m.Status)
+			return false
+		}
+		if hooked < len(m.History) || (len(m.History) < types.MaxHistory && hooked != len(m.History)) {
+			t.Logf("Hooks ran %d times for %d recorded transitions", hooked, len(m.History))
+			return false
+		}
+		return true
+	}
+
+	if err := quick.Check(property, &quick.Config{MaxCount: 500}); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestStateMachine_reachable checks every state can be reached from
+// Pending, and every state but the ones needing a reset can still lead to
+// a complete map
+func TestStateMachine_reachable(t *testing.T) {
+	reachable := func(from string) map[string]bool {
+		seen := map[string]bool{from: true}
+		queue := []string{from}
+		for len(queue) > 0 {
+			next := queue[0]
+			queue = queue[1:]
+			for _, to := range allStatuses {
+				if !seen[to] && CanTransition(next, to) {
+					seen[to] = true
+					queue = append(queue, to)
+				}
+			}
+		}
+		return seen
+	}
+
+	fromPending := reachable(common.StatusPending)
+	for _, status := range allStatuses {
+		if status == common.StatusNotFound {
+			// Only reported by RustMaps, never a map's state
+			continue
+		}
+		if !fromPending[status] {
+			t.Errorf("%s cannot be reached from %s", status, common.StatusPending)
+		}
+	}
+
+	for _, status := range []string{common.StatusPending, common.StatusSubmitted, common.StatusGenerating, common.StatusRateLimited} {
+		if !reachable(status)[common.StatusDownloaded] {
+			t.Errorf("%s cannot lead to %s", status, common.StatusDownloaded)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package rustmaps
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/maintc/rustmaps-cli/pkg/api"
+	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/types"
+	"go.uber.org/zap"
+)
+
+// Refresh asks RustMaps about m and records the answer in m's saved state,
+// as far as the state machine allows: a Pending map that RustMaps already
+// has keeps its status until generate submits it. Only saved maps that
+// Import or ImportShared locked are saved again, maps only known by their
+// map ID are filled in from the answer. It returns the full status, or the *api.Error
+// matching api.ErrUnauthorized when RustMaps rejected the API key.
+func (g *Generator) Refresh(ctx context.Context, log *zap.Logger, m *types.Map) (*api.RustMapsStatusResponse, error) {
+	status, err := g.GetStatus(ctx, log, m)
+	if err != nil {
+		return nil, err
+	}
+
+	if d := status.Data; d.ID != "" {
+		m.MapID = d.ID
+		if m.Seed == "" {
+			m.Seed = strconv.Itoa(d.Seed)
+			m.Size = d.Size
+			m.Staging = d.IsStaging
+		}
+	}
+	if status.Generation != nil {
+		recordProgress(m, *status.Generation)
+	}
+	if to, event := refreshedStatus(m, status.Meta.Status); to != "" && CanTransition(m.Status, to) {
+		g.transition(m, to, event)
+	}
+
+	if !g.locked(m) {
+		// Another process owns the saved state
+		return status, nil
+	}
+	if err := g.mapStore().Save(m); err != nil {
+		log.Error("Error saving map", zap.Error(err), zap.String("map", m.String()))
+		return status, err
+	}
+	return status, nil
+}
+
+// refreshedStatus is the status m moves to because RustMaps reported
+// remote, empty when m stays as it is
+func refreshedStatus(m *types.Map, remote string) (status, event string) {
+	switch remote {
+	case common.StatusGenerating:
+		return common.StatusGenerating, EventProgress
+	case common.StatusComplete:
+		if m.Status == common.StatusDownloaded {
+			return common.StatusDownloaded, EventCompleted
+		}
+		return common.StatusComplete, EventCompleted
+	case common.StatusNotFound:
+		if m.Status == common.StatusGenerating || m.IsComplete() {
+			return common.StatusPending, EventLost
+		}
+	}
+	return "", ""
+}
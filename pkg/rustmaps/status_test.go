@@ -0,0 +1,178 @@
+package rustmaps
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maintc/rustmaps-cli/pkg/api"
+	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/fsutil"
+	"github.com/maintc/rustmaps-cli/pkg/types"
+	"go.uber.org/zap"
+)
+
+func TestGenerator_Refresh(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     string
+		rmcli      *MockedRustMapsCLI
+		wantErr    error
+		wantStatus string
+		wantMapID  string
+	}{
+		{
+			name:       "Generating completes",
+			status:     common.StatusGenerating,
+			rmcli:      &MockedRustMapsCLI{MapID: "abc"},
+			wantStatus: common.StatusComplete,
+			wantMapID:  "abc",
+		},
+		{
+			name:       "Downloaded stays downloaded",
+			status:     common.StatusDownloaded,
+			rmcli:      &MockedRustMapsCLI{},
+			wantStatus: common.StatusDownloaded,
+		},
+		{
+			name:       "Still generating",
+			status:     common.StatusGenerating,
+			rmcli:      &MockedRustMapsCLI{Status: common.StatusGenerating},
+			wantStatus: common.StatusGenerating,
+		},
+		{
+			name:       "Pending stays pending when RustMaps has it",
+			status:     common.StatusPending,
+			rmcli:      &MockedRustMapsCLI{MapID: "abc"},
+			wantStatus: common.StatusPending,
+			wantMapID:  "abc",
+		},
+		{
+			name:       "Lost by RustMaps",
+			status:     common.StatusComplete,
+			rmcli:      &MockedRustMapsCLI{Status: common.StatusNotFound},
+			wantStatus: common.StatusPending,
+		},
+		{
+			name:       "Failed is left alone",
+			status:     common.StatusFailed,
+			rmcli:      &MockedRustMapsCLI{Status: common.StatusNotFound},
+			wantStatus: common.StatusFailed,
+		},
+		{
+			name:       "API key rejected",
+			status:     common.StatusGenerating,
+			rmcli:      &MockedRustMapsCLI{Status: common.StatusUnauthorized},
+			wantErr:    api.ErrUnauthorized,
+			wantStatus: common.StatusGenerating,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &types.Map{Seed: "1", Size: 4000, Status: tt.status}
+			g := NewMockedGenerator(t, &Generator{rmcli: tt.rmcli})
+			g.AddMap(m)
+			if err := g.mapStore().Save(m); err != nil {
+				t.Fatalf("Store.Save() error = %v", err)
+			}
+			if err := g.ImportShared(zap.NewNop()); err != nil {
+				t.Fatalf("Generator.ImportShared() error = %v", err)
+			}
+
+			status, err := g.Refresh(context.Background(), zap.NewNop(), m)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Generator.Refresh() error = %v, want %v", err, tt.wantErr)
+			}
+			if status == nil && tt.wantErr == nil {
+				t.Fatal("Generator.Refresh() status = nil")
+			}
+			if m.Status != tt.wantStatus || m.MapID != tt.wantMapID {
+				t.Errorf("Generator.Refresh() map = %s %q, want %s %q", m.Status, m.MapID, tt.wantStatus, tt.wantMapID)
+			}
+
+			saved, err := g.mapStore().Load(m.Filename)
+			if err != nil {
+				t.Fatalf("Store.Load() error = %v", err)
+			}
+			if saved == nil || saved.Status != tt.wantStatus || saved.MapID != tt.wantMapID {
+				t.Errorf("Generator.Refresh() saved %+v, want %s %q", saved, tt.wantStatus, tt.wantMapID)
+			}
+		})
+	}
+}
+
+func TestGenerator_Refresh_unsaved(t *testing.T) {
+	m := types.NewMap("99", 4000, "", false)
+	g := NewMockedGenerator(t, &Generator{rmcli: &MockedRustMapsCLI{Status: common.StatusComplete}})
+	g.AddMap(m)
+	if err := g.ImportShared(zap.NewNop()); err != nil {
+		t.Fatalf("Generator.ImportShared() error = %v", err)
+	}
+
+	if _, err := g.Refresh(context.Background(), zap.NewNop(), m); err != nil {
+		t.Fatalf("Generator.Refresh() error = %v", err)
+	}
+	if m.Status != "" {
+		t.Errorf("Generator.Refresh() status = %q, want none for an untracked map", m.Status)
+	}
+	if maps, _ := g.QueryMaps(Query{}); len(maps) != 0 {
+		t.Errorf("Generator.Refresh() saved %d map(s), want none", len(maps))
+	}
+	if len(g.locks) != 0 {
+		t.Errorf("Generator.ImportShared() locked %d map(s), want none", len(g.locks))
+	}
+}
+
+func TestGenerator_Refresh_byMapID(t *testing.T) {
+	m := &types.Map{MapID: "abc"}
+	g := NewMockedGenerator(t, &Generator{rmcli: &MockedRustMapsCLI{MapID: "abc"}})
+
+	if _, err := g.Refresh(context.Background(), zap.NewNop(), m); err != nil {
+		t.Fatalf("Generator.Refresh() error = %v", err)
+	}
+	if m.Seed != "0" || m.Filename != "" {
+		t.Errorf("Generator.Refresh() map = %+v, want the seed filled in and no file", m)
+	}
+	if maps, _ := g.QueryMaps(Query{}); len(maps) != 0 {
+		t.Errorf("Generator.Refresh() saved %d map(s), want none", len(maps))
+	}
+}
+
+func TestGenerator_Refresh_locked(t *testing.T) {
+	dir := t.TempDir()
+	g := NewMockedGenerator(t, &Generator{importsDir: dir, rmcli: &MockedRustMapsCLI{MapID: "abc"}})
+	saved := &types.Map{Seed: "1", Size: 4000, Status: common.StatusGenerating}
+	saved.SetFilename()
+	if err := g.mapStore().Save(saved); err != nil {
+		t.Fatalf("Store.Save() error = %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, ".locks"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	lock, err := fsutil.TryLock(filepath.Join(dir, ".locks", saved.Filename+".lock"))
+	if err != nil {
+		t.Fatalf("fsutil.TryLock() error = %v", err)
+	}
+	defer lock.Unlock()
+
+	m := types.NewMap("1", 4000, "", false)
+	g.AddMap(m)
+	if err := g.ImportShared(zap.NewNop()); err != nil {
+		t.Fatalf("Generator.ImportShared() of a locked map error = %v", err)
+	}
+	if m.Status != common.StatusGenerating {
+		t.Fatalf("Generator.ImportShared() status = %v, want the saved %v", m.Status, common.StatusGenerating)
+	}
+
+	if _, err := g.Refresh(context.Background(), zap.NewNop(), m); err != nil {
+		t.Fatalf("Generator.Refresh() error = %v", err)
+	}
+	if m.Status != common.StatusComplete {
+		t.Errorf("Generator.Refresh() status = %v, want %v", m.Status, common.StatusComplete)
+	}
+	if got, _ := g.mapStore().Load(m.Filename); got == nil || got.Status != common.StatusGenerating || got.MapID != "" {
+		t.Errorf("Generator.Refresh() saved %+v over the state of a locked map", got)
+	}
+}
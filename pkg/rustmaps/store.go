@@ -0,0 +1,240 @@
+package rustmaps
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/fsutil"
+	"github.com/maintc/rustmaps-cli/pkg/types"
+)
+
+// Map stores, set with the store config key
+const (
+	// StoreJSON keeps one JSON file per map in the imports directory
+	StoreJSON = "json"
+	// StoreSQLite keeps every map in a single SQLite database
+	StoreSQLite = "sqlite"
+)
+
+// ErrCorrupt is returned by Store.Load when a map's saved state cannot be
+// decoded
+var ErrCorrupt = errors.New("saved state is corrupt")
+
+// Store keeps the state of every map between runs, keyed by the map's
+// filename (see types.Map.SetFilename)
+type Store interface {
+	// Load returns the saved state of the map with the given filename, nil
+	// when there is none
+	Load(filename string) (*types.Map, error)
+	// Save writes the state of m, replacing what was saved before
+	Save(m *types.Map) error
+	// Query returns the saved maps q matches, ordered by filename
+	Query(q Query) ([]*types.Map, error)
+	Close() error
+}
+
+// Query selects saved maps. Zero fields match every map.
+type Query struct {
+	Seed string
+	Size int
+	// SavedConfig matches maps generated with that saved config,
+	// "procedural" matches maps generated without one
+	SavedConfig string
+	// Staging matches maps for the staging branch or not, when set
+	Staging *bool
+	Status  string
+	MapID   string
+	// Since and Until bound when the map was last submitted, or when it
+	// completed for maps saved without a submission time. Until is
+	// exclusive. Maps with neither time do not match either.
+	Since time.Time
+	Until time.Time
+}
+
+// Match reports whether q selects m
+func (q Query) Match(m *types.Map) bool {
+	switch {
+	case q.Seed != "" && m.Seed != q.Seed,
+		q.Size != 0 && m.Size != q.Size,
+		q.SavedConfig != "" && savedConfigName(m.SavedConfig) != q.SavedConfig,
+		q.Staging != nil && m.Staging != *q.Staging,
+		q.Status != "" && m.Status != q.Status,
+		q.MapID != "" && m.MapID != q.MapID:
+		return false
+	}
+	if q.Since.IsZero() && q.Until.IsZero() {
+		return true
+	}
+	dated, err := time.Parse(time.RFC3339, datedAt(m))
+	if err != nil {
+		return false
+	}
+	return (q.Since.IsZero() || !dated.Before(q.Since)) && (q.Until.IsZero() || dated.Before(q.Until))
+}
+
+// datedAt is the time Query.Since and Query.Until bound: when m was last
+// submitted, or when it completed if only that was recorded
+func datedAt(m *types.Map) string {
+	if m.SubmittedAt != "" {
+		return m.SubmittedAt
+	}
+	return m.CompletedAt
+}
+
+// savedConfigName is how a map's saved config is named in queries
+func savedConfigName(savedConfig string) string {
+	if savedConfig == "" {
+		return "procedural"
+	}
+	return savedConfig
+}
+
+// JSONStore keeps each map in its own JSON file in a directory, written
+// atomically. It has no index, queries read every file.
+type JSONStore struct {
+	dir string
+}
+
+// NewJSONStore returns a store for the map files in dir
+func NewJSONStore(dir string) *JSONStore {
+	return &JSONStore{dir: dir}
+}
+
+// Load reads the map's file. A file that cannot be decoded is quarantined
+// and ErrCorrupt returned, the map can then be saved again from scratch.
+func (s *JSONStore) Load(filename string) (*types.Map, error) {
+	path := filepath.Join(s.dir, filename)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var m types.Map
+	if err := json.Unmarshal(data, &m); err != nil {
+		quarantined, qerr := fsutil.Quarantine(path)
+		if qerr != nil {
+			return nil, fmt.Errorf("quarantining %s: %w", path, qerr)
+		}
+		return nil, fmt.Errorf("%w, moved it to %s: %v", ErrCorrupt, quarantined, err)
+	}
+	return &m, nil
+}
+
+// Save writes m to its file
+func (s *JSONStore) Save(m *types.Map) error {
+	return m.SaveJSON(s.dir)
+}
+
+// Query reads every map file and returns the maps q matches. Files that
+// cannot be decoded are skipped, Load quarantines them.
+func (s *JSONStore) Query(q Query) ([]*types.Map, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var maps []*types.Map
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var m types.Map
+		if json.Unmarshal(data, &m) != nil {
+			continue
+		}
+		if m.Filename == "" {
+			m.Filename = e.Name()
+		}
+		if q.Match(&m) {
+			maps = append(maps, &m)
+		}
+	}
+	sort.Slice(maps, func(i, j int) bool { return maps[i].Filename < maps[j].Filename })
+	return maps, nil
+}
+
+// Close does nothing, files are written as they are saved
+func (s *JSONStore) Close() error {
+	return nil
+}
+
+// mapStore returns the store in use, the imports directory unless another
+// store was configured or set
+func (g *Generator) mapStore() Store {
+	if g.store == nil {
+		return NewJSONStore(g.importsDir)
+	}
+	return g.store
+}
+
+// openStore switches to the store the configuration names
+func (g *Generator) openStore() error {
+	switch g.config.Store {
+	case "", StoreJSON:
+		return g.SetStore(NewJSONStore(g.importsDir))
+	case StoreSQLite:
+		s, err := OpenSQLiteStore(g.GetDatabasePath())
+		if err != nil {
+			return err
+		}
+		return g.SetStore(s)
+	}
+	return fmt.Errorf("unknown store %q, use %q or %q", g.config.Store, StoreJSON, StoreSQLite)
+}
+
+// QueryMaps returns the saved maps q matches, from whichever store is in
+// use
+func (g *Generator) QueryMaps(q Query) ([]*types.Map, error) {
+	return g.mapStore().Query(q)
+}
+
+// MigrateToSQLite copies every map in the imports directory into the
+// SQLite database and switches the configuration to it, returning how many
+// maps were copied. The JSON files are left in place, running it again
+// copies them again.
+func (g *Generator) MigrateToSQLite() (int, error) {
+	db, err := OpenSQLiteStore(g.GetDatabasePath())
+	if err != nil {
+		return 0, err
+	}
+	n, err := Migrate(NewJSONStore(g.importsDir), db)
+	if err != nil {
+		db.Close()
+		return n, err
+	}
+
+	g.config.Store = StoreSQLite
+	if err := g.SaveConfig(); err != nil {
+		db.Close()
+		return n, err
+	}
+	return n, g.SetStore(db)
+}
+
+// Migrate copies every map saved in from into to, replacing any already
+// there, and returns how many it copied. from is left as it was.
+func Migrate(from, to Store) (int, error) {
+	maps, err := from.Query(Query{})
+	if err != nil {
+		return 0, err
+	}
+	for i, m := range maps {
+		if err := to.Save(m); err != nil {
+			return i, fmt.Errorf("saving %s: %w", m.String(), err)
+		}
+	}
+	return len(maps), nil
+}
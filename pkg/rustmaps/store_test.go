@@ -0,0 +1,199 @@
+package rustmaps
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/types"
+)
+
+// storedMaps are saved into every store under test
+func storedMaps() []*types.Map {
+	maps := []*types.Map{
+		{Seed: "1", Size: 4000, Status: common.StatusComplete, MapID: "a", SubmittedAt: "2026-09-03T10:00:00Z"},
+		{Seed: "1", Size: 4500, SavedConfig: "mycfg", Status: common.StatusGenerating, MapID: "b", SubmittedAt: "2026-10-02T10:00:00+02:00"},
+		{Seed: "2", Size: 4000, Staging: true, Status: common.StatusPending},
+		// Saved complete without ever being submitted by rustmaps
+		{Seed: "3", Size: 4000, Status: common.StatusComplete, MapID: "c", CompletedAt: "2026-09-10T10:00:00Z"},
+	}
+	for _, m := range maps {
+		m.SetFilename()
+	}
+	return maps
+}
+
+// testStores opens an empty store of each kind
+func testStores(t *testing.T) map[string]Store {
+	db, err := OpenSQLiteStore(filepath.Join(t.TempDir(), "maps.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLiteStore() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return map[string]Store{
+		StoreJSON:   NewJSONStore(t.TempDir()),
+		StoreSQLite: db,
+	}
+}
+
+func TestStore_Load(t *testing.T) {
+	for kind, s := range testStores(t) {
+		t.Run(kind, func(t *testing.T) {
+			if got, err := s.Load("1_4000.json"); err != nil || got != nil {
+				t.Fatalf("Store.Load() of a missing map = %v, %v, want nil", got, err)
+			}
+
+			want := storedMaps()[1]
+			want.RecordTransition(types.Transition{From: common.StatusPending, To: common.StatusSubmitted, Event: EventSubmit})
+			if err := s.Save(want); err != nil {
+				t.Fatalf("Store.Save() error = %v", err)
+			}
+			want.Status = common.StatusComplete
+			if err := s.Save(want); err != nil {
+				t.Fatalf("Store.Save() again error = %v", err)
+			}
+
+			got, err := s.Load(want.Filename)
+			if err != nil {
+				t.Fatalf("Store.Load() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Store.Load() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestStore_Query(t *testing.T) {
+	staging := true
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{name: "Everything", query: Query{}, want: []string{"1_4000.json", "1_4500_mycfg.json", "2_4000_staging.json", "3_4000.json"}},
+		{name: "Seed", query: Query{Seed: "1"}, want: []string{"1_4000.json", "1_4500_mycfg.json"}},
+		{name: "Size", query: Query{Size: 4000}, want: []string{"1_4000.json", "2_4000_staging.json", "3_4000.json"}},
+		{name: "Saved config", query: Query{SavedConfig: "mycfg"}, want: []string{"1_4500_mycfg.json"}},
+		{name: "Procedural", query: Query{SavedConfig: "procedural"}, want: []string{"1_4000.json", "2_4000_staging.json", "3_4000.json"}},
+		{name: "Staging", query: Query{Staging: &staging}, want: []string{"2_4000_staging.json"}},
+		{name: "Status", query: Query{Status: common.StatusGenerating}, want: []string{"1_4500_mycfg.json"}},
+		{name: "Map ID", query: Query{MapID: "a"}, want: []string{"1_4000.json"}},
+		{
+			name:  "Submitted or completed last month",
+			query: Query{Since: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), Until: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)},
+			want:  []string{"1_4000.json", "3_4000.json"},
+		},
+		{
+			name:  "Submitted since, in another zone",
+			query: Query{Since: time.Date(2026, 10, 2, 8, 0, 0, 0, time.UTC)},
+			want:  []string{"1_4500_mycfg.json"},
+		},
+		{name: "Nothing matches", query: Query{Seed: "1", Staging: &staging}, want: nil},
+	}
+	for kind, s := range testStores(t) {
+		for _, m := range storedMaps() {
+			if err := s.Save(m); err != nil {
+				t.Fatalf("Store.Save() error = %v", err)
+			}
+		}
+		for _, tt := range tests {
+			t.Run(kind+"/"+tt.name, func(t *testing.T) {
+				maps, err := s.Query(tt.query)
+				if err != nil {
+					t.Fatalf("Store.Query() error = %v", err)
+				}
+				var got []string
+				for _, m := range maps {
+					got = append(got, m.Filename)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Store.Query() = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestJSONStore_Load_corrupt(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "1_4000.json")
+	if err := os.WriteFile(path, []byte(`{"seed": "1", "si`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := NewJSONStore(dir)
+
+	if maps, err := s.Query(Query{}); err != nil || len(maps) != 0 {
+		t.Errorf("JSONStore.Query() = %v, %v, want the corrupt file skipped", maps, err)
+	}
+	if _, err := s.Load("1_4000.json"); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("JSONStore.Load() error = %v, want %v", err, ErrCorrupt)
+	}
+	if quarantined, _ := filepath.Glob(path + ".corrupt-*"); len(quarantined) != 1 {
+		t.Errorf("JSONStore.Load() quarantined %v, want one file", quarantined)
+	}
+	if got, err := s.Load("1_4000.json"); err != nil || got != nil {
+		t.Errorf("JSONStore.Load() after quarantine = %v, %v, want nil", got, err)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	stores := testStores(t)
+	from, to := stores[StoreJSON], stores[StoreSQLite]
+	for _, m := range storedMaps() {
+		if err := from.Save(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := Migrate(from, to)
+	if err != nil || n != 4 {
+		t.Fatalf("Migrate() = %d, %v, want 4 maps", n, err)
+	}
+	want, _ := from.Query(Query{})
+	if got, err := to.Query(Query{}); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Migrate() copied %v, want %v", got, want)
+	}
+}
+
+func TestGenerator_MigrateToSQLite(t *testing.T) {
+	g := NewMockedGenerator(t, &Generator{baseDir: t.TempDir()})
+	for _, m := range storedMaps() {
+		if err := m.SaveJSON(g.importsDir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := g.MigrateToSQLite()
+	if err != nil || n != 4 {
+		t.Fatalf("Generator.MigrateToSQLite() = %d, %v, want 4 maps", n, err)
+	}
+	defer g.Close()
+	if g.GetStore() != StoreSQLite {
+		t.Errorf("Generator.MigrateToSQLite() store = %s, want %s", g.GetStore(), StoreSQLite)
+	}
+	if maps, err := g.QueryMaps(Query{Seed: "1"}); err != nil || len(maps) != 2 {
+		t.Errorf("Generator.QueryMaps() = %v, %v, want 2 maps", maps, err)
+	}
+
+	// A restart opens the database from the saved config
+	restarted := NewMockedGenerator(t, &Generator{baseDir: g.baseDir, configPath: g.configPath, importsDir: t.TempDir()})
+	if err := restarted.LoadConfig(); err != nil {
+		t.Fatalf("Generator.LoadConfig() error = %v", err)
+	}
+	defer restarted.Close()
+	if maps, err := restarted.QueryMaps(Query{}); err != nil || len(maps) != 4 {
+		t.Errorf("Generator.QueryMaps() after a restart = %v, %v, want 4 maps", maps, err)
+	}
+}
+
+func TestGenerator_openStore_unknown(t *testing.T) {
+	g := NewMockedGenerator(t, &Generator{config: types.Config{Store: "postgres"}})
+	if err := g.openStore(); err == nil {
+		t.Error("Generator.openStore() error = nil, want an unknown store error")
+	}
+}
@@ -0,0 +1,27 @@
+package rustmaps
+
+import (
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/report"
+)
+
+// Summary describes the run so far: the maps in each status, the ones that
+// failed, how long it has taken and how many maps RustMaps started
+// generating for it. The monthly usage and exit code are left to the caller.
+func (g *Generator) Summary() report.Summary {
+	s := report.Summary{
+		Statuses:  make(map[string]int),
+		Generated: int(g.generated.Load()),
+	}
+	for _, m := range g.maps {
+		s.Statuses[m.Status]++
+	}
+	for _, m := range g.Failed() {
+		s.Failed = append(s.Failed, report.NewMapRecord(m))
+	}
+	if !g.started.IsZero() {
+		s.DurationSeconds = int(time.Since(g.started) / time.Second)
+	}
+	return s
+}
@@ -0,0 +1,70 @@
+package rustmaps
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/types"
+)
+
+func TestGenerator_Summary(t *testing.T) {
+	tests := []struct {
+		name         string
+		maps         []*types.Map
+		started      time.Time
+		generated    int32
+		wantStatuses map[string]int
+		wantFailed   []string
+		wantDuration int
+	}{
+		{
+			name: "Complete",
+			maps: []*types.Map{
+				{Seed: "1", Status: common.StatusComplete},
+				{Seed: "2", Status: common.StatusDownloaded},
+			},
+			started:      time.Now().Add(-time.Minute),
+			generated:    2,
+			wantStatuses: map[string]int{common.StatusComplete: 1, common.StatusDownloaded: 1},
+			wantDuration: 60,
+		},
+		{
+			name: "Failed and refused",
+			maps: []*types.Map{
+				{Seed: "1", Status: common.StatusComplete},
+				{Seed: "2", Status: common.StatusFailed, FailureReason: "stalled"},
+				{Seed: "3", Status: common.StatusForbidden, FailureReason: "rustmaps api: 403 Forbidden"},
+			},
+			wantStatuses: map[string]int{common.StatusComplete: 1, common.StatusFailed: 1, common.StatusForbidden: 1},
+			wantFailed:   []string{"2", "3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewMockedGenerator(t, &Generator{maps: tt.maps})
+			g.started = tt.started
+			g.generated.Store(tt.generated)
+
+			got := g.Summary()
+			if !reflect.DeepEqual(got.Statuses, tt.wantStatuses) {
+				t.Errorf("Generator.Summary() statuses = %v, want %v", got.Statuses, tt.wantStatuses)
+			}
+			var failed []string
+			for _, r := range got.Failed {
+				failed = append(failed, r.Seed)
+			}
+			if !reflect.DeepEqual(failed, tt.wantFailed) {
+				t.Errorf("Generator.Summary() failed = %v, want %v", failed, tt.wantFailed)
+			}
+			if got.Generated != int(tt.generated) {
+				t.Errorf("Generator.Summary() generated = %d, want %d", got.Generated, tt.generated)
+			}
+			// allow a second either way for the clock ticking over
+			if diff := got.DurationSeconds - tt.wantDuration; diff < -1 || diff > 1 {
+				t.Errorf("Generator.Summary() duration = %d, want %d", got.DurationSeconds, tt.wantDuration)
+			}
+		})
+	}
+}
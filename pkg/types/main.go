@@ -3,17 +3,38 @@ package types
 import (
 	"encoding/json"
 	"fmt"
-	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/maintc/rustmaps-cli/pkg/common"
+	"github.com/maintc/rustmaps-cli/pkg/fsutil"
 )
 
 // Config represents the application configuration
 type Config struct {
 	APIKey string `json:"api_key"`
 	Tier   string `json:"tier"`
+	// APIUrl replaces the RustMaps API, for example with a local mock server
+	APIUrl string `json:"api_url,omitempty"`
+	// RateLimit is the average number of API calls allowed per minute
+	RateLimit int `json:"rate_limit,omitempty"`
+	// RateBurst is how many API calls may be made back to back
+	RateBurst int `json:"rate_burst,omitempty"`
+	// Timeout is how many seconds each API request attempt may take
+	Timeout int `json:"timeout,omitempty"`
+	// UserAgent is sent with every API request
+	UserAgent string `json:"user_agent,omitempty"`
+	// Proxy is the URL of an HTTP(S) proxy for API calls and downloads
+	Proxy string `json:"proxy,omitempty"`
+	// CABundle is a PEM file of extra certificates to trust, for proxies that
+	// intercept TLS
+	CABundle string `json:"ca_bundle,omitempty"`
+	// QuotaReserve is how many maps of the monthly quota generate always
+	// leaves unused
+	QuotaReserve int `json:"quota_reserve,omitempty"`
+	// Store is where map state is kept, "json" files in the imports
+	// directory by default or a "sqlite" database
+	Store string `json:"store,omitempty"`
 }
 
 // Map represents a single map configuration
@@ -26,6 +47,38 @@ type Map struct {
 	Status      string `json:"status"`
 	LastSync    string `json:"last_sync,omitempty"`
 	Filename    string `json:"filename,omitempty"`
+	// QueuePosition, State, CurrentStep and LastGeneratorPing are what
+	// RustMaps last said about the generation, LastGeneratorPing is RFC3339
+	QueuePosition     int    `json:"queue_position,omitempty"`
+	State             string `json:"state,omitempty"`
+	CurrentStep       string `json:"current_step,omitempty"`
+	LastGeneratorPing string `json:"last_generator_ping,omitempty"`
+	// SubmittedAt and CompletedAt are RFC3339 times, when the map was last
+	// accepted by RustMaps and when it was first seen complete afterwards
+	SubmittedAt string `json:"submitted_at,omitempty"`
+	CompletedAt string `json:"completed_at,omitempty"`
+	// GenerationSeconds is how long the map took from submission to completion
+	GenerationSeconds int `json:"generation_seconds,omitempty"`
+	// Resubmits counts how often the map was submitted again after stalling
+	Resubmits int `json:"resubmits,omitempty"`
+	// FailureReason says why the map failed or was refused
+	FailureReason string `json:"failure_reason,omitempty"`
+	// History is every status change of the map, oldest first
+	History []Transition `json:"history,omitempty"`
+}
+
+// MaxHistory is how many transitions a map keeps, the oldest are dropped
+// first
+const MaxHistory = 50
+
+// Transition is a change of a map's status
+type Transition struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Event is what caused the change
+	Event string `json:"event"`
+	// At is an RFC3339 time
+	At string `json:"at"`
 }
 
 func NewMap(seed string, size int, savedConfig string, staging bool) *Map {
@@ -55,12 +108,76 @@ func (m *Map) SetFilename() {
 	m.Filename = filename
 }
 
-func (m *Map) ReportStatus(status string) {
+// SetStatus records status as the map's current state and marks the map as
+// synced. The first time a submitted map is seen complete its completion
+// time and generation duration are recorded.
+func (m *Map) SetStatus(status string) {
 	m.Status = status
-	fmt.Println(m.String())
+	if status == common.StatusComplete && m.SubmittedAt != "" && m.CompletedAt == "" {
+		// Truncated like the recorded times so the duration matches them
+		now := time.Now().Truncate(time.Second)
+		m.CompletedAt = now.Format(time.RFC3339)
+		if submitted, err := time.Parse(time.RFC3339, m.SubmittedAt); err == nil {
+			m.GenerationSeconds = int(now.Sub(submitted) / time.Second)
+		}
+		m.QueuePosition = 0
+	}
 	m.MarkSynced()
 }
 
+// RecordTransition appends t to the map's history
+func (m *Map) RecordTransition(t Transition) {
+	m.History = append(m.History, t)
+	if len(m.History) > MaxHistory {
+		m.History = m.History[len(m.History)-MaxHistory:]
+	}
+}
+
+// IsComplete reports whether RustMaps has finished generating the map,
+// whether or not it was downloaded since
+func (m *Map) IsComplete() bool {
+	return m.Status == common.StatusComplete || m.Status == common.StatusDownloaded
+}
+
+// IsFailed reports whether the map will not complete without a change,
+// because it was given up on or RustMaps refused it
+func (m *Map) IsFailed() bool {
+	switch m.Status {
+	case common.StatusFailed, common.StatusBadRequest, common.StatusStagingNotEnabled,
+		common.StatusUnauthorized, common.StatusForbidden:
+		return true
+	}
+	return false
+}
+
+// MarkSubmitted records that RustMaps accepted the map at t, forgetting any
+// earlier generation
+func (m *Map) MarkSubmitted(t time.Time) {
+	m.SubmittedAt = t.Format(time.RFC3339)
+	m.CompletedAt = ""
+	m.GenerationSeconds = 0
+}
+
+// SetProgress records what RustMaps said about the generation
+func (m *Map) SetProgress(queuePosition int, state, currentStep string, lastPing time.Time) {
+	m.QueuePosition = queuePosition
+	m.State = state
+	m.CurrentStep = currentStep
+	m.LastGeneratorPing = ""
+	if !lastPing.IsZero() {
+		m.LastGeneratorPing = lastPing.UTC().Format(time.RFC3339)
+	}
+}
+
+// GenerationDuration returns how long the map took to generate, false
+// until a submitted map has completed
+func (m *Map) GenerationDuration() (time.Duration, bool) {
+	if m.CompletedAt == "" {
+		return 0, false
+	}
+	return time.Duration(m.GenerationSeconds) * time.Second, true
+}
+
 func (m *Map) String() string {
 	return fmt.Sprintf("Seed: %s | Size: %d | Config: '%s' | Status: '%s'", m.Seed, m.Size, m.SavedConfig, m.Status)
 }
@@ -90,8 +207,21 @@ func (m *Map) MergeFrom(other Map) {
 	m.MapID = other.MapID
 	m.Status = other.Status
 	m.LastSync = other.LastSync
+	m.QueuePosition = other.QueuePosition
+	m.State = other.State
+	m.CurrentStep = other.CurrentStep
+	m.LastGeneratorPing = other.LastGeneratorPing
+	m.SubmittedAt = other.SubmittedAt
+	m.CompletedAt = other.CompletedAt
+	m.GenerationSeconds = other.GenerationSeconds
+	m.Resubmits = other.Resubmits
+	m.FailureReason = other.FailureReason
+	m.History = other.History
 }
 
+// SaveJSON writes the map to its file in outputDir. The file is replaced
+// atomically, so a crash leaves the previous state rather than a truncated
+// file.
 func (m *Map) SaveJSON(outputDir string) error {
 	// Check if the Filename field is set
 	if m.Filename == "" {
@@ -100,18 +230,13 @@ func (m *Map) SaveJSON(outputDir string) error {
 
 	outputPath := filepath.Join(outputDir, m.Filename)
 
-	// Open the file for writing (create it if it doesn't exist)
-	file, err := os.Create(outputPath)
+	data, err := json.MarshalIndent(m, "", "  ")
 	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+		return fmt.Errorf("failed to encode map to JSON: %w", err)
 	}
-	defer file.Close()
 
-	// Create a JSON encoder and write the struct to the file
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ") // Optional: Pretty-print the JSON
-	if err := encoder.Encode(m); err != nil {
-		return fmt.Errorf("failed to encode map to JSON: %w", err)
+	if err := fsutil.WriteFile(outputPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
 	}
 
 	return nil
@@ -1,7 +1,12 @@
 package types
 
 import (
+	"fmt"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/maintc/rustmaps-cli/pkg/common"
 )
 
 func TestNewMap(t *testing.T) {
@@ -84,7 +89,7 @@ func TestMap_SetFilename(t *testing.T) {
 	}
 }
 
-func TestMap_ReportStatus(t *testing.T) {
+func TestMap_SetStatus(t *testing.T) {
 	type fields struct {
 		Seed        string
 		Size        int
@@ -94,6 +99,8 @@ func TestMap_ReportStatus(t *testing.T) {
 		Status      string
 		LastSync    string
 		Filename    string
+		SubmittedAt string
+		CompletedAt string
 	}
 	type args struct {
 		status string
@@ -102,9 +109,11 @@ func TestMap_ReportStatus(t *testing.T) {
 		name   string
 		fields fields
 		args   args
+		// wantSeconds is the recorded generation time, -1 when none is
+		wantSeconds int
 	}{
 		{
-			name: "TestReportStatus",
+			name: "TestSetStatus",
 			fields: fields{
 				Seed:        "test",
 				Size:        1,
@@ -114,6 +123,42 @@ func TestMap_ReportStatus(t *testing.T) {
 			args: args{
 				status: "test",
 			},
+			wantSeconds: -1,
+		},
+		{
+			name: "Complete after submission",
+			fields: fields{
+				Seed:        "test",
+				Status:      common.StatusGenerating,
+				SubmittedAt: time.Now().Add(-90 * time.Second).Format(time.RFC3339),
+			},
+			args: args{
+				status: common.StatusComplete,
+			},
+			wantSeconds: 90,
+		},
+		{
+			name: "Complete again keeps the first completion",
+			fields: fields{
+				Seed:        "test",
+				Status:      common.StatusComplete,
+				SubmittedAt: "2024-01-02T03:00:00Z",
+				CompletedAt: "2024-01-02T03:01:00Z",
+			},
+			args: args{
+				status: common.StatusComplete,
+			},
+			wantSeconds: 0,
+		},
+		{
+			name: "Complete without submission",
+			fields: fields{
+				Seed: "test",
+			},
+			args: args{
+				status: common.StatusComplete,
+			},
+			wantSeconds: -1,
 		},
 	}
 	for _, tt := range tests {
@@ -127,8 +172,24 @@ func TestMap_ReportStatus(t *testing.T) {
 				Status:      tt.fields.Status,
 				LastSync:    tt.fields.LastSync,
 				Filename:    tt.fields.Filename,
+				SubmittedAt: tt.fields.SubmittedAt,
+				CompletedAt: tt.fields.CompletedAt,
+			}
+			m.SetStatus(tt.args.status)
+			if m.Status != tt.args.status {
+				t.Errorf("Map.SetStatus() status = %v, want %v", m.Status, tt.args.status)
+			}
+			if m.LastSync == "" {
+				t.Errorf("Map.SetStatus() did not mark the map as synced")
+			}
+			got, ok := m.GenerationDuration()
+			if ok != (tt.wantSeconds >= 0) {
+				t.Fatalf("Map.GenerationDuration() ok = %v, want %v", ok, tt.wantSeconds >= 0)
+			}
+			// allow a second either way for the clock ticking over
+			if ok && (got < time.Duration(tt.wantSeconds-1)*time.Second || got > time.Duration(tt.wantSeconds+1)*time.Second) {
+				t.Errorf("Map.GenerationDuration() = %v, want %ds", got, tt.wantSeconds)
 			}
-			m.ReportStatus(tt.args.status)
 		})
 	}
 }
@@ -291,10 +352,20 @@ func TestMap_MergeFrom(t *testing.T) {
 			},
 			args: args{
 				other: Map{
-					Seed:        "test",
-					Size:        1,
-					SavedConfig: "test",
-					Staging:     true,
+					Seed:              "test",
+					Size:              1,
+					SavedConfig:       "test",
+					Staging:           true,
+					QueuePosition:     2,
+					CurrentStep:       "Queued",
+					SubmittedAt:       "2024-01-02T03:00:00Z",
+					CompletedAt:       "2024-01-02T03:01:00Z",
+					GenerationSeconds: 60,
+					Resubmits:         1,
+					FailureReason:     "stalled",
+					History: []Transition{
+						{From: common.StatusPending, To: common.StatusSubmitted, Event: "submit", At: "2024-01-02T03:00:00Z"},
+					},
 				},
 			},
 		},
@@ -312,6 +383,141 @@ func TestMap_MergeFrom(t *testing.T) {
 				Filename:    tt.fields.Filename,
 			}
 			m.MergeFrom(tt.args.other)
+			if !reflect.DeepEqual(*m, tt.args.other) {
+				t.Errorf("Map.MergeFrom() = %+v, want %+v", *m, tt.args.other)
+			}
+		})
+	}
+}
+
+func TestMap_MarkSubmitted(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	m := &Map{CompletedAt: "2024-01-01T00:00:00Z", GenerationSeconds: 60}
+	m.MarkSubmitted(at)
+	if m.SubmittedAt != "2024-01-02T03:04:05Z" || m.CompletedAt != "" || m.GenerationSeconds != 0 {
+		t.Errorf("Map.MarkSubmitted() = %+v, want a fresh submission at %v", m, at)
+	}
+}
+
+func TestMap_RecordTransition(t *testing.T) {
+	tests := []struct {
+		name        string
+		existing    int
+		wantLen     int
+		wantFirstAt string
+	}{
+		{
+			name:        "Appends",
+			existing:    2,
+			wantLen:     3,
+			wantFirstAt: "0",
+		},
+		{
+			name:        "Drops the oldest when full",
+			existing:    MaxHistory,
+			wantLen:     MaxHistory,
+			wantFirstAt: "1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Map{}
+			for i := 0; i < tt.existing; i++ {
+				m.History = append(m.History, Transition{At: fmt.Sprint(i)})
+			}
+			last := Transition{From: common.StatusGenerating, To: common.StatusComplete, Event: "completed", At: "last"}
+			m.RecordTransition(last)
+			if len(m.History) != tt.wantLen {
+				t.Fatalf("Map.RecordTransition() history has %d entries, want %d", len(m.History), tt.wantLen)
+			}
+			if m.History[0].At != tt.wantFirstAt {
+				t.Errorf("Map.RecordTransition() oldest = %+v, want at %q", m.History[0], tt.wantFirstAt)
+			}
+			if m.History[len(m.History)-1] != last {
+				t.Errorf("Map.RecordTransition() newest = %+v, want %+v", m.History[len(m.History)-1], last)
+			}
+		})
+	}
+}
+
+func TestMap_IsComplete(t *testing.T) {
+	tests := []struct {
+		status string
+		want   bool
+	}{
+		{status: common.StatusPending, want: false},
+		{status: common.StatusGenerating, want: false},
+		{status: common.StatusFailed, want: false},
+		{status: common.StatusComplete, want: true},
+		{status: common.StatusDownloaded, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			m := &Map{Status: tt.status}
+			if got := m.IsComplete(); got != tt.want {
+				t.Errorf("Map.IsComplete() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMap_IsFailed(t *testing.T) {
+	tests := []struct {
+		status string
+		want   bool
+	}{
+		{status: common.StatusPending, want: false},
+		{status: common.StatusRateLimited, want: false},
+		{status: common.StatusComplete, want: false},
+		{status: common.StatusFailed, want: true},
+		{status: common.StatusBadRequest, want: true},
+		{status: common.StatusStagingNotEnabled, want: true},
+		{status: common.StatusUnauthorized, want: true},
+		{status: common.StatusForbidden, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			m := &Map{Status: tt.status}
+			if got := m.IsFailed(); got != tt.want {
+				t.Errorf("Map.IsFailed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMap_SetProgress(t *testing.T) {
+	type args struct {
+		queuePosition int
+		state         string
+		currentStep   string
+		lastPing      time.Time
+	}
+	tests := []struct {
+		name     string
+		args     args
+		wantPing string
+	}{
+		{
+			name:     "With ping",
+			args:     args{queuePosition: 3, state: "Generating", currentStep: "Placing monuments", lastPing: time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))},
+			wantPing: "2024-01-02T02:04:05Z",
+		},
+		{
+			name:     "Without ping",
+			args:     args{queuePosition: 1, state: "Queued"},
+			wantPing: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Map{LastGeneratorPing: "stale"}
+			m.SetProgress(tt.args.queuePosition, tt.args.state, tt.args.currentStep, tt.args.lastPing)
+			if m.QueuePosition != tt.args.queuePosition || m.State != tt.args.state || m.CurrentStep != tt.args.currentStep {
+				t.Errorf("Map.SetProgress() = %+v, want %+v", m, tt.args)
+			}
+			if m.LastGeneratorPing != tt.wantPing {
+				t.Errorf("Map.SetProgress() ping = %v, want %v", m.LastGeneratorPing, tt.wantPing)
+			}
 		})
 	}
 }